@@ -0,0 +1,179 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// AutocmdEvent identifies an editor event that autocommands can listen for.
+// The names follow Vim's autocommand events.
+type AutocmdEvent string
+
+const (
+	// EventBufWritePre fires before the buffer is written
+	EventBufWritePre AutocmdEvent = "BufWritePre"
+	// EventBufWritePost fires after the buffer has been written
+	EventBufWritePost AutocmdEvent = "BufWritePost"
+	// EventInsertEnter fires when entering insert mode
+	EventInsertEnter AutocmdEvent = "InsertEnter"
+	// EventInsertLeave fires when leaving insert mode
+	EventInsertLeave AutocmdEvent = "InsertLeave"
+	// EventModeChanged fires after every mode transition
+	EventModeChanged AutocmdEvent = "ModeChanged"
+	// EventCmdExecute fires before a command-mode command is executed
+	EventCmdExecute AutocmdEvent = "CmdExecute"
+	// EventTextChanged fires after the buffer content has changed
+	EventTextChanged AutocmdEvent = "TextChanged"
+	// EventTextYankPost fires after text has been yanked or deleted into the yank buffer
+	EventTextYankPost AutocmdEvent = "TextYankPost"
+	// EventCursorHold fires once when the user has not pressed a key for the
+	// update time while in normal or visual mode
+	EventCursorHold AutocmdEvent = "CursorHold"
+	// EventCursorHoldI is like EventCursorHold but fires in insert mode
+	EventCursorHoldI AutocmdEvent = "CursorHoldI"
+	// EventOptionSet fires after an option value has changed
+	EventOptionSet AutocmdEvent = "OptionSet"
+	// EventBufEnter fires after another buffer is shown in the current window
+	EventBufEnter AutocmdEvent = "BufEnter"
+	// EventBufLeave fires before the current window leaves its buffer
+	EventBufLeave AutocmdEvent = "BufLeave"
+	// EventWinEnter fires after another window has become current
+	EventWinEnter AutocmdEvent = "WinEnter"
+	// EventWinLeave fires before the current window is left for another one
+	EventWinLeave AutocmdEvent = "WinLeave"
+	// EventTabEnter fires after another tab page has become current
+	EventTabEnter AutocmdEvent = "TabEnter"
+	// EventTabLeave fires before the current tab page is left for another one
+	EventTabLeave AutocmdEvent = "TabLeave"
+)
+
+// AutocmdArgs is the payload passed to autocommand handlers.
+// Only the fields relevant to the event are set.
+type AutocmdArgs struct {
+	Event    AutocmdEvent     // Event that triggered the handler
+	File     string           // Name of the file the event applies to, if any
+	Cursor   Cursor           // Cursor position when the event fired
+	OldMode  EditorMode       // Previous mode (ModeChanged, InsertEnter, InsertLeave)
+	NewMode  EditorMode       // New mode (ModeChanged, InsertEnter, InsertLeave)
+	Command  string           // Full command line (CmdExecute)
+	Text     string           // Yanked text (TextYankPost)
+	Operator string           // Operator that filled the yank buffer: "y", "d" or "c" (TextYankPost)
+	Change   TextChange       // The buffer modification (TextChanged)
+	Option   OptionChangedMsg // The option change (OptionSet)
+}
+
+// AutocmdFn is a handler invoked when an autocommand event fires
+type AutocmdFn func(Buffer, AutocmdArgs) tea.Cmd
+
+// autocmd is a registered handler together with its file pattern filter
+type autocmd struct {
+	pattern string    // Comma separated glob patterns matched against the file name
+	handler AutocmdFn // Handler to invoke
+}
+
+// AutocmdRegistry stores autocommand handlers by event
+type AutocmdRegistry struct {
+	handlers map[AutocmdEvent][]autocmd
+}
+
+// defaultUpdateTime is the idle time after which CursorHold fires
+const defaultUpdateTime = 4 * time.Second
+
+// newAutocmdRegistry creates a new empty autocommand registry
+func newAutocmdRegistry() *AutocmdRegistry {
+	return &AutocmdRegistry{
+		handlers: make(map[AutocmdEvent][]autocmd),
+	}
+}
+
+// Add registers a handler for an event. The pattern is a comma separated list
+// of globs matched against the file name or its base name. An empty pattern
+// or "*" matches every buffer, including ones without a file name.
+func (r *AutocmdRegistry) Add(event AutocmdEvent, pattern string, fn AutocmdFn) {
+	r.handlers[event] = append(r.handlers[event], autocmd{pattern: pattern, handler: fn})
+}
+
+// Get returns the handlers registered for an event that match the file name
+func (r *AutocmdRegistry) Get(event AutocmdEvent, file string) []AutocmdFn {
+	var result []AutocmdFn
+	for _, ac := range r.handlers[event] {
+		if matchAutocmdPattern(ac.pattern, file) {
+			result = append(result, ac.handler)
+		}
+	}
+	return result
+}
+
+// matchAutocmdPattern reports whether a file name matches an autocommand pattern
+func matchAutocmdPattern(pattern, file string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	if file == "" {
+		return false
+	}
+	for _, p := range strings.Split(pattern, ",") {
+		p = strings.TrimSpace(p)
+		if ok, _ := filepath.Match(p, file); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(p, filepath.Base(file)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AddAutocmd registers a handler for an editor event, filtered by file pattern
+func (m *editorModel) AddAutocmd(event AutocmdEvent, pattern string, fn AutocmdFn) {
+	m.autocmds.Add(event, pattern, fn)
+}
+
+// fireAutocmd runs the handlers registered for an event. Handlers run
+// immediately so they can inspect or modify the buffer; the commands they
+// return are delivered at the end of the current update.
+func (m *editorModel) fireAutocmd(event AutocmdEvent, args AutocmdArgs) {
+	args.Event = event
+	if args.File == "" {
+		args.File = m.fileName()
+	}
+	args.Cursor = m.cursor
+	for _, fn := range m.autocmds.Get(event, args.File) {
+		if cmd := fn(m.GetBuffer(), args); cmd != nil {
+			m.pendingCmds = append(m.pendingCmds, cmd)
+		}
+	}
+}
+
+// flushPendingCmds returns the commands queued by autocommands and clears the queue
+func (m *editorModel) flushPendingCmds() tea.Cmd {
+	cmds := m.pendingCmds
+	m.pendingCmds = nil
+	return tea.Batch(cmds...)
+}
+
+// checkCursorHold fires CursorHold once the editor has been idle for the update time
+func (m *editorModel) checkCursorHold(now time.Time) {
+	if m.cursorHoldFired || now.Sub(m.lastActivity) < m.updateTime {
+		return
+	}
+	m.cursorHoldFired = true
+
+	switch m.mode {
+	case ModeInsert:
+		m.fireAutocmd(EventCursorHoldI, AutocmdArgs{})
+	case ModeNormal, ModeVisual:
+		m.fireAutocmd(EventCursorHold, AutocmdArgs{})
+	}
+}
+
+// WithUpdateTime sets the idle time after which CursorHold autocommands fire
+func WithUpdateTime(d time.Duration) EditorOption {
+	return func(o *options) {
+		o.UpdateTime = d
+	}
+}
@@ -0,0 +1,93 @@
+package vimtea
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutocmdPatternMatching(t *testing.T) {
+	assert.True(t, matchAutocmdPattern("", ""), "Empty pattern should match buffers without a file")
+	assert.True(t, matchAutocmdPattern("*", "main.go"), "Star should match every file")
+	assert.True(t, matchAutocmdPattern("*.go", "cmd/app/main.go"), "Pattern should match the base name")
+	assert.True(t, matchAutocmdPattern("*.mod, *.go", "main.go"), "Comma separated patterns should be tried in turn")
+	assert.False(t, matchAutocmdPattern("*.py", "main.go"), "Non-matching pattern should be rejected")
+	assert.False(t, matchAutocmdPattern("*.go", ""), "File patterns should not match unnamed buffers")
+}
+
+func TestAutocmdModeEvents(t *testing.T) {
+	editor := NewEditor(WithContent("abc"), WithFileName("main.go"))
+
+	var events []AutocmdEvent
+	record := func(_ Buffer, args AutocmdArgs) tea.Cmd {
+		events = append(events, args.Event)
+		return nil
+	}
+	editor.AddAutocmd(EventInsertEnter, "*.go", record)
+	editor.AddAutocmd(EventInsertLeave, "*.go", record)
+	editor.AddAutocmd(EventModeChanged, "*.py", record)
+
+	editor.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	editor.Update(tea.KeyMsg{Type: tea.KeyEsc})
+
+	assert.Equal(t, []AutocmdEvent{EventInsertEnter, EventInsertLeave}, events,
+		"Only handlers matching the file pattern should run")
+}
+
+func TestAutocmdTextYankPost(t *testing.T) {
+	editor := NewEditor(WithContent("hello world"))
+
+	var yanked AutocmdArgs
+	editor.AddAutocmd(EventTextYankPost, "", func(_ Buffer, args AutocmdArgs) tea.Cmd {
+		yanked = args
+		return nil
+	})
+
+	model := editor.(*editorModel)
+	yankInnerWord(model)
+	assert.Equal(t, "hello", yanked.Text, "Yanked text should be passed to the handler")
+	assert.Equal(t, "y", yanked.Operator, "Yank should report the y operator")
+
+	deleteLine(model)
+	assert.Equal(t, "\nhello world", yanked.Text, "Deleted line should be passed to the handler")
+	assert.Equal(t, "d", yanked.Operator, "Delete should report the d operator")
+}
+
+func TestAutocmdCmdExecute(t *testing.T) {
+	editor := NewEditor()
+
+	var command string
+	editor.AddAutocmd(EventCmdExecute, "", func(_ Buffer, args AutocmdArgs) tea.Cmd {
+		command = args.Command
+		return func() tea.Msg { return "done" }
+	})
+
+	_, cmd := editor.Update(CommandMsg{Command: "zr"})
+	assert.Equal(t, "zr", command, "Handler should receive the executed command")
+	assert.Contains(t, collectMsgs(cmd), tea.Msg("done"), "Commands returned by handlers should be delivered")
+}
+
+func TestAutocmdCursorHold(t *testing.T) {
+	editor := NewEditor(WithUpdateTime(10 * time.Millisecond))
+	model := editor.(*editorModel)
+
+	holds := 0
+	editor.AddAutocmd(EventCursorHold, "", func(Buffer, AutocmdArgs) tea.Cmd {
+		holds++
+		return nil
+	})
+
+	now := model.lastActivity
+	editor.Update(cursorBlinkMsg(now.Add(5 * time.Millisecond)))
+	assert.Equal(t, 0, holds, "CursorHold should not fire before the update time")
+
+	editor.Update(cursorBlinkMsg(now.Add(20 * time.Millisecond)))
+	editor.Update(cursorBlinkMsg(now.Add(30 * time.Millisecond)))
+	assert.Equal(t, 1, holds, "CursorHold should fire once per idle period")
+
+	editor.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'l'}})
+	editor.Update(cursorBlinkMsg(time.Now().Add(time.Second)))
+	assert.Equal(t, 2, holds, "A keypress should re-arm CursorHold")
+}
@@ -1,7 +1,11 @@
 // Package vimtea provides a Vim-like text editor component for terminal applications
 package vimtea
 
-import tea "github.com/charmbracelet/bubbletea"
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
 
 // Command is a function that performs an action on the editor model
 // and returns a bubbletea command
@@ -138,11 +142,17 @@ func (r *BindingRegistry) FindExact(keySeq string, mode EditorMode) *internalKey
 
 // IsPrefix checks if the key sequence is a prefix of any registered binding
 // This is used to determine if we should wait for more input
+// A numeric prefix is ignored, so "3>" is a prefix of ">>"
 func (r *BindingRegistry) IsPrefix(keySeq string, mode EditorMode) bool {
-	if prefixes, ok := r.prefixBindings[mode]; ok {
-		return prefixes[keySeq]
+	prefixes, ok := r.prefixBindings[mode]
+	if !ok {
+		return false
+	}
+	if prefixes[keySeq] {
+		return true
 	}
-	return false
+	cmdPart := strings.TrimLeft(keySeq, "0123456789")
+	return cmdPart != keySeq && prefixes[cmdPart]
 }
 
 // GetAll returns all registered key bindings
@@ -17,6 +17,9 @@ type Buffer interface {
 	// Lines returns all lines in the buffer as a string slice
 	Lines() []string
 
+	// Line returns the line at the given row, or "" if the row is out of range
+	Line(row int) string
+
 	// LineCount returns the number of lines in the buffer
 	LineCount() int
 
@@ -24,7 +27,7 @@ type Buffer interface {
 	LineLength(row int) int
 
 	// VisualLineLength returns the visual length of the line at the given row
-	// counting tabs as tabWidth spaces
+	// counting tabs up to the next tab stop
 	VisualLineLength(row int) int
 
 	// InsertAt inserts text at the specified position
@@ -47,19 +50,38 @@ type Buffer interface {
 	
 	// Clear removes all content from the buffer and resets to empty state
 	Clear() tea.Cmd
+
+	// Modified returns whether the content differs from the last saved state.
+	// Undoing back to the saved state clears the flag again.
+	Modified() bool
+
+	// MarkSaved records the current content as the saved state
+	MarkSaved()
 }
 
 // buffer implements the Buffer interface
 type buffer struct {
-	lines     []string      // Text content as lines
-	undoStack []bufferState // Stack of previous buffer states for undo
-	redoStack []bufferState // Stack of undone states for redo
+	lines     []string       // Text content as lines
+	undoStack []bufferState  // Stack of previous buffer states for undo
+	redoStack []bufferState  // Stack of undone states for redo
+	version   int            // Monotonic counter bumped on every modification
+	state     int            // Identifies the current content; restored by undo/redo
+	saved     int            // State of the content that was last saved
+	pending   *pendingChange // Lines modified since the last change notification, nil if none
+
+	fileFormat FileFormat // Line ending used when writing
+	encoding   string     // Encoding used when writing
+	bom        bool       // Whether a byte order mark is written
+
+	tabs    tabSettings    // Tab and indentation settings
+	options map[string]any // Values of buffer-local options set on this buffer
 }
 
 // bufferState represents a snapshot of the buffer for undo/redo
 type bufferState struct {
 	lines  []string // Content at the time of snapshot
 	cursor Cursor   // Cursor position at the time of snapshot
+	state  int      // Content state at the time of snapshot
 }
 
 // TextRange represents a range of text with start and end positions
@@ -68,19 +90,38 @@ type TextRange struct {
 	End   Cursor // Ending position (inclusive)
 }
 
-// tabWidth defines the visual width of a tab character
-const tabWidth = 4
-
 // newBuffer creates a new buffer with the given content
+// The line ending style of the content is detected and used for writing
 func newBuffer(content string) *buffer {
-	lines := strings.Split(content, "\n")
+	format := detectFileFormat(content)
+	lines := strings.Split(normalizeLineEndings(content, format), "\n")
 	return &buffer{
-		lines:     lines,
-		undoStack: []bufferState{},
-		redoStack: []bufferState{},
+		lines:      lines,
+		undoStack:  []bufferState{},
+		redoStack:  []bufferState{},
+		fileFormat: format,
+		encoding:   EncodingUTF8,
+		tabs:       defaultTabSettings(),
 	}
 }
 
+// touch records that the buffer content has been modified
+// Every modification produces a new content state
+func (b *buffer) touch() {
+	b.version++
+	b.state = b.version
+}
+
+// modified returns whether the buffer content differs from the saved state
+func (b *buffer) modified() bool {
+	return b.state != b.saved
+}
+
+// markSaved records the current content as saved
+func (b *buffer) markSaved() {
+	b.saved = b.state
+}
+
 // text returns the entire buffer content as a string
 func (b *buffer) text() string {
 	return strings.Join(b.lines, "\n")
@@ -109,13 +150,13 @@ func (b *buffer) lineLength(idx int) int {
 	return len(b.lines[idx])
 }
 
-// visualLineLength returns the visual length of the line, counting tabs as tabWidth spaces
+// visualLineLength returns the visual length of the line, counting tabs up to the next tab stop
 // Returns 0 if the index is out of bounds
 func (b *buffer) visualLineLength(idx int) int {
 	if idx < 0 || idx >= len(b.lines) {
 		return 0
 	}
-	return visualLength(b.lines[idx], 0)
+	return visualLength(b.lines[idx], 0, b.tabs.tabStop)
 }
 
 // setLine replaces the line at the given index with new content
@@ -124,7 +165,9 @@ func (b *buffer) setLine(idx int, content string) {
 	if idx < 0 || idx >= len(b.lines) {
 		return
 	}
+	b.record(idx, idx+1, 1)
 	b.lines[idx] = content
+	b.touch()
 }
 
 // insertLine inserts a new line at the given index
@@ -134,6 +177,9 @@ func (b *buffer) insertLine(idx int, content string) {
 		return
 	}
 
+	b.record(idx, idx, 1)
+	b.touch()
+
 	// Special case: appending at the end
 	if idx == len(b.lines) {
 		b.lines = append(b.lines, content)
@@ -157,11 +203,14 @@ func (b *buffer) deleteLine(idx int) string {
 	}
 
 	line := b.lines[idx]
+	b.touch()
 
 	// Keep at least one line in the buffer
 	if len(b.lines) > 1 {
+		b.record(idx, idx+1, 0)
 		b.lines = slices.Delete(b.lines, idx, idx+1)
 	} else {
+		b.record(0, 1, 1)
 		b.lines[0] = ""
 	}
 
@@ -170,7 +219,9 @@ func (b *buffer) deleteLine(idx int) string {
 
 // clear removes all content from the buffer and resets to a single empty line
 func (b *buffer) clear() {
+	b.record(0, len(b.lines), 1)
 	b.lines = []string{""}
+	b.touch()
 }
 
 // insertAt inserts text at the specified position
@@ -188,7 +239,9 @@ func (b *buffer) insertAt(row, col int, text string) {
 	lines := strings.Split(text, "\n")
 	if len(lines) == 1 {
 		// Simple case: inserting text within a single line
+		b.record(row, row+1, 1)
 		b.lines[row] = line[:col] + text + line[col:]
+		b.touch()
 	} else {
 		// Complex case: inserting multiple lines
 		// This splits the current line at the insertion point
@@ -199,7 +252,9 @@ func (b *buffer) insertAt(row, col int, text string) {
 		lastLineText := lines[len(lines)-1] + line[col:]
 
 		// Replace current line with first line of result
+		b.record(row, row+1, 1)
 		b.lines[row] = firstLineText
+		b.touch()
 
 		// Insert all middle lines (if any)
 		insertPos := row + 1
@@ -252,7 +307,7 @@ func (b *buffer) saveUndoState(cursor Cursor) {
 	copy(contentCopy, b.lines)
 
 	// Add the current state to the undo stack
-	b.undoStack = append(b.undoStack, bufferState{lines: contentCopy, cursor: cursor})
+	b.undoStack = append(b.undoStack, bufferState{lines: contentCopy, cursor: cursor, state: b.state})
 
 	// Clear the redo stack since we've made a new change
 	b.redoStack = []bufferState{}
@@ -286,10 +341,13 @@ func (b *buffer) undo(c Cursor) tea.Cmd {
 		b.redoStack = append(b.redoStack, bufferState{
 			lines:  contentCopy,
 			cursor: c,
+			state:  b.state,
 		})
 
 		// Restore the previous state
-		b.lines = lastState.lines
+		b.restoreLines(lastState.lines)
+		b.touch()
+		b.state = lastState.state
 
 		// Return a message with the new cursor position
 		return UndoRedoMsg{
@@ -322,10 +380,13 @@ func (b *buffer) redo(c Cursor) tea.Cmd {
 		b.undoStack = append(b.undoStack, bufferState{
 			lines:  contentCopy,
 			cursor: c,
+			state:  b.state,
 		})
 
 		// Restore the state from redo stack
-		b.lines = lastState.lines
+		b.restoreLines(lastState.lines)
+		b.touch()
+		b.state = lastState.state
 
 		// Return a message with the new cursor position
 		return UndoRedoMsg{
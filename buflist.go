@@ -0,0 +1,420 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// BufferInfo describes a buffer of the buffer list
+type BufferInfo struct {
+	Number   int    // Buffer number used by :b and :bd
+	Name     string // File of the buffer, empty when it has none
+	FileType string // Language of the syntax highlighting
+	Modified bool   // Whether the buffer has unsaved changes
+	Lines    int    // Number of lines, 0 while the buffer is unloaded
+}
+
+// modified reports whether the document has unsaved changes
+func (d *document) modified() bool {
+	return d.buffer != nil && d.buffer.modified()
+}
+
+// name returns the name shown for the document in messages and lists
+func (d *document) name() string {
+	if d.filePath == "" {
+		return "[No Name]"
+	}
+	return d.filePath
+}
+
+// unload drops the text of a document, discarding its changes. It is read
+// from its file again when it is shown.
+func (d *document) unload() {
+	d.buffer = nil
+	d.signs = nil
+	d.diagnostics = nil
+	d.version = 0
+}
+
+// info returns the public description of a document
+func (d *document) info() BufferInfo {
+	info := BufferInfo{
+		Number:   d.number,
+		Name:     d.filePath,
+		FileType: d.highlighter.fileType(),
+		Modified: d.modified(),
+	}
+	if d.buffer != nil {
+		info.Lines = d.buffer.lineCount()
+	}
+	return info
+}
+
+// newDocument adds a document with an empty buffer to the buffer list
+func (m *editorModel) newDocument(path string) *document {
+	m.nextBufferNumber++
+	b := newBuffer("")
+	b.tabs = m.buffer.tabs
+	d := &document{
+		number:      m.nextBufferNumber,
+		buffer:      b,
+		filePath:    path,
+		highlighter: newSyntaxHighlighter(m.theme.Syntax, path),
+		version:     b.version,
+	}
+	m.docs = append(m.docs, d)
+	return d
+}
+
+// findDocument returns the document of a buffer number, or nil
+func (m *editorModel) findDocument(number int) *document {
+	for _, d := range m.docs {
+		if d.number == number {
+			return d
+		}
+	}
+	return nil
+}
+
+// fileDocument returns the document of a file, or nil
+func (m *editorModel) fileDocument(path string) *document {
+	m.storeWindow()
+	for _, d := range m.docs {
+		if d.filePath == path {
+			return d
+		}
+	}
+	return nil
+}
+
+// openFile shows the buffer of a file in the current window, reading the
+// file into a new buffer when no buffer has it yet
+func (m *editorModel) openFile(path string) error {
+	if d := m.fileDocument(path); d != nil {
+		m.showDocument(d)
+		return nil
+	}
+	b, status, err := m.readFile(path)
+	if err != nil {
+		return err
+	}
+	b.tabs = m.buffer.tabs
+	b.markSaved()
+	d := m.newDocument(path)
+	d.buffer, d.version = b, b.version
+	m.showDocument(d)
+	m.statusMessage = status
+	return nil
+}
+
+// showDocument shows a document in the current window at its last cursor
+// position. The document shown before becomes the alternate buffer and
+// keeps its changes. An unloaded document is read from its file again.
+func (m *editorModel) showDocument(d *document) {
+	if d == m.win.doc {
+		return
+	}
+	if m.mode == ModeVisual {
+		m.mode = ModeNormal
+	}
+	m.endSnippet()
+	m.completion = nil
+	m.fireAutocmd(EventBufLeave, AutocmdArgs{})
+	m.storeWindow()
+
+	reload := d.buffer == nil
+	if reload {
+		d.buffer = newBuffer("")
+		d.buffer.tabs = m.buffer.tabs
+	}
+	w := m.win
+	w.alt, w.doc = w.doc, d
+	w.cursor, w.desiredCol = d.cursor, d.cursor.Col
+	w.yOffset, w.xOffset = 0, 0
+	w.visualStart, w.isVisualLine = Cursor{}, false
+	m.loadWindow(w)
+	if reload && d.filePath != "" {
+		if err := m.loadFile(d.filePath); err != nil {
+			m.statusMessage = err.Error()
+		}
+	}
+	m.ensureCursorVisible()
+	m.fireAutocmd(EventBufEnter, AutocmdArgs{})
+}
+
+// switchDocument shows another buffer in the current window, refusing to
+// hide unsaved changes no other window shows unless force is set
+func (m *editorModel) switchDocument(d *document, force bool) error {
+	if d == m.win.doc {
+		return nil
+	}
+	if !force && m.abandons(m.win) {
+		return errors.New("E37: No write since last change (add ! to override)")
+	}
+	m.showDocument(d)
+	return nil
+}
+
+// deleteDocument removes a buffer from the buffer list. Windows showing it
+// switch to their alternate buffer or to a neighbour in the list, and an
+// empty buffer takes its place when it was the last one.
+func (m *editorModel) deleteDocument(d *document, force bool) error {
+	m.storeWindow()
+	if !force && d.modified() {
+		return fmt.Errorf("E89: No write since last change for buffer %d (add ! to override)", d.number)
+	}
+
+	i := slices.Index(m.docs, d)
+	m.docs = slices.Delete(m.docs, i, i+1)
+	if len(m.docs) == 0 {
+		m.newDocument("")
+	}
+	neighbour := m.docs[min(i, len(m.docs)-1)]
+
+	current := m.win
+	for _, w := range m.allWindows() {
+		if w.alt == d {
+			w.alt = nil
+		}
+		if w.doc != d {
+			continue
+		}
+		next := neighbour
+		if w.alt != nil && slices.Contains(m.docs, w.alt) {
+			next = w.alt
+		}
+		if w == current {
+			m.showDocument(next)
+			m.win.alt = nil
+			continue
+		}
+		w.doc, w.alt = next, nil
+		w.cursor, w.desiredCol, w.yOffset, w.xOffset = next.cursor, next.cursor.Col, 0, 0
+	}
+	return nil
+}
+
+// documentArg returns the buffer named by a command argument: a number,
+// "%" for the current buffer, "#" for the alternate one or a unique part
+// of a file name
+func (m *editorModel) documentArg(arg string) (*document, error) {
+	switch arg {
+	case "%":
+		return m.win.doc, nil
+	case "#":
+		if m.win.alt == nil {
+			return nil, errors.New("E23: No alternate file")
+		}
+		return m.win.alt, nil
+	}
+	if n, err := strconv.Atoi(arg); err == nil {
+		if d := m.findDocument(n); d != nil {
+			return d, nil
+		}
+		return nil, fmt.Errorf("E86: Buffer %d does not exist", n)
+	}
+
+	m.storeWindow()
+	var matches []*document
+	for _, d := range m.docs {
+		if d.filePath == arg {
+			return d, nil
+		}
+		if strings.Contains(d.filePath, arg) {
+			matches = append(matches, d)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("E94: No matching buffer for %s", arg)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("E93: More than one match for %s", arg)
+	}
+}
+
+// cycleDocument shows the buffer count places after the current one in
+// the buffer list, before it when count is negative
+func (m *editorModel) cycleDocument(count int, force bool) error {
+	i := slices.Index(m.docs, m.win.doc)
+	n := len(m.docs)
+	return m.switchDocument(m.docs[((i+count)%n+n)%n], force)
+}
+
+// OpenBuffer shows the buffer of a file in the current window, reading
+// the file into a new buffer when no buffer has it yet, and returns its
+// number. The buffer shown before keeps its changes.
+func (m *editorModel) OpenBuffer(path string) (int, error) {
+	if err := m.openFile(path); err != nil {
+		return 0, err
+	}
+	return m.win.doc.number, nil
+}
+
+// SwitchBuffer shows a buffer of the buffer list in the current window.
+// The buffer shown before keeps its changes.
+func (m *editorModel) SwitchBuffer(number int) error {
+	d := m.findDocument(number)
+	if d == nil {
+		return fmt.Errorf("E86: Buffer %d does not exist", number)
+	}
+	m.showDocument(d)
+	return nil
+}
+
+// DeleteBuffer removes a buffer from the buffer list. Unsaved changes are
+// only discarded when force is set.
+func (m *editorModel) DeleteBuffer(number int, force bool) error {
+	d := m.findDocument(number)
+	if d == nil {
+		return fmt.Errorf("E86: Buffer %d does not exist", number)
+	}
+	return m.deleteDocument(d, force)
+}
+
+// Buffers returns the buffer list ordered by number
+func (m *editorModel) Buffers() []BufferInfo {
+	m.storeWindow()
+	infos := make([]BufferInfo, 0, len(m.docs))
+	for _, d := range m.docs {
+		infos = append(infos, d.info())
+	}
+	return infos
+}
+
+// CurrentBuffer returns the buffer shown in the current window
+func (m *editorModel) CurrentBuffer() BufferInfo {
+	m.storeWindow()
+	return m.win.doc.info()
+}
+
+// registerBufferCommands registers the commands of the buffer list
+func registerBufferCommands(m *editorModel) {
+	for _, name := range []string{"ls", "buffers", "files"} {
+		m.commands.Register(name, listBuffers)
+	}
+	for _, name := range []string{"b", "buffer"} {
+		m.commands.Register(name, bufferCommand)
+	}
+	for _, name := range []string{"bn", "bnext"} {
+		m.commands.Register(name, bufferCycleCommand(1))
+	}
+	for _, name := range []string{"bp", "bprevious", "bN", "bNext"} {
+		m.commands.Register(name, bufferCycleCommand(-1))
+	}
+	for _, name := range []string{"bd", "bdelete"} {
+		m.commands.Register(name, bufferDeleteCommand)
+	}
+	m.registry.Add("ctrl+^", alternateBuffer, ModeNormal, "Edit the alternate buffer, or buffer N")
+}
+
+// listBuffers implements :ls. The flags are those of Vim: % for the
+// current buffer, # for the alternate one, a for buffers shown in a
+// window, h for hidden ones and + for unsaved changes.
+func listBuffers(model *editorModel) tea.Cmd {
+	model.storeWindow()
+	shown := make(map[*document]bool)
+	for _, w := range model.allWindows() {
+		shown[w.doc] = true
+	}
+
+	lines := make([]string, 0, len(model.docs))
+	for _, d := range model.docs {
+		flags := []byte("   ")
+		switch d {
+		case model.win.doc:
+			flags[0] = '%'
+		case model.win.alt:
+			flags[0] = '#'
+		}
+		if shown[d] {
+			flags[1] = 'a'
+		} else if d.buffer != nil {
+			flags[1] = 'h'
+		}
+		if d.modified() {
+			flags[2] = '+'
+		}
+		lines = append(lines, fmt.Sprintf("%3d %s %-20s line %d", d.number, flags, strconv.Quote(d.name()), d.cursor.Row+1))
+	}
+	model.showPopup(lines, false)
+	return nil
+}
+
+// bufferCommand implements :b[uffer][!] {N|name|#}
+func bufferCommand(model *editorModel) tea.Cmd {
+	bang, args := model.commandLine()
+	if len(args) == 0 {
+		return nil
+	}
+	d, err := model.documentArg(args[0])
+	if err == nil {
+		err = model.switchDocument(d, bang)
+	}
+	if err != nil {
+		return SetStatusMsg(err.Error())
+	}
+	return nil
+}
+
+// bufferCycleCommand implements :bn[ext][!] [N] and :bp[revious][!] [N]
+func bufferCycleCommand(dir int) Command {
+	return func(model *editorModel) tea.Cmd {
+		bang, args := model.commandLine()
+		count := 1
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return SetStatusMsg("E475: Invalid argument: " + args[0])
+			}
+			count = n
+		}
+		if err := model.cycleDocument(dir*count, bang); err != nil {
+			return SetStatusMsg(err.Error())
+		}
+		return nil
+	}
+}
+
+// bufferDeleteCommand implements :bd[elete][!] [N|name ...], deleting the
+// current buffer without arguments
+func bufferDeleteCommand(model *editorModel) tea.Cmd {
+	bang, args := model.commandLine()
+	if len(args) == 0 {
+		args = []string{"%"}
+	}
+	for _, arg := range args {
+		d, err := model.documentArg(arg)
+		if err == nil {
+			err = model.deleteDocument(d, bang)
+		}
+		if err != nil {
+			return SetStatusMsg(err.Error())
+		}
+	}
+	return nil
+}
+
+// alternateBuffer implements <C-^>, going to buffer N with a count
+func alternateBuffer(model *editorModel) tea.Cmd {
+	arg := "#"
+	if model.countPrefix > 1 {
+		arg = strconv.Itoa(model.countPrefix)
+	}
+	model.countPrefix = 1
+	d, err := model.documentArg(arg)
+	if err == nil {
+		err = model.switchDocument(d, false)
+	}
+	if err != nil {
+		return SetStatusMsg(err.Error())
+	}
+	return nil
+}
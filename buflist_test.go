@@ -0,0 +1,108 @@
+package vimtea
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferList(t *testing.T) {
+	fsys := NewMemFileSystem(map[string]string{"a.go": "package a", "b.txt": "beta\nbeta", "c.txt": "gamma"})
+	model := NewEditor(WithFile("a.go"), WithFileSystem(fsys)).(*editorModel)
+
+	runCommand(t, model, "e b.txt")
+	sendKeys(model, "j")
+	assert.Equal(t, []BufferInfo{
+		{Number: 1, Name: "a.go", FileType: "go", Lines: 1},
+		{Number: 2, Name: "b.txt", FileType: "plaintext", Lines: 2},
+	}, model.Buffers(), ":e should add a buffer")
+	assert.Equal(t, 2, model.CurrentBuffer().Number)
+
+	pressKey(model, tea.KeyCtrlCaret)
+	assert.Equal(t, "package a", model.buffer.text(), "<C-^> should edit the alternate buffer")
+	sendKeys(model, "x")
+
+	msgs := runCommand(t, model, "bn")
+	assert.Contains(t, msgs, statusMessageMsg("E37: No write since last change (add ! to override)"))
+	runCommand(t, model, "bn!")
+	assert.Equal(t, "beta\nbeta", model.buffer.text(), ":bn! should hide the modified buffer")
+	assert.Equal(t, Cursor{1, 0}, model.cursor, "The buffer should come back at its last position")
+
+	runCommand(t, model, "ls")
+	require.NotNil(t, model.float(model.popup), ":ls should list the buffers")
+	assert.Equal(t, []string{
+		`  1 #h+ "a.go"               line 1`,
+		`  2 %a  "b.txt"              line 2`,
+	}, model.float(model.popup).lines)
+
+	runCommand(t, model, "b a.go")
+	assert.Equal(t, "ackage a", model.buffer.text(), "Hidden buffers should keep their changes")
+	model.buffer.undo(model.cursor)()
+	assert.Equal(t, "package a", model.buffer.text(), "Each buffer should have its own undo history")
+	sendKeys(model, "x")
+
+	number, err := model.OpenBuffer("c.txt")
+	require.NoError(t, err)
+	assert.Equal(t, 3, number)
+	assert.Equal(t, "gamma", model.buffer.text())
+	_, err = model.OpenBuffer("a.go")
+	require.NoError(t, err)
+	assert.Equal(t, "ackage a", model.buffer.text(), "OpenBuffer should switch to the buffer that has the file")
+
+	msgs = runCommand(t, model, "bd")
+	assert.Contains(t, msgs, statusMessageMsg("E89: No write since last change for buffer 1 (add ! to override)"))
+	runCommand(t, model, "bd!")
+	assert.Equal(t, []int{2, 3}, bufferNumbers(model), ":bd! should delete the buffer")
+	assert.Equal(t, "gamma", model.buffer.text(), "The window should show its alternate buffer")
+
+	sendKeys(model, "2")
+	pressKey(model, tea.KeyCtrlCaret)
+	assert.Equal(t, 2, model.CurrentBuffer().Number, "A count should edit that buffer")
+
+	msgs = runCommand(t, model, "b 9")
+	assert.Contains(t, msgs, statusMessageMsg("E86: Buffer 9 does not exist"))
+	msgs = runCommand(t, model, "b txt")
+	assert.Contains(t, msgs, statusMessageMsg("E93: More than one match for txt"))
+}
+
+// bufferNumbers returns the numbers of the buffer list
+func bufferNumbers(model *editorModel) []int {
+	var numbers []int
+	for _, b := range model.Buffers() {
+		numbers = append(numbers, b.Number)
+	}
+	return numbers
+}
+
+func TestBuffersInWindows(t *testing.T) {
+	fsys := NewMemFileSystem(map[string]string{"a.txt": "alpha", "b.txt": "beta"})
+	model := NewEditor(WithFile("a.txt"), WithFileSystem(fsys)).(*editorModel)
+	model.SetSize(30, 8)
+
+	runCommand(t, model, "vsplit b.txt")
+	runCommand(t, model, "bd 1")
+	assert.Equal(t, []int{2}, bufferNumbers(model))
+	for _, w := range model.Windows() {
+		assert.Equal(t, "b.txt", w.File, "Windows should leave a deleted buffer")
+	}
+
+	runCommand(t, model, "bd")
+	require.Equal(t, []BufferInfo{{Number: 3, Lines: 1}}, model.Buffers(),
+		"Deleting the last buffer should leave an empty one")
+	assert.Equal(t, " [No Name]", ansi.Strip(model.renderWindowStatus(true))[:10])
+}
+
+func TestEditDiscardsChanges(t *testing.T) {
+	fsys := NewMemFileSystem(map[string]string{"a.txt": "alpha", "b.txt": "beta"})
+	model := NewEditor(WithFile("a.txt"), WithFileSystem(fsys)).(*editorModel)
+
+	sendKeys(model, "x")
+	runCommand(t, model, "e! b.txt")
+	assert.False(t, model.Buffers()[0].Modified, ":e! should discard the changes of the buffer it leaves")
+	runCommand(t, model, "b 1")
+	assert.Equal(t, "alpha", model.buffer.text(), "The discarded buffer should be read again")
+	assert.Equal(t, "a.txt", model.filePath)
+}
@@ -34,6 +34,7 @@ func withCountPrefix(model *editorModel, fn func()) {
 // switchMode changes the editor mode and performs necessary setup for the new mode
 // Different modes require different cursor handling and UI state
 func switchMode(model *editorModel, newMode EditorMode) tea.Cmd {
+	oldMode := model.mode
 	model.mode = newMode
 
 	switch newMode {
@@ -50,6 +51,17 @@ func switchMode(model *editorModel, newMode EditorMode) tea.Cmd {
 		model.commandBuffer = ""
 	}
 
+	if oldMode != newMode {
+		args := AutocmdArgs{OldMode: oldMode, NewMode: newMode}
+		if oldMode == ModeInsert {
+			model.fireAutocmd(EventInsertLeave, args)
+		}
+		if newMode == ModeInsert {
+			model.fireAutocmd(EventInsertEnter, args)
+		}
+		model.fireAutocmd(EventModeChanged, args)
+	}
+
 	return func() tea.Msg {
 		return EditorModeMsg{newMode}
 	}
@@ -88,24 +100,13 @@ func registerBindings(m *editorModel) {
 	m.registry.Add("cw", changeInnerWord, ModeNormal, "Change word")
 
 	for _, mode := range []EditorMode{ModeNormal, ModeVisual} {
-		m.registry.Add("h", moveCursorLeft, mode, "Move cursor left")
-		m.registry.Add("j", moveCursorDown, mode, "Move cursor down")
-		m.registry.Add("k", moveCursorUp, mode, "Move cursor up")
-		m.registry.Add("l", moveCursorRight, mode, "Move cursor right")
-		m.registry.Add("w", moveToNextWordStart, mode, "Move to next word")
-		m.registry.Add("b", moveToPrevWordStart, mode, "Move to previous word")
-
-		m.registry.Add(" ", moveCursorRightOrNextLine, mode, "Move cursor right")
-		m.registry.Add("0", moveToStartOfLine, mode, "Move to start of line")
-		m.registry.Add("^", moveToFirstNonWhitespace, mode, "Move to first non-whitespace character")
-		m.registry.Add("$", moveToEndOfLine, mode, "Move to end of line")
-		m.registry.Add("gg", moveToStartOfDocument, mode, "Move to document start")
-		m.registry.Add("G", moveToEndOfDocument, mode, "Move to document end")
-
-		m.registry.Add("up", moveCursorUp, mode, "Move cursor up")
-		m.registry.Add("down", moveCursorDown, mode, "Move cursor down")
-		m.registry.Add("left", moveCursorLeft, mode, "Move cursor left")
-		m.registry.Add("right", moveCursorRight, mode, "Move cursor right")
+		for _, mo := range motions {
+			m.registry.Add(mo.key, mo.command, mode, mo.help)
+		}
+		m.registry.Add("zh", scrollLeft, mode, "Scroll view left")
+		m.registry.Add("zl", scrollRight, mode, "Scroll view right")
+		m.registry.Add("zs", scrollCursorToStart, mode, "Scroll cursor to start of screen")
+		m.registry.Add("ze", scrollCursorToEnd, mode, "Scroll cursor to end of screen")
 	}
 
 	m.registry.Add("esc", exitModeVisual, ModeVisual, "Exit visual mode")
@@ -119,7 +120,7 @@ func registerBindings(m *editorModel) {
 
 	m.registry.Add("esc", exitModeInsert, ModeInsert, "Exit insert mode")
 	m.registry.Add("backspace", handleInsertBackspace, ModeInsert, "Backspace")
-	m.registry.Add("tab", handleInsertTab, ModeInsert, "Tab")
+	m.registry.Add("tab", snippetTab, ModeInsert, "Tab, or expand or continue a snippet")
 	m.registry.Add("enter", handleInsertEnterKey, ModeInsert, "Enter")
 	m.registry.Add("up", handleArrowKeys("up"), ModeInsert, "Move cursor up")
 	m.registry.Add("down", handleArrowKeys("down"), ModeInsert, "Move cursor down")
@@ -133,10 +134,26 @@ func registerBindings(m *editorModel) {
 	m.commands.Register("zr", toggleRelativeLineNumbers)
 	m.commands.Register("clear", clearBuffer)
 	m.commands.Register("reset", resetEditor)
+	m.commands.Register("set", setCommand)
+	m.commands.Register("se", setCommand)
+	m.commands.Register("colorscheme", colorschemeCommand)
+	m.commands.Register("colo", colorschemeCommand)
+
+	registerIndentBindings(m)
+	registerFileCommands(m)
+	registerQuitCommands(m)
+	registerWindowCommands(m)
+	registerBufferCommands(m)
+	registerTabCommands(m)
+	registerDiagnosticBindings(m)
+	registerCompletionBindings(m)
+	registerSnippetBindings(m)
+	registerWindowBindings(m)
+	registerMarkBindings(m)
 }
 
 func toggleRelativeLineNumbers(model *editorModel) tea.Cmd {
-	model.relativeNumbers = !model.relativeNumbers
+	_ = model.SetOption("relativenumber", !model.relativeNumbers)
 	if model.relativeNumbers {
 		return SetStatusMsg("relative line numbers: on")
 	} else {
@@ -184,8 +201,7 @@ func deleteToEndOfLine(model *editorModel) tea.Cmd {
 		start := Cursor{Row: row, Col: col}
 		end := Cursor{Row: row, Col: len(line) - 1}
 
-		model.yankBuffer = model.buffer.deleteRange(start, end)
-		clipboard.Write(clipboard.FmtText, []byte(model.yankBuffer))
+		storeYank(model, model.buffer.deleteRange(start, end), "d")
 	}
 
 	return nil
@@ -200,6 +216,7 @@ func exitModeVisual(model *editorModel) tea.Cmd {
 }
 
 func exitModeInsert(model *editorModel) tea.Cmd {
+	model.removeAddedIndent()
 	return switchMode(model, ModeNormal)
 }
 
@@ -246,19 +263,25 @@ func openLineBelow(model *editorModel) tea.Cmd {
 	model.buffer.saveUndoState(model.cursor)
 
 	model.buffer.insertLine(model.cursor.Row+1, "")
+	model.indentNewLine(model.cursor.Row+1, model.cursor.Row)
 	model.cursor.Row++
-	model.cursor.Col = 0
+	model.cursor.Col = model.buffer.lineLength(model.cursor.Row)
+	// Insert mode lets the cursor stay after the indentation
+	cmd := switchMode(model, ModeInsert)
 	model.ensureCursorVisible()
-	return switchMode(model, ModeInsert)
+	return cmd
 }
 
 func openLineAbove(model *editorModel) tea.Cmd {
 	model.buffer.saveUndoState(model.cursor)
 
 	model.buffer.insertLine(model.cursor.Row, "")
-	model.cursor.Col = 0
+	model.indentNewLine(model.cursor.Row, model.cursor.Row+1)
+	model.cursor.Col = model.buffer.lineLength(model.cursor.Row)
+	// Insert mode lets the cursor stay after the indentation
+	cmd := switchMode(model, ModeInsert)
 	model.ensureCursorVisible()
-	return switchMode(model, ModeInsert)
+	return cmd
 }
 
 func replaceCurrentCharacter(model *editorModel, char string) (tea.Model, tea.Cmd) {
@@ -288,6 +311,7 @@ func insertCharacter(model *editorModel, char string) (tea.Model, tea.Cmd) {
 	newLine := line[:model.cursor.Col] + char + line[model.cursor.Col:]
 	model.buffer.setLine(model.cursor.Row, newLine)
 	model.cursor.Col++
+	model.indentOnKey(char)
 
 	return model, nil
 }
@@ -295,6 +319,10 @@ func insertCharacter(model *editorModel, char string) (tea.Model, tea.Cmd) {
 func handleInsertBackspace(model *editorModel) tea.Cmd {
 	model.buffer.saveUndoState(model.cursor)
 
+	if deleteSoftTab(model) {
+		return nil
+	}
+
 	if model.cursor.Col > 0 {
 
 		model.buffer.deleteAt(model.cursor.Row, model.cursor.Col-1, model.cursor.Row, model.cursor.Col-1)
@@ -311,18 +339,9 @@ func handleInsertBackspace(model *editorModel) tea.Cmd {
 	return nil
 }
 
-func handleInsertTab(model *editorModel) tea.Cmd {
-	model.buffer.saveUndoState(model.cursor)
-
-	line := model.buffer.Line(model.cursor.Row)
-	newLine := line[:model.cursor.Col] + "\t" + line[model.cursor.Col:]
-	model.buffer.setLine(model.cursor.Row, newLine)
-	model.cursor.Col += 1
-	return nil
-}
-
 func handleInsertEnterKey(m *editorModel) tea.Cmd {
 	m.buffer.saveUndoState(m.cursor)
+	untouched := m.onAddedIndent()
 
 	currentLine := m.buffer.Line(m.cursor.Row)
 	newLine := ""
@@ -333,8 +352,16 @@ func handleInsertEnterKey(m *editorModel) tea.Cmd {
 	}
 
 	m.buffer.insertLine(m.cursor.Row+1, newLine)
+	m.indentNewLine(m.cursor.Row+1, m.cursor.Row)
+	if untouched {
+		// Nothing was typed on the line left behind, so it loses its indent
+		m.buffer.setLine(m.cursor.Row, "")
+	}
 	m.cursor.Row++
 	m.cursor.Col = 0
+	if m.autoIndent || m.smartIndent {
+		m.cursor.Col = m.buffer.lineLength(m.cursor.Row) - len(strings.TrimLeft(newLine, " \t"))
+	}
 	m.ensureCursorVisible()
 	return nil
 }
@@ -444,12 +471,32 @@ func handleArrowKeys(key string) func(*editorModel) tea.Cmd {
 	}
 }
 
+// executeCommand sends a CommandMsg for the command typed in command mode.
+// The full command line stays in the command buffer until the command has
+// run so that it can read its arguments.
 func executeCommand(model *editorModel) tea.Cmd {
-	command := model.commandBuffer
-	model.commandBuffer = ""
+	name, _, _ := parseCommandLine(model.commandBuffer)
 	return func() tea.Msg {
-		return CommandMsg{command}
+		return CommandMsg{name}
+	}
+}
+
+// parseCommandLine splits a command line such as "w! out.txt" into the
+// command name, whether it carries a trailing "!" and its arguments
+func parseCommandLine(line string) (string, bool, []string) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", false, nil
 	}
+	name := fields[0]
+	return name, strings.HasSuffix(name, "!") && len(name) > 1, fields[1:]
+}
+
+// commandLine returns whether the running command was given a "!"
+// and the arguments it was invoked with
+func (m *editorModel) commandLine() (bool, []string) {
+	_, bang, args := parseCommandLine(m.commandBuffer)
+	return bang, args
 }
 
 func addCommandCharacter(model *editorModel, char string) (tea.Model, tea.Cmd) {
@@ -560,9 +607,16 @@ func deleteCharAtCursor(model *editorModel) tea.Cmd {
 	return nil
 }
 
-func setupYankHighlight(model *editorModel, start, end Cursor, text string, isLinewise bool) {
+// storeYank saves text to the yank buffer and the system clipboard
+// and notifies TextYankPost autocommands
+func storeYank(model *editorModel, text string, operator string) {
 	model.yankBuffer = text
-	clipboard.Write(clipboard.FmtText, []byte(model.yankBuffer))
+	clipboard.Write(clipboard.FmtText, []byte(text))
+	model.fireAutocmd(EventTextYankPost, AutocmdArgs{Text: text, Operator: operator})
+}
+
+func setupYankHighlight(model *editorModel, start, end Cursor, text string, isLinewise bool) {
+	storeYank(model, text, "y")
 	model.statusMessage = fmt.Sprintf("yanked %d characters", len(text))
 	model.yankHighlight.Start = start
 	model.yankHighlight.End = end
@@ -591,8 +645,7 @@ func deleteLine(model *editorModel) tea.Cmd {
 
 	row := model.cursor.Row
 	lineContent := model.buffer.Line(row)
-	model.yankBuffer = "\n" + lineContent
-	clipboard.Write(clipboard.FmtText, []byte(model.yankBuffer))
+	storeYank(model, "\n"+lineContent, "d")
 
 	model.buffer.deleteLine(row)
 
@@ -816,8 +869,7 @@ func deleteVisualSelection(model *editorModel) tea.Cmd {
 	if model.isVisualLine {
 		selectedText = "\n" + selectedText
 	}
-	model.yankBuffer = selectedText
-	clipboard.Write(clipboard.FmtText, []byte(model.yankBuffer))
+	storeYank(model, selectedText, "d")
 
 	model.buffer.deleteRange(start, end)
 
@@ -831,8 +883,7 @@ func replaceVisualSelectionWithYank(model *editorModel) tea.Cmd {
 	model.buffer.saveUndoState(model.cursor)
 	start, end := model.GetSelectionBoundary()
 	oldSelection := model.buffer.deleteRange(start, end)
-	model.yankBuffer = oldSelection
-	clipboard.Write(clipboard.FmtText, []byte(model.yankBuffer))
+	storeYank(model, oldSelection, "c")
 
 	model.cursor = start
 
@@ -862,8 +913,7 @@ func performWordOperation(model *editorModel, operation string) tea.Cmd {
 		model.buffer.saveUndoState(model.cursor)
 	}
 
-	model.yankBuffer = word
-	clipboard.Write(clipboard.FmtText, []byte(model.yankBuffer))
+	storeYank(model, word, operation[:1])
 
 	switch operation {
 	case "yank":
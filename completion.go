@@ -0,0 +1,469 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// completionMenuHeight is the maximum number of items shown in the
+// completion menu at once
+const completionMenuHeight = 10
+
+// completionDocWidth is the maximum width of the documentation preview
+const completionDocWidth = 50
+
+// CompletionItem is a proposal shown in the completion menu
+type CompletionItem struct {
+	Label         string // Text shown in the menu and matched against the typed word
+	Kind          string // Short kind shown after the label, e.g. "table" or "func"
+	Detail        string // One line description, e.g. a signature or a column type
+	Documentation string // Longer text previewed next to the menu
+	InsertText    string // Text inserted when accepted, the label if empty
+	Snippet       bool   // Whether InsertText uses snippet syntax such as ${1:name}
+}
+
+// CompletionRequest describes the word a completion was started for
+type CompletionRequest struct {
+	ID     int    // Identifies the completion the results belong to
+	Buffer Buffer // Buffer being edited
+	Cursor Cursor // Cursor position when the completion started
+	Start  Cursor // Start of the word before the cursor
+	Prefix string // Word before the cursor, from Start to Cursor
+}
+
+// Result returns the message that delivers items for the request
+func (r CompletionRequest) Result(items []CompletionItem) CompletionResultMsg {
+	return CompletionResultMsg{ID: r.ID, Items: items}
+}
+
+// CompletionResultMsg delivers the items of a completion source. Items for
+// a completion that has been closed in the meantime are dropped.
+type CompletionResultMsg struct {
+	ID    int
+	Items []CompletionItem
+}
+
+// CompletionSource provides items for the completion menu, for example
+// table names of a database or proposals of a language server
+type CompletionSource interface {
+	// Complete returns a command producing the CompletionResultMsg of the
+	// request, usually made with req.Result, or nil to offer nothing. It is
+	// called when the menu opens; the menu filters the items while typing.
+	Complete(req CompletionRequest) tea.Cmd
+}
+
+// CompletionFunc adapts a function to a CompletionSource
+type CompletionFunc func(req CompletionRequest) tea.Cmd
+
+// Complete calls the function
+func (f CompletionFunc) Complete(req CompletionRequest) tea.Cmd {
+	return f(req)
+}
+
+// WithCompletionSource adds a source of completion items to the words of
+// the buffer offered by <C-n> and <C-p>
+func WithCompletionSource(source CompletionSource) EditorOption {
+	return func(o *options) {
+		o.CompletionSources = append(o.CompletionSources, source)
+	}
+}
+
+// AddCompletionSource adds a source of completion items
+func (m *editorModel) AddCompletionSource(source CompletionSource) {
+	m.completionSources = append(m.completionSources, source)
+}
+
+// completion is the state of the open completion menu
+type completion struct {
+	request  CompletionRequest
+	items    []CompletionItem // Items received so far, buffer words first
+	typed    string           // Word the matches were filtered for
+	matches  []int            // Indexes of the items matching typed, best first
+	selected int              // Index into matches, -1 while none is selected
+	top      int              // First match shown in the menu
+	pending  int              // Sources that have not answered yet
+	choice   bool             // Whether the items are the choices of a snippet tabstop
+}
+
+// registerCompletionBindings registers <C-n> and <C-p> in insert mode
+func registerCompletionBindings(m *editorModel) {
+	m.registry.Add("ctrl+n", completeNext, ModeInsert, "Complete the word before the cursor")
+	m.registry.Add("ctrl+p", completePrev, ModeInsert, "Complete the word before the cursor, last match first")
+}
+
+func completeNext(m *editorModel) tea.Cmd {
+	return m.startCompletion(false)
+}
+
+func completePrev(m *editorModel) tea.Cmd {
+	return m.startCompletion(true)
+}
+
+// startCompletion opens the completion menu with the words of the buffer
+// and asks the completion sources for their items
+func (m *editorModel) startCompletion(last bool) tea.Cmd {
+	start := m.wordStart()
+	m.nextCompletionID++
+	req := CompletionRequest{
+		ID:     m.nextCompletionID,
+		Buffer: m.GetBuffer(),
+		Cursor: m.cursor,
+		Start:  start,
+		Prefix: m.buffer.Line(start.Row)[start.Col:m.cursor.Col],
+	}
+	c := &completion{request: req, items: append(m.bufferWords(start), m.snippetItems()...), selected: -1}
+
+	var cmds []tea.Cmd
+	for _, source := range m.completionSources {
+		if cmd := source.Complete(req); cmd != nil {
+			cmds = append(cmds, cmd)
+			c.pending++
+		}
+	}
+
+	c.filter(req.Prefix)
+	if len(c.matches) == 0 && c.pending == 0 {
+		m.statusMessage = "Pattern not found"
+		return nil
+	}
+	m.completion = c
+	if last {
+		c.selectMatch(len(c.matches) - 1)
+	} else {
+		c.selectMatch(0)
+	}
+	return tea.Batch(cmds...)
+}
+
+// wordStart returns the start of the keyword characters before the cursor
+func (m *editorModel) wordStart() Cursor {
+	line := m.buffer.Line(m.cursor.Row)
+	col := min(m.cursor.Col, len(line))
+	for col > 0 {
+		r, size := utf8.DecodeLastRuneInString(line[:col])
+		if !isKeywordRune(r) {
+			break
+		}
+		col -= size
+	}
+	return Cursor{Row: m.cursor.Row, Col: col}
+}
+
+// isKeywordRune reports whether a rune is part of a word for completion
+func isKeywordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// bufferWords returns the words of the buffer as completion items, nearest
+// after the cursor first and wrapping around like Vim's <C-n>. The word
+// being completed is left out.
+func (m *editorModel) bufferWords(skip Cursor) []CompletionItem {
+	var items []CompletionItem
+	seen := map[string]bool{}
+	count := m.buffer.lineCount()
+	for i := range count {
+		row := (skip.Row + i) % count
+		line := m.buffer.Line(row)
+		for col := 0; col < len(line); {
+			r, size := utf8.DecodeRuneInString(line[col:])
+			if !isKeywordRune(r) {
+				col += size
+				continue
+			}
+			end := col
+			for end < len(line) {
+				r, size := utf8.DecodeRuneInString(line[end:])
+				if !isKeywordRune(r) {
+					break
+				}
+				end += size
+			}
+			word := line[col:end]
+			if !seen[word] && (Cursor{Row: row, Col: col}) != skip {
+				seen[word] = true
+				items = append(items, CompletionItem{Label: word})
+			}
+			col = end
+		}
+	}
+	return items
+}
+
+// filter matches the items against a typed word, keeping the selected
+// item selected when it still matches
+func (c *completion) filter(typed string) {
+	selected := -1
+	if c.selected >= 0 {
+		selected = c.matches[c.selected]
+	}
+
+	type scored struct {
+		index, score int
+	}
+	var found []scored
+	for i, item := range c.items {
+		if score, ok := fuzzyScore(typed, item.Label); ok {
+			found = append(found, scored{i, score})
+		}
+	}
+	slices.SortStableFunc(found, func(a, b scored) int { return b.score - a.score })
+
+	c.typed = typed
+	c.matches = c.matches[:0]
+	c.selected = -1
+	for i, f := range found {
+		c.matches = append(c.matches, f.index)
+		if f.index == selected {
+			c.selected = i
+		}
+	}
+	c.selectMatch(c.selected)
+}
+
+// selectMatch selects a match and scrolls the menu to it
+func (c *completion) selectMatch(i int) {
+	if i < 0 || i >= len(c.matches) {
+		c.selected = -1
+		c.top = 0
+		return
+	}
+	c.selected = i
+	if i < c.top {
+		c.top = i
+	} else if i >= c.top+completionMenuHeight {
+		c.top = i - completionMenuHeight + 1
+	}
+}
+
+// move moves the selection by delta, passing through "no selection"
+// between the last and the first match like Vim
+func (c *completion) move(delta int) {
+	n := len(c.matches) + 1
+	pos := (c.selected + 1 + delta%n + n) % n
+	c.selectMatch(pos - 1)
+}
+
+// fuzzyScore matches pattern against text as a case-insensitive
+// subsequence. Matches at the start, at word boundaries and in runs score
+// higher; ok is false when pattern does not match.
+func fuzzyScore(pattern, text string) (score int, ok bool) {
+	if pattern == "" {
+		return 0, true
+	}
+	p := []rune(strings.ToLower(pattern))
+	runes := []rune(text)
+	pi, prev := 0, -2
+	for i, r := range runes {
+		if pi == len(p) {
+			break
+		}
+		if unicode.ToLower(r) != p[pi] {
+			continue
+		}
+		score++
+		switch {
+		case i == 0:
+			score += 10
+		case prev == i-1:
+			score += 5
+		case runes[i-1] == '_' || (unicode.IsLower(runes[i-1]) && unicode.IsUpper(r)):
+			score += 3
+		}
+		if pi == 0 {
+			score -= i
+		}
+		prev = i
+		pi++
+	}
+	return score, pi == len(p)
+}
+
+// completionKey handles the keys of the open completion menu and reports
+// whether the key was used
+func (m *editorModel) completionKey(key string) (bool, tea.Cmd) {
+	c := m.completion
+	switch key {
+	case "ctrl+n", "down":
+		c.move(1)
+	case "ctrl+p", "up":
+		c.move(-1)
+	case "ctrl+y", "enter":
+		if c.selected < 0 {
+			m.completion = nil
+			return key == "ctrl+y", nil
+		}
+		item := c.items[c.matches[c.selected]]
+		m.completion = nil
+		return true, m.acceptCompletion(c.request.Start, item)
+	case "ctrl+e":
+		m.completion = nil
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// acceptCompletion replaces the word before the cursor with an item
+func (m *editorModel) acceptCompletion(start Cursor, item CompletionItem) tea.Cmd {
+	text := item.InsertText
+	if text == "" {
+		text = item.Label
+	}
+	if item.Snippet {
+		return m.insertSnippet(start, text)
+	}
+
+	m.buffer.saveUndoState(m.cursor)
+	m.cursor = m.buffer.replaceRange(start, m.cursor, text)
+	m.desiredCol = m.cursor.Col
+	m.ensureCursorVisible()
+	return nil
+}
+
+// refreshCompletion filters the menu after a key press and closes it when
+// the cursor has left the word or nothing matches anymore
+func (m *editorModel) refreshCompletion() {
+	c := m.completion
+	if c == nil {
+		return
+	}
+	start := c.request.Start
+	if m.mode != ModeInsert || m.cursor.Row != start.Row || m.cursor.Col < start.Col {
+		m.completion = nil
+		return
+	}
+	typed := m.buffer.Line(start.Row)[start.Col:min(m.cursor.Col, m.buffer.lineLength(start.Row))]
+	if !c.choice && strings.IndexFunc(typed, func(r rune) bool { return !isKeywordRune(r) }) >= 0 {
+		m.completion = nil
+		return
+	}
+	if typed != c.typed {
+		c.filter(typed)
+	}
+	if len(c.matches) == 0 && c.pending == 0 {
+		m.completion = nil
+	}
+}
+
+// addCompletionResult adds the items of a source to the open menu
+func (m *editorModel) addCompletionResult(msg CompletionResultMsg) {
+	c := m.completion
+	if c == nil || msg.ID != c.request.ID {
+		return
+	}
+	c.pending--
+	c.items = append(c.items, msg.Items...)
+	empty := len(c.matches) == 0
+	c.filter(c.typed)
+	if empty {
+		// Select the first item when these are the first matches
+		c.selectMatch(0)
+	}
+	if len(c.matches) == 0 && c.pending == 0 {
+		m.completion = nil
+	}
+}
+
+// completionFloats returns the completion menu below the word being
+// completed, and the documentation of the selected item beside it, as
+// floats for an area
+func (m *editorModel) completionFloats(areaWidth, areaHeight int) []*floatWindow {
+	c := m.completion
+	if c == nil || len(c.matches) == 0 {
+		return nil
+	}
+
+	visible := c.matches[c.top:min(c.top+completionMenuHeight, len(c.matches))]
+	labelWidth, kindWidth := 0, 0
+	for _, i := range visible {
+		labelWidth = max(labelWidth, ansi.StringWidth(c.items[i].Label))
+		kindWidth = max(kindWidth, ansi.StringWidth(c.items[i].Kind))
+	}
+	width := labelWidth + 2
+	if kindWidth > 0 {
+		width += kindWidth + 1
+	}
+	width = min(width, areaWidth)
+
+	rows := make([]string, 0, len(visible))
+	for n, i := range visible {
+		item := c.items[i]
+		row := " " + item.Label + strings.Repeat(" ", labelWidth-ansi.StringWidth(item.Label)) + " "
+		if kindWidth > 0 {
+			row += item.Kind + strings.Repeat(" ", kindWidth-ansi.StringWidth(item.Kind)) + " "
+		}
+		row = ansi.Truncate(row, width, "…")
+		style := m.theme.Pmenu
+		if c.top+n == c.selected {
+			style = m.theme.PmenuSel
+		}
+		rows = append(rows, style.Width(width).Render(row))
+	}
+	menu := &floatWindow{lines: rows, opts: FloatOptions{
+		Anchor: AnchorBuffer,
+		Pos:    c.request.Start,
+		Row:    1,
+		Col:    -1,
+		ZIndex: completionZIndex,
+		Style:  m.theme.Pmenu,
+	}}
+	floats := []*floatWindow{menu}
+
+	x, y, ok := m.placeFloat(menu, width, len(rows), areaWidth, areaHeight)
+	if ok && c.selected >= 0 {
+		if doc := m.completionDoc(c.items[c.matches[c.selected]], x, width, y, areaWidth); doc != nil {
+			floats = append(floats, doc)
+		}
+	}
+	return floats
+}
+
+// completionDoc returns the detail and documentation of an item in a
+// float to the right of the menu, or to its left when there is no room
+func (m *editorModel) completionDoc(item CompletionItem, menuX, menuWidth, y, areaWidth int) *floatWindow {
+	var parts []string
+	if item.Detail != "" {
+		parts = append(parts, item.Detail)
+	}
+	if item.Documentation != "" {
+		parts = append(parts, item.Documentation)
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+
+	right := areaWidth - menuX - menuWidth
+	left := menuX
+	room := max(right, left) - 2 // Leave room for the border
+	if room < 10 {
+		return nil
+	}
+	text := strings.Join(parts, "\n\n")
+	width := 1
+	for _, line := range strings.Split(text, "\n") {
+		width = max(width, ansi.StringWidth(line))
+	}
+	width = min(width, room, completionDocWidth)
+
+	x := menuX + menuWidth
+	if right < left {
+		x = menuX - width - 2
+	}
+	return &floatWindow{
+		lines: strings.Split(lipgloss.NewStyle().Width(width).Render(text), "\n"),
+		opts: FloatOptions{
+			Anchor: AnchorEditor,
+			Row:    y,
+			Col:    max(x, 0),
+			Border: lipgloss.RoundedBorder(),
+			ZIndex: completionZIndex,
+		},
+	}
+}
@@ -0,0 +1,149 @@
+package vimtea
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// completionRows returns the rendered content with the completion menu as
+// plain text rows
+func completionRows(model *editorModel) []string {
+	content := strings.TrimSuffix(model.renderFloats(model.renderContent()), "\n")
+	rows := strings.Split(ansi.Strip(content), "\n")
+	for i := range rows {
+		rows[i] = strings.TrimRight(rows[i], " ")
+	}
+	return rows
+}
+
+func pressKey(model *editorModel, key tea.KeyType) tea.Cmd {
+	_, cmd := model.Update(tea.KeyMsg{Type: key})
+	return cmd
+}
+
+func TestKeywordCompletion(t *testing.T) {
+	model := newWrapEditor("apple apricot banana\nap", 30, 6)
+	sendKeys(model, "jA")
+
+	pressKey(model, tea.KeyCtrlN)
+	require.NotNil(t, model.completion, "<C-n> should open the completion menu")
+	assert.Equal(t, []string{
+		"apple apricot banana",
+		"ap",
+		" apple",
+		" apricot",
+		"",
+		"",
+	}, completionRows(model), "The menu should list matching words of the buffer below the word")
+	assert.Equal(t, "apple", model.completion.items[model.completion.matches[model.completion.selected]].Label, "<C-n> should select the first match")
+
+	pressKey(model, tea.KeyCtrlN)
+	pressKey(model, tea.KeyEnter)
+	assert.Nil(t, model.completion, "Accepting should close the menu")
+	assert.Equal(t, "apple apricot banana\napricot", model.buffer.text())
+	assert.Equal(t, Cursor{1, 7}, model.cursor)
+	assert.Equal(t, ModeInsert, model.mode)
+
+	pressKey(model, tea.KeyEnter)
+	assert.Equal(t, "apple apricot banana\napricot\n", model.buffer.text(), "Enter should insert a line break again once the menu is closed")
+}
+
+func TestCompletionFiltering(t *testing.T) {
+	model := newWrapEditor("getStatus lastGist gist\n", 30, 6)
+	sendKeys(model, "jA")
+	sendKeys(model, "g")
+
+	pressKey(model, tea.KeyCtrlN)
+	c := model.completion
+	require.NotNil(t, c)
+	require.Len(t, c.matches, 3)
+
+	sendKeys(model, "st")
+	labels := []string{}
+	for _, i := range c.matches {
+		labels = append(labels, c.items[i].Label)
+	}
+	assert.Equal(t, []string{"getStatus", "gist", "lastGist"}, labels, "Matches should be filtered fuzzily while typing, best first")
+	assert.Equal(t, 0, c.selected, "The selected item should stay selected while it matches")
+
+	sendKeys(model, "x")
+	assert.Nil(t, model.completion, "The menu should close when nothing matches")
+
+	sendKeys(model, " ")
+	pressKey(model, tea.KeyCtrlP)
+	require.NotNil(t, model.completion)
+	assert.Equal(t, 3, model.completion.selected, "<C-p> should select the last match")
+	pressKey(model, tea.KeyCtrlN)
+	assert.Equal(t, -1, model.completion.selected, "Moving past the last match should select nothing")
+	pressKey(model, tea.KeyCtrlE)
+	assert.Nil(t, model.completion, "<C-e> should close the menu")
+	assert.Equal(t, "getStatus lastGist gist\ngstx ", model.buffer.text(), "Closing the menu should keep the typed text")
+
+	sendKeys(model, "q")
+	pressKey(model, tea.KeyCtrlN)
+	assert.Nil(t, model.completion)
+	assert.Equal(t, "Pattern not found", model.statusMessage)
+}
+
+func TestCompletionSource(t *testing.T) {
+	var requests []CompletionRequest
+	source := CompletionFunc(func(req CompletionRequest) tea.Cmd {
+		requests = append(requests, req)
+		return func() tea.Msg {
+			return req.Result([]CompletionItem{
+				{Label: "users", Kind: "table", Detail: "3 columns", Documentation: "Registered accounts"},
+				{Label: "user_roles", Kind: "table"},
+			})
+		}
+	})
+	model := newWrapEditor("select * from us", 60, 8, WithCompletionSource(source))
+	sendKeys(model, "A")
+
+	cmd := pressKey(model, tea.KeyCtrlN)
+	require.Len(t, requests, 1)
+	assert.Equal(t, Cursor{0, 14}, requests[0].Start)
+	assert.Equal(t, "us", requests[0].Prefix)
+	require.NotNil(t, model.completion, "The menu should wait for the sources")
+	assert.Empty(t, model.completion.matches)
+
+	stale := requests[0].Result([]CompletionItem{{Label: "stale"}})
+	stale.ID--
+	model.Update(stale)
+	for _, msg := range collectMsgs(cmd) {
+		model.Update(msg)
+	}
+	require.Len(t, model.completion.matches, 2, "Results of other completions should be dropped")
+	assert.Equal(t, 0, model.completion.selected, "The first result should be selected")
+
+	rows := completionRows(model)
+	assert.Equal(t, "              users      table ╭───────────────────╮", rows[1], "The selected item's documentation should be previewed next to the menu")
+	assert.Equal(t, "              user_roles table │3 columns          │", rows[2])
+	assert.Contains(t, rows[4], "│Registered accounts│")
+
+	pressKey(model, tea.KeyCtrlY)
+	assert.Equal(t, "select * from users", model.buffer.text())
+}
+
+func TestCompletionSnippet(t *testing.T) {
+	source := CompletionFunc(func(req CompletionRequest) tea.Cmd {
+		return func() tea.Msg {
+			return req.Result([]CompletionItem{{Label: "for", InsertText: "for ${1:i} := range ${2:items} {\n\t$0\n}", Snippet: true}})
+		}
+	})
+	model := newWrapEditor("", 40, 8, WithCompletionSource(source))
+	sendKeys(model, "ifo")
+	for _, msg := range collectMsgs(pressKey(model, tea.KeyCtrlN)) {
+		model.Update(msg)
+	}
+	pressKey(model, tea.KeyEnter)
+
+	assert.Equal(t, "for i := range items {\n\t\n}", model.buffer.text(), "Snippets should be inserted with their placeholders")
+	assert.Equal(t, Cursor{0, 5}, model.cursor, "The cursor should be on the first tabstop")
+	require.NotNil(t, model.snippet)
+	assert.True(t, model.snippet.selecting, "The first placeholder should be selected")
+}
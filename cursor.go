@@ -23,6 +23,19 @@ func (m *editorModel) ensureCursorVisible() {
 	// If cursor is above the viewport, scroll up
 	if m.cursor.Row < m.viewport.YOffset {
 		m.viewport.YOffset = m.cursor.Row
+	} else if m.wrap {
+		// Wrapped lines take several screen rows, go up from the cursor's
+		// row until the screen is filled
+		top, rows := m.cursor.Row, m.cursorDisplayLine()+1
+		for top > m.viewport.YOffset {
+			above := len(m.displayLines(top - 1))
+			if rows+above > m.height {
+				break
+			}
+			top--
+			rows += above
+		}
+		m.viewport.YOffset = top
 	} else if m.cursor.Row >= m.viewport.YOffset+m.height {
 		// If cursor is below the viewport, scroll down
 		m.viewport.YOffset = m.cursor.Row - m.height + 1
@@ -30,6 +43,7 @@ func (m *editorModel) ensureCursorVisible() {
 
 	// Ensure cursor is within valid bounds
 	m.adjustCursorPosition()
+	m.ensureCursorVisibleX()
 }
 
 // adjustCursorPosition ensures the cursor stays within valid bounds
@@ -0,0 +1,298 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// DiagnosticSeverity is the severity of a diagnostic, most severe first
+type DiagnosticSeverity int
+
+const (
+	// SeverityError marks errors, and diagnostics without a severity
+	SeverityError DiagnosticSeverity = iota + 1
+	// SeverityWarning marks warnings
+	SeverityWarning
+	// SeverityInfo marks information
+	SeverityInfo
+	// SeverityHint marks hints
+	SeverityHint
+)
+
+// String returns the lowercase name of the severity
+func (s DiagnosticSeverity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	case SeverityHint:
+		return "hint"
+	default:
+		return "error"
+	}
+}
+
+// diagnosticSignGroup is the sign group of the diagnostic signs
+const diagnosticSignGroup = "diagnostic"
+
+// Diagnostic is a problem reported for a range of the buffer, for example by
+// a linter. The range moves with the text when the buffer is edited.
+type Diagnostic struct {
+	Range    TextRange          // Range of the problem, End inclusive
+	Severity DiagnosticSeverity // Severity, SeverityError when zero
+	Message  string             // Description of the problem
+	Source   string             // Tool that reported the problem, e.g. "yamllint"
+}
+
+// SetDiagnostics replaces all diagnostics of the buffer. They are drawn as
+// underlines, signs and virtual text after the end of the line.
+func (m *editorModel) SetDiagnostics(diagnostics []Diagnostic) {
+	m.diagnostics = make([]Diagnostic, 0, len(diagnostics))
+	lastRow := m.buffer.lineCount() - 1
+	for _, d := range diagnostics {
+		if d.Severity < SeverityError || d.Severity > SeverityHint {
+			d.Severity = SeverityError
+		}
+		start, end := d.Range.Start, d.Range.End
+		if end.Row < start.Row || (end.Row == start.Row && end.Col < start.Col) {
+			start, end = end, start
+		}
+		start.Row = max(0, min(start.Row, lastRow))
+		end.Row = max(0, min(end.Row, lastRow))
+		d.Range = TextRange{Start: start, End: end}
+		m.diagnostics = append(m.diagnostics, d)
+	}
+	slices.SortStableFunc(m.diagnostics, func(a, b Diagnostic) int {
+		return comparePositions(a.Range.Start, b.Range.Start)
+	})
+
+	m.placeDiagnosticSigns()
+}
+
+// placeDiagnosticSigns places a sign for the most severe diagnostic of
+// every line that has diagnostics
+func (m *editorModel) placeDiagnosticSigns() {
+	m.UnplaceSigns(diagnosticSignGroup)
+	signed := make(map[int]DiagnosticSeverity)
+	for _, d := range m.diagnostics {
+		if sev, ok := signed[d.Range.Start.Row]; !ok || d.Severity < sev {
+			signed[d.Range.Start.Row] = d.Severity
+		}
+	}
+	for row, sev := range signed {
+		m.PlaceSign(Sign{
+			Group:    diagnosticSignGroup,
+			Line:     row,
+			Text:     strings.ToUpper(sev.String()[:1]),
+			Style:    m.theme.diagnosticStyle(sev),
+			Priority: 10 + int(SeverityHint-sev),
+		})
+	}
+}
+
+// GetDiagnostics returns the diagnostics at their current positions
+func (m *editorModel) GetDiagnostics() []Diagnostic {
+	return slices.Clone(m.diagnostics)
+}
+
+// comparePositions orders buffer positions
+func comparePositions(a, b Cursor) int {
+	if a.Row != b.Row {
+		return a.Row - b.Row
+	}
+	return a.Col - b.Col
+}
+
+// shiftDiagnostics moves diagnostics with their text after a change of the
+// buffer. Diagnostics inside removed text shrink to the start of the change.
+func (m *editorModel) shiftDiagnostics(change TextChange) {
+	for i := range m.diagnostics {
+		r := &m.diagnostics[i].Range
+		r.Start = change.shiftPos(r.Start)
+		r.End = change.shiftPos(r.End)
+		if comparePositions(r.End, r.Start) < 0 {
+			r.End = r.Start
+		}
+	}
+	if len(m.diagnostics) > 0 {
+		m.placeDiagnosticSigns()
+	}
+}
+
+// diagnosticStyle returns the style of the signs, virtual text and messages
+// of a severity
+func (t *Theme) diagnosticStyle(sev DiagnosticSeverity) lipgloss.Style {
+	switch sev {
+	case SeverityWarning:
+		return t.DiagnosticWarn
+	case SeverityInfo:
+		return t.DiagnosticInfo
+	case SeverityHint:
+		return t.DiagnosticHint
+	default:
+		return t.DiagnosticError
+	}
+}
+
+// diagnosticUnderline returns the style of text with a diagnostic of a severity
+func (t *Theme) diagnosticUnderline(sev DiagnosticSeverity) lipgloss.Style {
+	switch sev {
+	case SeverityWarning:
+		return t.DiagnosticUnderlineWarn
+	case SeverityInfo:
+		return t.DiagnosticUnderlineInfo
+	case SeverityHint:
+		return t.DiagnosticUnderlineHint
+	default:
+		return t.DiagnosticUnderlineError
+	}
+}
+
+// diagnosticSpans returns the underlines of the diagnostics on a row, the
+// most severe drawn last
+func (m *editorModel) diagnosticSpans(row int) []Span {
+	var spans []Span
+	for sev := SeverityHint; sev >= SeverityError; sev-- {
+		for _, d := range m.diagnostics {
+			if d.Severity != sev || row < d.Range.Start.Row || row > d.Range.End.Row {
+				continue
+			}
+			start, end := 0, m.buffer.lineLength(row)
+			if row == d.Range.Start.Row {
+				start = d.Range.Start.Col
+			}
+			if row == d.Range.End.Row {
+				end = min(d.Range.End.Col+1, end)
+			}
+			spans = append(spans, Span{Start: start, End: end, Style: m.theme.diagnosticUnderline(sev)})
+		}
+	}
+	return spans
+}
+
+// lineDiagnostics returns the diagnostics that start on a row, most severe first
+func (m *editorModel) lineDiagnostics(row int) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, d := range m.diagnostics {
+		if d.Range.Start.Row == row {
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	slices.SortStableFunc(diagnostics, func(a, b Diagnostic) int { return int(a.Severity - b.Severity) })
+	return diagnostics
+}
+
+// renderVirtualText renders the message of the most severe diagnostic of a
+// row, drawn after the end of the line
+func (m *editorModel) renderVirtualText(row int) string {
+	diagnostics := m.lineDiagnostics(row)
+	if len(diagnostics) == 0 {
+		return ""
+	}
+	d := diagnostics[0]
+	message, _, _ := strings.Cut(d.Message, "\n")
+	return "  " + m.theme.diagnosticStyle(d.Severity).Render("■ "+message)
+}
+
+// formatDiagnostic formats a diagnostic for the detail view and the list.
+// The source follows the first line of the message.
+func formatDiagnostic(d Diagnostic) []string {
+	lines := strings.Split(d.Message, "\n")
+	lines[0] = fmt.Sprintf("%s: %s", d.Severity, lines[0])
+	if d.Source != "" {
+		lines[0] += " [" + d.Source + "]"
+	}
+	return lines
+}
+
+// registerDiagnosticBindings registers ]d and [d to jump between
+// diagnostics, <C-w>d to show the diagnostics of the cursor line and the
+// :diagnostics command
+func registerDiagnosticBindings(m *editorModel) {
+	m.registry.Add("]d", nextDiagnostic, ModeNormal, "Jump to the next diagnostic")
+	m.registry.Add("[d", prevDiagnostic, ModeNormal, "Jump to the previous diagnostic")
+	m.registry.Add("ctrl+wd", showLineDiagnostics, ModeNormal, "Show diagnostics of the line")
+	m.registry.Add("ctrl+wctrl+d", showLineDiagnostics, ModeNormal, "Show diagnostics of the line")
+
+	m.commands.Register("diagnostics", listDiagnostics)
+	m.commands.Register("dia", listDiagnostics)
+}
+
+func nextDiagnostic(model *editorModel) tea.Cmd {
+	return model.jumpDiagnostic(model.countPrefix)
+}
+
+func prevDiagnostic(model *editorModel) tea.Cmd {
+	return model.jumpDiagnostic(-model.countPrefix)
+}
+
+// jumpDiagnostic moves the cursor count diagnostics forward, or backward
+// when count is negative, wrapping around the end of the buffer
+func (m *editorModel) jumpDiagnostic(count int) tea.Cmd {
+	m.countPrefix = 1
+	n := len(m.diagnostics)
+	if n == 0 {
+		return SetStatusMsg("No more diagnostics")
+	}
+
+	// Forward from the first diagnostic after the cursor, backward from
+	// the last one before it
+	index := 0
+	for index < n && comparePositions(m.diagnostics[index].Range.Start, m.cursor) < 0 {
+		index++
+	}
+	if count > 0 {
+		for index < n && m.diagnostics[index].Range.Start == m.cursor {
+			index++
+		}
+		count--
+	}
+	index += count
+	index = ((index % n) + n) % n
+
+	d := m.diagnostics[index]
+	m.cursor = d.Range.Start
+	m.adjustCursorPosition()
+	m.desiredCol = m.cursor.Col
+	m.ensureCursorVisible()
+	return SetStatusMsg(formatDiagnostic(d)[0])
+}
+
+// showLineDiagnostics shows the diagnostics of the cursor line next to the cursor
+func showLineDiagnostics(model *editorModel) tea.Cmd {
+	var lines []string
+	for _, d := range model.diagnostics {
+		if model.cursor.Row < d.Range.Start.Row || model.cursor.Row > d.Range.End.Row {
+			continue
+		}
+		style := model.theme.diagnosticStyle(d.Severity).Inherit(model.theme.NormalFloat)
+		for _, line := range formatDiagnostic(d) {
+			lines = append(lines, style.Render(line))
+		}
+	}
+	if len(lines) == 0 {
+		return SetStatusMsg("No diagnostics")
+	}
+	model.showPopup(lines, true)
+	return nil
+}
+
+// listDiagnostics shows all diagnostics of the buffer
+func listDiagnostics(model *editorModel) tea.Cmd {
+	if len(model.diagnostics) == 0 {
+		return SetStatusMsg("No diagnostics")
+	}
+	lines := make([]string, 0, len(model.diagnostics))
+	for _, d := range model.diagnostics {
+		line := fmt.Sprintf("%d:%d %s", d.Range.Start.Row+1, d.Range.Start.Col+1, formatDiagnostic(d)[0])
+		lines = append(lines, model.theme.diagnosticStyle(d.Severity).Inherit(model.theme.NormalFloat).Render(line))
+	}
+	model.showPopup(lines, false)
+	return nil
+}
@@ -0,0 +1,130 @@
+package vimtea
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func yamlDiagnostics() []Diagnostic {
+	return []Diagnostic{
+		{
+			Range:    TextRange{Start: Cursor{2, 4}, End: Cursor{2, 6}},
+			Severity: SeverityWarning,
+			Message:  "truthy value should be true or false",
+			Source:   "yamllint",
+		},
+		{
+			Range:   TextRange{Start: Cursor{0, 0}, End: Cursor{0, 3}},
+			Message: "unknown key\nexpected one of: name, port",
+			Source:  "schema",
+		},
+	}
+}
+
+func TestDiagnosticsRendering(t *testing.T) {
+	model := newWrapEditor("name: app\nport: 80\non: yes", 60, 3)
+	model.SetDiagnostics(yamlDiagnostics())
+
+	diagnostics := model.GetDiagnostics()
+	require.Len(t, diagnostics, 2)
+	assert.Equal(t, SeverityError, diagnostics[0].Severity, "Diagnostics without a severity should be errors")
+	assert.Equal(t, Cursor{0, 0}, diagnostics[0].Range.Start, "Diagnostics should be ordered by position")
+
+	assert.Equal(t, []string{
+		"E name: app  ■ unknown key",
+		"  port: 80",
+		"W on: yes  ■ truthy value should be true or false",
+	}, screenRows(model))
+
+	spans := model.diagnosticSpans(2)
+	require.Len(t, spans, 1)
+	assert.Equal(t, 4, spans[0].Start)
+	assert.Equal(t, 7, spans[0].End, "Ranges should include their end")
+	assert.True(t, spans[0].Style.GetUnderline())
+
+	model.width = 20
+	assert.Equal(t, "W on: yes  ■ truthy", screenRows(model)[2], "Virtual text should be cut at the edge of the screen")
+}
+
+func TestDiagnosticsFollowEdits(t *testing.T) {
+	model := NewEditor(WithContent("name: app\nport: 80\non: yes")).(*editorModel)
+	model.SetDiagnostics(yamlDiagnostics())
+
+	// Insert a line above the warning and text before it on its line
+	model.cursor = newCursor(2, 0)
+	sendKeys(model, "Ox")
+	model.Update(tea.KeyMsg{Type: tea.KeyEscape})
+	model.cursor = newCursor(3, 0)
+	sendKeys(model, "i# ")
+	model.Update(tea.KeyMsg{Type: tea.KeyEscape})
+	require.Equal(t, "name: app\nport: 80\nx\n# on: yes", model.buffer.text())
+
+	warning := model.GetDiagnostics()[1]
+	assert.Equal(t, TextRange{Start: Cursor{3, 6}, End: Cursor{3, 8}}, warning.Range)
+	assert.Equal(t, "yes", model.buffer.Line(3)[warning.Range.Start.Col:warning.Range.End.Col+1])
+
+	var signLines []int
+	for _, sign := range model.GetSigns() {
+		signLines = append(signLines, sign.Line)
+	}
+	assert.ElementsMatch(t, []int{0, 3}, signLines, "Diagnostic signs should follow the diagnostics")
+}
+
+func TestDiagnosticNavigation(t *testing.T) {
+	model := NewEditor(WithContent("name: app\nport: 80\non: yes")).(*editorModel)
+
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("]")})
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	assert.Contains(t, collectMsgs(cmd), statusMessageMsg("No more diagnostics"))
+
+	model.SetDiagnostics(yamlDiagnostics())
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("]")})
+	_, cmd = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	assert.Equal(t, Cursor{2, 4}, model.cursor)
+	assert.Contains(t, collectMsgs(cmd), statusMessageMsg("warning: truthy value should be true or false [yamllint]"))
+
+	sendKeys(model, "]d")
+	assert.Equal(t, Cursor{0, 0}, model.cursor, "]d should wrap around")
+
+	sendKeys(model, "[d")
+	assert.Equal(t, Cursor{2, 4}, model.cursor, "[d should wrap around")
+
+	sendKeys(model, "[d")
+	assert.Equal(t, Cursor{0, 0}, model.cursor)
+
+	sendKeys(model, "2]d")
+	assert.Equal(t, Cursor{0, 0}, model.cursor, "A count should jump over diagnostics")
+}
+
+func TestDiagnosticFloatAndList(t *testing.T) {
+	model := newWrapEditor("name: app\nport: 80\non: yes", 80, 6)
+	model.SetDiagnostics(yamlDiagnostics())
+
+	model.Update(tea.KeyMsg{Type: tea.KeyCtrlW})
+	model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	require.NotNil(t, model.float(model.popup))
+	view := ansi.Strip(model.renderFloats(model.renderContent()))
+	assert.Contains(t, view, "│error: unknown key [schema]")
+	assert.Contains(t, view, "│expected one of: name, port")
+	rows := strings.Split(view, "\n")
+	assert.True(t, strings.HasPrefix(rows[1], "  ╭"), "The float should open below the cursor")
+
+	sendKeys(model, "j")
+	assert.Nil(t, model.float(model.popup), "The float should close on the next key")
+
+	runCommand(t, model, "diagnostics")
+	require.NotNil(t, model.float(model.popup))
+	view = ansi.Strip(model.renderFloats(model.renderContent()))
+	assert.Contains(t, view, "1:1 error: unknown key [schema]")
+	assert.Contains(t, view, "3:5 warning: truthy value should be true or false [yamllint]")
+
+	model.SetDiagnostics(nil)
+	msgs := runCommand(t, model, "dia")
+	assert.Contains(t, msgs, statusMessageMsg("No diagnostics"))
+	assert.Empty(t, model.GetSigns(), "Clearing diagnostics should remove their signs")
+}
@@ -0,0 +1,81 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"slices"
+	"strings"
+)
+
+// TextEdit replaces the text between two positions, for example a fix or
+// reformatting computed by an external tool. Positions use the same row
+// and byte column coordinates as Cursor.
+type TextEdit struct {
+	Start   Cursor // Start of the replaced text
+	End     Cursor // End of the replaced text (exclusive)
+	NewText string // Text inserted in its place
+}
+
+// ApplyEdits applies non-overlapping edits to the buffer as a single undo
+// step. Positions refer to the buffer before any of the edits, and edits at
+// the same position are inserted in the order given. The cursor stays on
+// the text it was on.
+func (m *editorModel) ApplyEdits(edits []TextEdit) {
+	if len(edits) == 0 {
+		return
+	}
+	m.buffer.saveUndoState(m.cursor)
+	m.endSnippet()
+
+	// Apply from the end of the buffer so earlier positions stay valid
+	edits = slices.Clone(edits)
+	slices.SortStableFunc(edits, func(a, b TextEdit) int { return comparePositions(a.Start, b.Start) })
+	slices.Reverse(edits)
+	for _, edit := range edits {
+		start, end := m.clampPosition(edit.Start), m.clampPosition(edit.End)
+		if comparePositions(end, start) < 0 {
+			start, end = end, start
+		}
+		newEnd := m.buffer.replaceRange(start, end, edit.NewText)
+		m.cursor = TextChange{Start: start, End: end, NewEnd: newEnd}.shiftPos(m.cursor)
+	}
+
+	m.adjustCursorPosition()
+	m.desiredCol = m.cursor.Col
+	m.ensureCursorVisible()
+}
+
+// clampPosition moves a position into the buffer. Positions after the last
+// line are at the end of the buffer.
+func (m *editorModel) clampPosition(pos Cursor) Cursor {
+	last := m.buffer.lineCount() - 1
+	if pos.Row > last {
+		return Cursor{Row: last, Col: m.buffer.lineLength(last)}
+	}
+	pos.Row = max(pos.Row, 0)
+	pos.Col = max(0, min(pos.Col, m.buffer.lineLength(pos.Row)))
+	return pos
+}
+
+// textBetween returns the text from start to the exclusive end
+func (b *buffer) textBetween(start, end Cursor) string {
+	if start.Row == end.Row {
+		return b.Line(start.Row)[start.Col:end.Col]
+	}
+	lines := []string{b.Line(start.Row)[start.Col:]}
+	lines = append(lines, b.lines[start.Row+1:end.Row]...)
+	return strings.Join(append(lines, b.Line(end.Row)[:end.Col]), "\n")
+}
+
+// replaceRange replaces the text from start to the exclusive end with text
+// and returns the end of the inserted text
+func (b *buffer) replaceRange(start, end Cursor, text string) Cursor {
+	prefix := b.Line(start.Row)[:start.Col]
+	suffix := b.Line(end.Row)[end.Col:]
+	lines := strings.Split(prefix+text+suffix, "\n")
+	b.record(start.Row, end.Row+1, len(lines))
+	b.lines = slices.Replace(b.lines, start.Row, end.Row+1, lines...)
+	b.touch()
+
+	last := len(lines) - 1
+	return Cursor{Row: start.Row + last, Col: len(lines[last]) - len(suffix)}
+}
@@ -0,0 +1,44 @@
+package vimtea
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyEdits(t *testing.T) {
+	model := NewEditor(WithContent("x:=1\nprint(x)\nreturn")).(*editorModel)
+	model.cursor = Cursor{2, 3}
+
+	model.ApplyEdits([]TextEdit{
+		{Start: Cursor{1, 6}, End: Cursor{1, 7}, NewText: "count"},
+		{Start: Cursor{0, 0}, End: Cursor{0, 1}, NewText: "count"},
+		{Start: Cursor{0, 1}, End: Cursor{0, 1}, NewText: " "},
+		{Start: Cursor{0, 3}, End: Cursor{0, 3}, NewText: " "},
+	})
+	assert.Equal(t, "count := 1\nprint(count)\nreturn", model.buffer.text(), "Positions should refer to the buffer before the edits")
+	assert.Equal(t, Cursor{2, 3}, model.cursor, "The cursor should stay on its text")
+
+	model.ApplyEdits([]TextEdit{{Start: Cursor{0, 10}, End: Cursor{2, 0}, NewText: "\n"}})
+	assert.Equal(t, "count := 1\nreturn", model.buffer.text(), "Edits may join lines")
+	assert.Equal(t, Cursor{1, 3}, model.cursor)
+
+	model.ApplyEdits([]TextEdit{{Start: Cursor{5, 0}, End: Cursor{9, 0}, NewText: "\n}"}})
+	assert.Equal(t, "count := 1\nreturn\n}", model.buffer.text(), "Positions past the end should append")
+
+	model.buffer.undo(model.cursor)()
+	assert.Equal(t, "count := 1\nreturn", model.buffer.text(), "Each call should be one undo step")
+	model.buffer.undo(model.cursor)()
+	model.buffer.undo(model.cursor)()
+	assert.Equal(t, "x:=1\nprint(x)\nreturn", model.buffer.text())
+}
+
+func TestSetCursor(t *testing.T) {
+	model := NewEditor(WithContent("one\ntwo")).(*editorModel)
+
+	model.SetCursor(Cursor{1, 2})
+	assert.Equal(t, Cursor{1, 2}, model.GetCursor())
+
+	model.SetCursor(Cursor{7, 9})
+	assert.Equal(t, Cursor{1, 2}, model.GetCursor(), "Positions outside the buffer should be clamped")
+}
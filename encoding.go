@@ -0,0 +1,200 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// FileFormat identifies the line ending used when a buffer is written
+type FileFormat string
+
+const (
+	// FileFormatUnix uses "\n" line endings
+	FileFormatUnix FileFormat = "unix"
+	// FileFormatDOS uses "\r\n" line endings
+	FileFormatDOS FileFormat = "dos"
+	// FileFormatMac uses "\r" line endings
+	FileFormatMac FileFormat = "mac"
+)
+
+// Supported file encodings
+const (
+	EncodingUTF8    = "utf-8"
+	EncodingUTF16LE = "utf-16le"
+	EncodingUTF16BE = "utf-16be"
+	EncodingLatin1  = "latin1"
+)
+
+// utf8BOM is the byte order mark written at the start of UTF-8 files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// eol returns the line ending for the file format
+func (f FileFormat) eol() string {
+	switch f {
+	case FileFormatDOS:
+		return "\r\n"
+	case FileFormatMac:
+		return "\r"
+	default:
+		return "\n"
+	}
+}
+
+// parseFileFormat validates a file format name
+func parseFileFormat(name string) (FileFormat, error) {
+	switch ff := FileFormat(name); ff {
+	case FileFormatUnix, FileFormatDOS, FileFormatMac:
+		return ff, nil
+	}
+	return "", fmt.Errorf("E474: Invalid argument: fileformat=%s", name)
+}
+
+// detectFileFormat guesses the line ending of content the way Vim does:
+// dos when every "\n" is preceded by "\r", mac when there are only "\r"
+// line endings, and unix otherwise
+func detectFileFormat(content string) FileFormat {
+	lf := strings.Count(content, "\n")
+	if lf == 0 {
+		if strings.Contains(content, "\r") {
+			return FileFormatMac
+		}
+		return FileFormatUnix
+	}
+	if strings.Count(content, "\r\n") == lf {
+		return FileFormatDOS
+	}
+	return FileFormatUnix
+}
+
+// normalizeLineEndings converts the line endings of content to "\n"
+func normalizeLineEndings(content string, format FileFormat) string {
+	switch format {
+	case FileFormatDOS:
+		return strings.ReplaceAll(content, "\r\n", "\n")
+	case FileFormatMac:
+		return strings.ReplaceAll(content, "\r", "\n")
+	default:
+		return content
+	}
+}
+
+// lookupEncoding returns the x/text encoding for a supported encoding name
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	switch strings.ToLower(name) {
+	case EncodingUTF8, "utf8":
+		return nil, nil
+	case EncodingUTF16LE:
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), nil
+	case EncodingUTF16BE:
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), nil
+	case EncodingLatin1, "iso-8859-1":
+		return charmap.ISO8859_1, nil
+	}
+	return nil, fmt.Errorf("E474: Invalid argument: fileencoding=%s", name)
+}
+
+// canonicalEncoding maps encoding aliases to the names used by the editor
+func canonicalEncoding(name string) string {
+	switch strings.ToLower(name) {
+	case "utf8":
+		return EncodingUTF8
+	case "iso-8859-1":
+		return EncodingLatin1
+	}
+	return strings.ToLower(name)
+}
+
+// decodeFile converts raw file data to text. It recognises UTF-8 and UTF-16
+// byte order marks and falls back to Latin-1 for data that is not valid UTF-8.
+func decodeFile(data []byte) (text string, enc string, bom bool, err error) {
+	switch {
+	case bytes.HasPrefix(data, utf8BOM):
+		return string(data[len(utf8BOM):]), EncodingUTF8, true, nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		enc, bom = EncodingUTF16LE, true
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		enc, bom = EncodingUTF16BE, true
+	case utf8.Valid(data):
+		return string(data), EncodingUTF8, false, nil
+	default:
+		enc = EncodingLatin1
+	}
+
+	if bom {
+		data = data[2:]
+	}
+	e, _ := lookupEncoding(enc)
+	decoded, err := e.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", "", false, err
+	}
+	return string(decoded), enc, bom, nil
+}
+
+// encodeFile converts text to raw file data in the given encoding,
+// optionally prefixed with a byte order mark
+func encodeFile(text string, enc string, bom bool) ([]byte, error) {
+	e, err := lookupEncoding(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if e == nil {
+		data = []byte(text)
+	} else if data, err = e.NewEncoder().Bytes([]byte(text)); err != nil {
+		return nil, fmt.Errorf("E513: write error, conversion failed: %w", err)
+	}
+
+	if !bom {
+		return data, nil
+	}
+	switch canonicalEncoding(enc) {
+	case EncodingUTF8:
+		return append(append([]byte{}, utf8BOM...), data...), nil
+	case EncodingUTF16LE:
+		return append([]byte{0xFF, 0xFE}, data...), nil
+	case EncodingUTF16BE:
+		return append([]byte{0xFE, 0xFF}, data...), nil
+	}
+	return data, nil
+}
+
+// serialize returns the buffer content as it should be written to disk,
+// using the buffer's line endings, encoding and byte order mark
+func (b *buffer) serialize() ([]byte, error) {
+	return encodeFile(strings.Join(b.lines, b.fileFormat.eol()), b.encoding, b.bom)
+}
+
+// newBufferFromFile creates a buffer from raw file data, detecting the
+// encoding, byte order mark and line endings
+func newBufferFromFile(data []byte) (*buffer, error) {
+	text, enc, bom, err := decodeFile(data)
+	if err != nil {
+		return nil, err
+	}
+	b := newBuffer(text)
+	b.encoding = enc
+	b.bom = bom
+	return b, nil
+}
+
+// formatInfo returns the file format and, when it is not UTF-8, the encoding
+// for display in the status bar
+func (b *buffer) formatInfo() string {
+	info := string(b.fileFormat)
+	if b.encoding != EncodingUTF8 {
+		info = b.encoding + " " + info
+	}
+	if b.bom {
+		info += " [BOM]"
+	}
+	return info
+}
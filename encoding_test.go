@@ -0,0 +1,90 @@
+package vimtea
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFileFormat(t *testing.T) {
+	assert.Equal(t, FileFormatUnix, detectFileFormat("a\nb\n"))
+	assert.Equal(t, FileFormatDOS, detectFileFormat("a\r\nb\r\n"))
+	assert.Equal(t, FileFormatMac, detectFileFormat("a\rb\r"))
+	assert.Equal(t, FileFormatUnix, detectFileFormat("a\r\nb\n"), "Mixed line endings should be treated as unix")
+	assert.Equal(t, FileFormatUnix, detectFileFormat("single line"))
+}
+
+func TestBufferStripsCRLF(t *testing.T) {
+	buf := newBuffer("Line 1\r\nLine 2\r\n")
+
+	assert.Equal(t, []string{"Line 1", "Line 2", ""}, buf.lines, "Lines should not keep a trailing carriage return")
+	assert.Equal(t, 6, buf.lineLength(0), "Carriage return should not count towards the line length")
+	assert.Equal(t, FileFormatDOS, buf.fileFormat)
+
+	data, err := buf.serialize()
+	require.NoError(t, err)
+	assert.Equal(t, "Line 1\r\nLine 2\r\n", string(data), "Writing should restore the CRLF line endings")
+}
+
+func TestDecodeFile(t *testing.T) {
+	text, enc, bom, err := decodeFile([]byte("\xEF\xBB\xBFhello"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", text, "UTF-8 BOM should be stripped")
+	assert.Equal(t, EncodingUTF8, enc)
+	assert.True(t, bom)
+
+	text, enc, bom, err = decodeFile([]byte{0xFF, 0xFE, 'h', 0, 'i', 0})
+	require.NoError(t, err)
+	assert.Equal(t, "hi", text, "UTF-16LE content should be decoded")
+	assert.Equal(t, EncodingUTF16LE, enc)
+	assert.True(t, bom)
+
+	text, enc, bom, err = decodeFile([]byte("caf\xE9"))
+	require.NoError(t, err)
+	assert.Equal(t, "café", text, "Invalid UTF-8 should be decoded as Latin-1")
+	assert.Equal(t, EncodingLatin1, enc)
+	assert.False(t, bom)
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+	files := map[string]string{
+		"bom.txt":   "\xEF\xBB\xBFone\r\ntwo",
+		"utf16.txt": string([]byte{0xFE, 0xFF, 0, 'a', 0, '\r', 0, 'b'}),
+		"latin.txt": "caf\xE9\n",
+	}
+	fsys := NewMemFileSystem(files)
+
+	for name, content := range files {
+		model := NewEditor(WithFile(name), WithFileSystem(fsys)).(*editorModel)
+		runCommand(t, model, "w")
+		assert.Equal(t, content, string(fsys.files[name].data), "%s should be written back unchanged", name)
+	}
+}
+
+func TestSetFileFormat(t *testing.T) {
+	fsys := NewMemFileSystem(map[string]string{"a.txt": "one\ntwo"})
+	model := NewEditor(WithFile("a.txt"), WithFileSystem(fsys)).(*editorModel)
+	assert.Contains(t, model.renderStatusLine(), "unix", "Status bar should show the file format")
+
+	runCommand(t, model, "set fileformat=dos")
+	assert.Equal(t, FileFormatDOS, model.buffer.fileFormat)
+	assert.True(t, model.buffer.modified(), "Changing the file format should mark the buffer modified")
+	assert.Contains(t, model.renderStatusLine(), "dos")
+
+	runCommand(t, model, "set fenc=latin1 bomb")
+	runCommand(t, model, "w")
+	assert.Equal(t, "one\r\ntwo", string(fsys.files["a.txt"].data), "Latin-1 has no byte order mark")
+
+	runCommand(t, model, "set fenc=utf-8 ff=unix")
+	runCommand(t, model, "w")
+	assert.Equal(t, "\xEF\xBB\xBFone\ntwo", string(fsys.files["a.txt"].data))
+
+	msgs := runCommand(t, model, "set ff=windows")
+	assert.Contains(t, msgs, tea.Msg(statusMessageMsg("E474: Invalid argument: fileformat=windows")))
+
+	scratch := NewEditor(WithContent("one\ntwo")).(*editorModel)
+	assert.NotContains(t, scratch.renderStatusLine(), "unix", "Buffers without a file should not show the file format")
+}
@@ -0,0 +1,319 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TextChange describes a single contiguous modification of the buffer.
+// Positions use the same zero-based row and byte column coordinates as Cursor.
+type TextChange struct {
+	Start    Cursor // Start of the modified region
+	End      Cursor // End of the replaced region before the edit (exclusive)
+	NewEnd   Cursor // End of the inserted text after the edit (exclusive)
+	Removed  string // Text that was removed from the buffer
+	Inserted string // Text that was inserted into the buffer
+}
+
+// shiftRow returns the row that a line at row before the change is at
+// afterwards. Lines inside the replaced region move to its start.
+func (c TextChange) shiftRow(row int) int {
+	switch {
+	case row < c.Start.Row:
+		return row
+	case row > c.End.Row:
+		return row + c.NewEnd.Row - c.End.Row
+	case row == c.End.Row && c.End.Col == 0:
+		// The whole line follows the change
+		return c.NewEnd.Row
+	default:
+		return c.Start.Row
+	}
+}
+
+// shiftPos returns the position that a position before the change is at
+// afterwards. Positions inside the replaced region move to its start.
+func (c TextChange) shiftPos(pos Cursor) Cursor {
+	switch {
+	case comparePositions(pos, c.Start) < 0:
+		return pos
+	case comparePositions(pos, c.End) < 0:
+		return c.Start
+	case pos.Row == c.End.Row:
+		return Cursor{Row: c.NewEnd.Row, Col: c.NewEnd.Col + pos.Col - c.End.Col}
+	default:
+		return Cursor{Row: pos.Row + c.NewEnd.Row - c.End.Row, Col: pos.Col}
+	}
+}
+
+// TextChangedMsg is sent after an update that modified the buffer content.
+// Several modifications made while handling one message are reported as a
+// single change covering all of them.
+type TextChangedMsg struct {
+	TextChange
+	Version int // Monotonic buffer version after the change
+}
+
+// OnChangeFn is called after the buffer content has changed
+type OnChangeFn func(TextChangedMsg) tea.Cmd
+
+// OnCursorMoveFn is called after the cursor has moved to a new position
+type OnCursorMoveFn func(Cursor) tea.Cmd
+
+// OnModeChangeFn is called after the editor has switched to a new mode
+type OnModeChangeFn func(EditorMode) tea.Cmd
+
+// editorState is the snapshot used to detect changes between updates
+type editorState struct {
+	version int        // Buffer version at the last notification
+	cursor  Cursor     // Cursor position at the last notification
+	mode    EditorMode // Editor mode at the last notification
+}
+
+// WithOnChange registers a callback invoked whenever the buffer content changes
+func WithOnChange(fn OnChangeFn) EditorOption {
+	return func(o *options) {
+		o.OnChange = append(o.OnChange, fn)
+	}
+}
+
+// WithOnCursorMove registers a callback invoked whenever the cursor moves
+func WithOnCursorMove(fn OnCursorMoveFn) EditorOption {
+	return func(o *options) {
+		o.OnCursorMove = append(o.OnCursorMove, fn)
+	}
+}
+
+// WithOnModeChange registers a callback invoked whenever the editor mode changes
+func WithOnModeChange(fn OnModeChangeFn) EditorOption {
+	return func(o *options) {
+		o.OnModeChange = append(o.OnModeChange, fn)
+	}
+}
+
+// snapshotState records the current editor state as the baseline for change detection
+func (m *editorModel) snapshotState() {
+	m.buffer.pending = nil
+	m.lastState = editorState{
+		version: m.buffer.version,
+		cursor:  m.cursor,
+		mode:    m.mode,
+	}
+}
+
+// notifyChanges compares the editor against the last snapshot and returns
+// the commands produced by the change notifications and registered callbacks
+func (m *editorModel) notifyChanges() tea.Cmd {
+	var cmds []tea.Cmd
+
+	if m.buffer.version != m.lastState.version {
+		if change, ok := m.buffer.takeChange(); ok {
+			m.shiftSigns(change)
+			m.shiftWindows(change)
+			m.shiftMarks(change)
+			m.shiftDiagnostics(change)
+			msg := TextChangedMsg{TextChange: change, Version: m.buffer.version}
+			cmds = append(cmds, func() tea.Msg { return msg })
+			for _, fn := range m.onChange {
+				cmds = append(cmds, fn(msg))
+			}
+			m.fireAutocmd(EventTextChanged, AutocmdArgs{Change: change})
+		}
+		m.lastState.version = m.buffer.version
+	}
+
+	if m.cursor != m.lastState.cursor {
+		m.lastState.cursor = m.cursor
+		for _, fn := range m.onCursorMove {
+			cmds = append(cmds, fn(m.cursor))
+		}
+	}
+
+	if m.mode != m.lastState.mode {
+		m.lastState.mode = m.mode
+		for _, fn := range m.onModeChange {
+			cmds = append(cmds, fn(m.mode))
+		}
+	}
+
+	return tea.Batch(cmds...)
+}
+
+// pendingChange is the region of the buffer modified since the last change
+// notification. Edits record the lines they replace so that a notification
+// only compares the lines that changed.
+type pendingChange struct {
+	start   int      // First line of the region
+	end     int      // Line after the region in the current content
+	removed []string // Lines of the region before the modifications
+}
+
+// record notes that the lines from start to the exclusive end are about to
+// be replaced by count lines. It must be called before the lines change.
+func (b *buffer) record(start, end, count int) {
+	p := b.pending
+	if p == nil {
+		b.pending = &pendingChange{start: start, end: start + count, removed: slices.Clone(b.lines[start:end])}
+		return
+	}
+
+	// Grow the region to cover both, taking the lines around the old
+	// region from the content as they are unchanged
+	from, to := min(p.start, start), max(p.end, end)
+	removed := make([]string, 0, p.start-from+len(p.removed)+to-p.end)
+	removed = append(removed, b.lines[from:p.start]...)
+	removed = append(removed, p.removed...)
+	removed = append(removed, b.lines[p.end:to]...)
+	p.start, p.end, p.removed = from, to+count-(end-start), removed
+}
+
+// restoreLines replaces the content with lines, recording only the lines
+// that differ, as undo and redo restore whole snapshots
+func (b *buffer) restoreLines(lines []string) {
+	prefix := 0
+	for prefix < len(lines) && prefix < len(b.lines) && lines[prefix] == b.lines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(lines)-prefix && suffix < len(b.lines)-prefix &&
+		lines[len(lines)-1-suffix] == b.lines[len(b.lines)-1-suffix] {
+		suffix++
+	}
+	if prefix < len(lines) || len(lines) != len(b.lines) {
+		b.record(prefix, len(b.lines)-suffix, len(lines)-suffix-prefix)
+	}
+	b.lines = lines
+}
+
+// replaceContent records that the whole content of b replaces the content
+// of old, for a buffer swapped in for another one
+func (b *buffer) replaceContent(old *buffer) {
+	lines := old.lines
+	if p := old.pending; p != nil {
+		lines = slices.Concat(old.lines[:p.start], p.removed, old.lines[p.end:])
+	}
+	b.pending = &pendingChange{start: 0, end: len(b.lines), removed: slices.Clone(lines)}
+}
+
+// takeChange returns the single change made by the modifications recorded
+// since the last call and starts recording anew. It returns false when the
+// content is unchanged.
+func (b *buffer) takeChange() (TextChange, bool) {
+	p := b.pending
+	b.pending = nil
+	if p == nil {
+		return TextChange{}, false
+	}
+
+	start, removed, lines := p.start, p.removed, b.lines[p.start:p.end]
+	last := p.end == len(b.lines)
+	if last && start > 0 {
+		// The last line has no line break, so the region takes the one at
+		// the end of the line before it
+		start--
+		removed = append([]string{b.lines[start]}, removed...)
+		lines = b.lines[start:p.end]
+	}
+	before, after := strings.Join(removed, "\n"), strings.Join(lines, "\n")
+	if !last {
+		before, after = terminateLines(before, len(removed)), terminateLines(after, len(lines))
+	}
+
+	change, ok := diffText(before, after)
+	if !ok {
+		return TextChange{}, false
+	}
+	change.Start.Row += start
+	change.End.Row += start
+	change.NewEnd.Row += start
+	return change, true
+}
+
+// terminateLines adds the line break after the last of count joined lines
+func terminateLines(text string, count int) string {
+	if count == 0 {
+		return text
+	}
+	return text + "\n"
+}
+
+// diffText computes the smallest single change that turns before into after.
+// It returns false when both texts are identical.
+func diffText(before, after string) (TextChange, bool) {
+	return diffTextAt(before, after, len(before))
+}
+
+// diffTextAt is like diffText but starts the change at offset limit at the
+// latest, which places ambiguous changes such as typing a letter next to the
+// same letter
+func diffTextAt(before, after string, limit int) (TextChange, bool) {
+	if before == after {
+		return TextChange{}, false
+	}
+
+	// Common prefix, backed up to a rune boundary
+	prefix := 0
+	for prefix < limit && prefix < len(before) && prefix < len(after) && before[prefix] == after[prefix] {
+		prefix++
+	}
+	for prefix > 0 && (!runeBoundary(before, prefix) || !runeBoundary(after, prefix)) {
+		prefix--
+	}
+
+	// Common suffix, not overlapping the prefix
+	suffix := 0
+	for suffix < len(before)-prefix && suffix < len(after)-prefix &&
+		before[len(before)-1-suffix] == after[len(after)-1-suffix] {
+		suffix++
+	}
+	for suffix > 0 && (!runeBoundary(before, len(before)-suffix) || !runeBoundary(after, len(after)-suffix)) {
+		suffix--
+	}
+
+	removed := before[prefix : len(before)-suffix]
+	inserted := after[prefix : len(after)-suffix]
+	start := offsetToCursor(before, prefix)
+
+	return TextChange{
+		Start:    start,
+		End:      advanceCursor(start, removed),
+		NewEnd:   advanceCursor(start, inserted),
+		Removed:  removed,
+		Inserted: inserted,
+	}, true
+}
+
+// runeBoundary reports whether offset falls between two runes of s
+func runeBoundary(s string, offset int) bool {
+	return offset <= 0 || offset >= len(s) || utf8.RuneStart(s[offset])
+}
+
+// offsetToCursor converts a byte offset in text into a row/column position
+func offsetToCursor(text string, offset int) Cursor {
+	return advanceCursor(Cursor{}, text[:offset])
+}
+
+// cursorToOffset converts a row/column position in text into a byte offset
+func cursorToOffset(text string, c Cursor) int {
+	offset := 0
+	for range c.Row {
+		i := strings.IndexByte(text[offset:], '\n')
+		if i < 0 {
+			return len(text)
+		}
+		offset += i + 1
+	}
+	return min(offset+c.Col, len(text))
+}
+
+// advanceCursor returns the position reached after writing text starting at c
+func advanceCursor(c Cursor, text string) Cursor {
+	if n := strings.Count(text, "\n"); n > 0 {
+		return Cursor{Row: c.Row + n, Col: len(text) - strings.LastIndex(text, "\n") - 1}
+	}
+	return Cursor{Row: c.Row, Col: c.Col + len(text)}
+}
@@ -0,0 +1,160 @@
+package vimtea
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffText(t *testing.T) {
+	_, ok := diffText("same", "same")
+	assert.False(t, ok, "Identical texts should not produce a change")
+
+	change, ok := diffText("Hello World", "Hello, World")
+	require.True(t, ok)
+	assert.Equal(t, Cursor{0, 5}, change.Start, "Insertion should start after 'Hello'")
+	assert.Equal(t, Cursor{0, 5}, change.End, "Pure insertion should not replace anything")
+	assert.Equal(t, Cursor{0, 6}, change.NewEnd, "Inserted text should end one column later")
+	assert.Equal(t, ",", change.Inserted)
+	assert.Equal(t, "", change.Removed)
+
+	change, ok = diffText("Line 1\nLine 2\nLine 3", "Line 1\nLine 3")
+	require.True(t, ok)
+	assert.Equal(t, "", change.Inserted)
+	assert.Equal(t, Cursor{1, 5}, change.Start)
+	assert.Equal(t, Cursor{2, 5}, change.End, "Removal should span into the next line")
+	assert.Equal(t, "2\nLine ", change.Removed)
+
+	change, ok = diffText("añb", "aéb")
+	require.True(t, ok)
+	assert.Equal(t, "ñ", change.Removed, "Changes should not split multi-byte runes")
+	assert.Equal(t, "é", change.Inserted, "Changes should not split multi-byte runes")
+}
+
+func TestTextChangedMsg(t *testing.T) {
+	var changes []TextChangedMsg
+	editor := NewEditor(
+		WithContent("abc"),
+		WithOnChange(func(msg TextChangedMsg) tea.Cmd {
+			changes = append(changes, msg)
+			return nil
+		}),
+	)
+
+	_, cmd := editor.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}})
+	require.Len(t, changes, 1, "Deleting a character should notify once")
+	assert.Equal(t, "a", changes[0].Removed)
+	assert.Equal(t, Cursor{0, 0}, changes[0].Start)
+
+	msgs := collectMsgs(cmd)
+	var found bool
+	for _, msg := range msgs {
+		if changed, ok := msg.(TextChangedMsg); ok {
+			found = true
+			assert.Equal(t, changes[0], changed, "Message should match the callback payload")
+		}
+	}
+	assert.True(t, found, "Update should emit a TextChangedMsg")
+
+	editor.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'l'}})
+	assert.Len(t, changes, 1, "Cursor movement should not report a text change")
+
+	editor.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}})
+	require.Len(t, changes, 2)
+	assert.Greater(t, changes[1].Version, changes[0].Version, "Versions should increase monotonically")
+}
+
+func TestRecordedChanges(t *testing.T) {
+	var changes []TextChangedMsg
+	model := NewEditor(
+		WithContent("one\ntwo\n\nthree four\nfive"),
+		WithOnChange(func(msg TextChangedMsg) tea.Cmd {
+			changes = append(changes, msg)
+			return nil
+		}),
+	).(*editorModel)
+
+	// Undo and redo change the buffer in their command, which is run before
+	// its message is passed back
+	press := func(key tea.KeyMsg) {
+		_, cmd := model.Update(key)
+		for _, msg := range collectMsgs(cmd) {
+			model.Update(msg)
+		}
+	}
+
+	// Each edit should report a change that turns the old text into the new one
+	for _, keys := range []string{"x", "jdd", "Gdd", "ggD", "u", "u", "\x12", ">G", "ggVGd", "u"} {
+		before := model.buffer.text()
+		changes = nil
+		for _, r := range keys {
+			if r == '\x12' {
+				press(tea.KeyMsg{Type: tea.KeyCtrlR})
+			} else {
+				press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+			}
+		}
+		require.Len(t, changes, 1, keys)
+
+		change := changes[0]
+		start, end := cursorToOffset(before, change.Start), cursorToOffset(before, change.End)
+		assert.Equal(t, before[start:end], change.Removed, keys)
+		assert.Equal(t, model.buffer.text(), before[:start]+change.Inserted+before[end:], keys)
+		assert.Equal(t, advanceCursor(change.Start, change.Inserted), change.NewEnd, keys)
+	}
+
+	lines := make([]string, 10000)
+	for i := range lines {
+		lines[i] = "line"
+	}
+	model = NewEditor(WithContent(strings.Join(lines, "\n"))).(*editorModel)
+	model.cursor.Row = 5000
+	model.buffer.deleteLine(5000)
+	model.buffer.insertLine(5001, "new")
+	assert.Equal(t, &pendingChange{start: 5000, end: 5002, removed: []string{"line", "line"}}, model.buffer.pending,
+		"Only the edited lines should be recorded")
+	model.Update(nil)
+	assert.Nil(t, model.buffer.pending, "Notifying should clear the recorded change")
+}
+
+func TestCursorAndModeCallbacks(t *testing.T) {
+	var cursors []Cursor
+	var modes []EditorMode
+	editor := NewEditor(
+		WithContent("abc\ndef"),
+		WithOnCursorMove(func(c Cursor) tea.Cmd {
+			cursors = append(cursors, c)
+			return nil
+		}),
+		WithOnModeChange(func(mode EditorMode) tea.Cmd {
+			modes = append(modes, mode)
+			return nil
+		}),
+	)
+
+	editor.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	assert.Equal(t, []Cursor{{1, 0}}, cursors, "Moving down should report the new cursor")
+
+	editor.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	editor.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, []EditorMode{ModeInsert, ModeNormal}, modes, "Mode transitions should be reported in order")
+}
+
+// collectMsgs runs a command and flattens any batched messages it produces
+func collectMsgs(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	msg := cmd()
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		var msgs []tea.Msg
+		for _, c := range batch {
+			msgs = append(msgs, collectMsgs(c)...)
+		}
+		return msgs
+	}
+	return []tea.Msg{msg}
+}
@@ -21,29 +21,13 @@ func main() {
 	// Set log output to the file
 	log.SetOutput(logFile)
 
-	file, err := os.Open("example/main.go")
-	if err != nil {
-		log.Fatalf("Failed to open example/main.go: %v", err)
-	}
-	defer file.Close()
-
-	stat, err := file.Stat()
-	if err != nil {
-		log.Fatalf("Failed to get file stat: %v", err)
-	}
-	// Read the file
-	buf := make([]byte, stat.Size())
-	_, err = file.Read(buf)
-	if err != nil {
-		log.Fatalf("Failed to read file: %v", err)
-	}
-
-	// Create a new editor with the file contents
-	// WithFileName is used for syntax highlighting
+	// Create a new editor that loads this file
+	// WithFile also enables :w, :e, :r and friends and is used for syntax highlighting
 	editor := vimtea.NewEditor(
-		vimtea.WithContent(string(buf)),
-		vimtea.WithFileName("example/main.go"),
+		vimtea.WithFile("example/main.go"),
 		vimtea.WithFullScreen(),
+		// The editor is the root model, so :q, :wq and :x quit the program
+		vimtea.WithQuitOnRequest(),
 	)
 
 	// Add a custom key binding for quitting with Ctrl+C
@@ -56,12 +40,7 @@ func main() {
 		},
 	})
 
-	// Add a custom command that can be invoked with :q
-	editor.AddCommand("q", func(b vimtea.Buffer, _ []string) tea.Cmd {
-		return tea.Quit
-	})
-
-	p := tea.NewProgram(editor, tea.WithAltScreen())
+	p := tea.NewProgram(editor, tea.WithAltScreen(), tea.WithMouseCellMotion())
 	if _, err := p.Run(); err != nil {
 		log.Printf("Error running program: %v", err)
 	}
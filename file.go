@@ -0,0 +1,379 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// FileSystem abstracts the file operations used by the file commands
+// (:w, :e, :r, ...). The default implementation uses the os package.
+type FileSystem interface {
+	// ReadFile returns the content of the named file
+	ReadFile(name string) ([]byte, error)
+
+	// WriteFile writes data to the named file, creating it if necessary
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+
+	// Stat returns file information for the named file
+	Stat(name string) (fs.FileInfo, error)
+}
+
+// osFileSystem implements FileSystem using the local disk
+type osFileSystem struct{}
+
+// OSFileSystem returns a FileSystem backed by the local disk
+func OSFileSystem() FileSystem {
+	return osFileSystem{}
+}
+
+// ReadFile reads the named file from disk
+func (osFileSystem) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+// WriteFile writes the named file to disk
+func (osFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+
+// Stat returns file information from disk
+func (osFileSystem) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// MemFileSystem is an in-memory FileSystem. It is useful for tests and for
+// hosts that keep documents in memory. Files are keyed by their names as
+// given, which may be any string, including absolute paths.
+type MemFileSystem struct {
+	files map[string]*memFile
+}
+
+// memFile is a file of a MemFileSystem
+type memFile struct {
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// NewMemFileSystem creates an in-memory file system with the given files
+func NewMemFileSystem(files map[string]string) *MemFileSystem {
+	mfs := &MemFileSystem{files: make(map[string]*memFile, len(files))}
+	for name, content := range files {
+		mfs.files[name] = &memFile{data: []byte(content), mode: 0o644}
+	}
+	return mfs
+}
+
+// ReadFile returns a copy of the content of the named file
+func (m *MemFileSystem) ReadFile(name string) ([]byte, error) {
+	f, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return slices.Clone(f.data), nil
+}
+
+// WriteFile stores data under the given name
+func (m *MemFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.files[name] = &memFile{data: slices.Clone(data), mode: perm, modTime: time.Now()}
+	return nil
+}
+
+// Stat returns information about the named file
+func (m *MemFileSystem) Stat(name string) (fs.FileInfo, error) {
+	f, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return memFileInfo{name: path.Base(name), file: f}, nil
+}
+
+// memFileInfo describes a file of a MemFileSystem
+type memFileInfo struct {
+	name string
+	file *memFile
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return int64(len(i.file.data)) }
+func (i memFileInfo) Mode() fs.FileMode  { return i.file.mode }
+func (i memFileInfo) ModTime() time.Time { return i.file.modTime }
+func (i memFileInfo) IsDir() bool        { return false }
+func (i memFileInfo) Sys() any           { return nil }
+
+// readOnlyFileSystem adapts an fs.FS to the FileSystem interface
+type readOnlyFileSystem struct {
+	fsys fs.FS
+}
+
+// ReadOnlyFileSystem wraps any fs.FS, such as an embed.FS, as a FileSystem.
+// Writes fail with fs.ErrPermission.
+func ReadOnlyFileSystem(fsys fs.FS) FileSystem {
+	return readOnlyFileSystem{fsys: fsys}
+}
+
+// ReadFile reads the named file from the wrapped file system
+func (r readOnlyFileSystem) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(r.fsys, name)
+}
+
+// WriteFile always fails because the wrapped file system is read-only
+func (r readOnlyFileSystem) WriteFile(name string, _ []byte, _ fs.FileMode) error {
+	return &fs.PathError{Op: "write", Path: name, Err: fs.ErrPermission}
+}
+
+// Stat returns file information from the wrapped file system
+func (r readOnlyFileSystem) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(r.fsys, name)
+}
+
+// WithFile loads the named file into the editor and makes it the target of :w.
+// The file name is also used for syntax highlighting. A missing file starts
+// an empty buffer that is created on the first write.
+func WithFile(path string) EditorOption {
+	return func(o *options) {
+		o.File = path
+		o.FileName = path
+	}
+}
+
+// WithFileSystem sets the file system used by the file commands
+func WithFileSystem(fsys FileSystem) EditorOption {
+	return func(o *options) {
+		o.FileSystem = fsys
+	}
+}
+
+// registerFileCommands registers the built-in file commands
+func registerFileCommands(m *editorModel) {
+	m.commands.Register("w", writeCommand)
+	m.commands.Register("write", writeCommand)
+	m.commands.Register("saveas", saveAsCommand)
+	m.commands.Register("wq", writeQuitCommand)
+	m.commands.Register("x", exitCommand)
+	m.commands.Register("xit", exitCommand)
+	m.commands.Register("e", editCommand)
+	m.commands.Register("edit", editCommand)
+	m.commands.Register("r", readCommand)
+	m.commands.Register("read", readCommand)
+}
+
+// loadFile reads a file into a fresh buffer that replaces the current one.
+// A missing file yields an empty buffer and a "[New]" status message.
+func (m *editorModel) loadFile(path string) error {
+	b, status, err := m.readFile(path)
+	if err != nil {
+		return err
+	}
+	m.replaceBuffer(b)
+	m.buffer.markSaved()
+	m.setFileName(path)
+	m.cursor = newCursor(0, 0)
+	m.desiredCol = 0
+	m.viewport.YOffset = 0
+	m.statusMessage = status
+	return nil
+}
+
+// readFile reads a file into a new buffer and returns it with the status
+// message describing the file
+func (m *editorModel) readFile(path string) (*buffer, string, error) {
+	data, err := m.fs.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, "", err
+	}
+
+	b, decodeErr := newBufferFromFile(data)
+	if decodeErr != nil {
+		return nil, "", decodeErr
+	}
+	if err != nil {
+		return b, fmt.Sprintf("%q [New]", path), nil
+	}
+	return b, fmt.Sprintf("%q %dL, %dB", path, b.lineCount(), len(data)), nil
+}
+
+// writeFile writes the buffer to path, running the BufWritePre and
+// BufWritePost autocommands around the write
+func (m *editorModel) writeFile(path string) error {
+	m.fireAutocmd(EventBufWritePre, AutocmdArgs{File: path})
+
+	perm := fs.FileMode(0o644)
+	if info, err := m.fs.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	data, err := m.buffer.serialize()
+	if err != nil {
+		return err
+	}
+	if err := m.fs.WriteFile(path, data, perm); err != nil {
+		return err
+	}
+	if path == m.filePath {
+		m.buffer.markSaved()
+	}
+
+	m.statusMessage = fmt.Sprintf("%q %dL, %dB written", path, m.buffer.lineCount(), len(data))
+	m.fireAutocmd(EventBufWritePost, AutocmdArgs{File: path})
+	return nil
+}
+
+// setFileName changes the file associated with the buffer
+func (m *editorModel) setFileName(path string) {
+	m.filePath = path
+	m.highlighter.setFileName(path)
+}
+
+// fileExists reports whether a file other than the current one exists at path
+func (m *editorModel) fileExists(path string) bool {
+	if path == m.filePath {
+		return false
+	}
+	_, err := m.fs.Stat(path)
+	return err == nil
+}
+
+// writeTarget resolves the file a write command should use
+func (m *editorModel) writeTarget(bang bool, args []string) (string, error) {
+	path := m.filePath
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if path == "" {
+		return "", errors.New("E32: No file name")
+	}
+	if !bang && m.fileExists(path) {
+		return "", errors.New("E13: File exists (add ! to override)")
+	}
+	return path, nil
+}
+
+// writeCommand implements :w[!] [file]
+func writeCommand(model *editorModel) tea.Cmd {
+	bang, args := model.commandLine()
+	path, err := model.writeTarget(bang, args)
+	if err != nil {
+		return SetStatusMsg(err.Error())
+	}
+
+	// Writing an unnamed buffer gives it a name
+	if model.filePath == "" {
+		model.setFileName(path)
+	}
+	if err := model.writeFile(path); err != nil {
+		return SetStatusMsg(err.Error())
+	}
+	return nil
+}
+
+// saveAsCommand implements :saveas[!] {file}
+func saveAsCommand(model *editorModel) tea.Cmd {
+	bang, args := model.commandLine()
+	if len(args) == 0 {
+		return SetStatusMsg("E471: Argument required")
+	}
+	path, err := model.writeTarget(bang, args)
+	if err != nil {
+		return SetStatusMsg(err.Error())
+	}
+
+	previous := model.filePath
+	model.setFileName(path)
+	if err := model.writeFile(path); err != nil {
+		model.setFileName(previous)
+		return SetStatusMsg(err.Error())
+	}
+	return nil
+}
+
+// writeQuitCommand implements :wq[!] [file]
+func writeQuitCommand(model *editorModel) tea.Cmd {
+	if cmd := writeCommand(model); cmd != nil {
+		return cmd
+	}
+	return model.quitWindow(false)
+}
+
+// exitCommand implements :x, which writes only when the buffer is modified
+func exitCommand(model *editorModel) tea.Cmd {
+	if model.buffer.modified() {
+		return writeQuitCommand(model)
+	}
+	return model.quitWindow(false)
+}
+
+// editCommand implements :e[!] [file]. Another file is shown in its own
+// buffer; "!" discards the changes of the buffer that is left. Without a
+// file the current buffer is read again.
+func editCommand(model *editorModel) tea.Cmd {
+	bang, args := model.commandLine()
+	path := model.filePath
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if path == "" {
+		return SetStatusMsg("E32: No file name")
+	}
+
+	if path != model.filePath {
+		if !bang && model.abandons(model.win) {
+			return SetStatusMsg("E37: No write since last change (add ! to override)")
+		}
+		previous, discard := model.win.doc, model.abandons(model.win)
+		if err := model.openFile(path); err != nil {
+			return SetStatusMsg(err.Error())
+		}
+		if discard {
+			previous.unload()
+		}
+		return nil
+	}
+
+	if !bang && model.buffer.modified() {
+		return SetStatusMsg("E37: No write since last change (add ! to override)")
+	}
+	if err := model.loadFile(path); err != nil {
+		return SetStatusMsg(err.Error())
+	}
+	return nil
+}
+
+// readCommand implements :r {file}, inserting the file below the cursor line
+func readCommand(model *editorModel) tea.Cmd {
+	_, args := model.commandLine()
+	if len(args) == 0 {
+		return SetStatusMsg("E32: No file name")
+	}
+
+	data, err := model.fs.ReadFile(args[0])
+	if err != nil {
+		return SetStatusMsg(fmt.Sprintf("E484: Can't open file %s", args[0]))
+	}
+	text, _, _, err := decodeFile(data)
+	if err != nil {
+		return SetStatusMsg(err.Error())
+	}
+	text = normalizeLineEndings(text, detectFileFormat(text))
+
+	model.buffer.saveUndoState(model.cursor)
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range lines {
+		model.buffer.insertLine(model.cursor.Row+1+i, line)
+	}
+
+	model.cursor.Row++
+	model.cursor.Col = 0
+	moveToFirstNonWhitespace(model)
+	model.ensureCursorVisible()
+	return SetStatusMsg(fmt.Sprintf("%q %dL, %dB", args[0], len(lines), len(data)))
+}
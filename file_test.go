@@ -0,0 +1,155 @@
+package vimtea
+
+import (
+	"testing"
+	"testing/fstest"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runCommand executes a command line as if it was typed in command mode
+func runCommand(t *testing.T, model *editorModel, line string) []tea.Msg {
+	t.Helper()
+	model.mode = ModeCommand
+	model.commandBuffer = line
+	_, cmd := model.Update(executeCommand(model)())
+	return collectMsgs(cmd)
+}
+
+func TestWithFileLoadsContent(t *testing.T) {
+	fsys := NewMemFileSystem(map[string]string{"notes.txt": "first\nsecond"})
+	model := NewEditor(WithFile("notes.txt"), WithFileSystem(fsys)).(*editorModel)
+
+	assert.Equal(t, "first\nsecond", model.buffer.text(), "Buffer should contain the file content")
+	assert.Equal(t, "notes.txt", model.filePath)
+	assert.False(t, model.buffer.modified(), "Freshly loaded buffer should not be modified")
+
+	missing := NewEditor(WithFile("new.txt"), WithFileSystem(fsys)).(*editorModel)
+	assert.Equal(t, "", missing.buffer.text(), "Missing file should start an empty buffer")
+	assert.Contains(t, missing.statusMessage, "[New]")
+}
+
+func TestWriteCommand(t *testing.T) {
+	fsys := NewMemFileSystem(map[string]string{"notes.txt": "hello", "other.txt": "keep"})
+	model := NewEditor(WithFile("notes.txt"), WithFileSystem(fsys)).(*editorModel)
+
+	var events []AutocmdEvent
+	model.AddAutocmd(EventBufWritePre, "*.txt", func(_ Buffer, args AutocmdArgs) tea.Cmd {
+		events = append(events, args.Event)
+		return nil
+	})
+	model.AddAutocmd(EventBufWritePost, "*.txt", func(_ Buffer, args AutocmdArgs) tea.Cmd {
+		events = append(events, args.Event)
+		return nil
+	})
+
+	model.buffer.insertAt(0, 5, " world")
+	assert.True(t, model.buffer.modified(), "Editing should mark the buffer modified")
+	assert.Contains(t, model.renderStatusLine(), "[+]", "Status line should show the modified marker")
+
+	runCommand(t, model, "w")
+	assert.Equal(t, "hello world", string(fsys.files["notes.txt"].data), "File should contain the buffer text")
+	assert.False(t, model.buffer.modified(), "Writing should clear the modified flag")
+	assert.NotContains(t, model.renderStatusLine(), "[+]")
+	assert.Equal(t, []AutocmdEvent{EventBufWritePre, EventBufWritePost}, events)
+
+	msgs := runCommand(t, model, "w other.txt")
+	assert.Equal(t, "keep", string(fsys.files["other.txt"].data), "Existing file should not be overwritten without !")
+	assert.Contains(t, msgs, tea.Msg(statusMessageMsg("E13: File exists (add ! to override)")))
+
+	runCommand(t, model, "w! other.txt")
+	assert.Equal(t, "hello world", string(fsys.files["other.txt"].data), "! should force the write")
+	assert.Equal(t, "notes.txt", model.filePath, "Writing to another file should keep the buffer name")
+}
+
+func TestWriteUnnamedBuffer(t *testing.T) {
+	fsys := NewMemFileSystem(nil)
+	model := NewEditor(WithContent("text"), WithFileSystem(fsys)).(*editorModel)
+
+	msgs := runCommand(t, model, "w")
+	assert.Contains(t, msgs, tea.Msg(statusMessageMsg("E32: No file name")))
+
+	runCommand(t, model, "w out.txt")
+	assert.Equal(t, "out.txt", model.filePath, "Writing an unnamed buffer should name it")
+	assert.Equal(t, "text", string(fsys.files["out.txt"].data))
+}
+
+func TestMemFileSystemNames(t *testing.T) {
+	fsys := NewMemFileSystem(map[string]string{"/tmp/a.txt": "alpha"})
+	model := NewEditor(WithFile("/tmp/a.txt"), WithFileSystem(fsys)).(*editorModel)
+	assert.Equal(t, "alpha", model.buffer.text())
+
+	sendKeys(model, "x")
+	runCommand(t, model, "w")
+	assert.Equal(t, "lpha", string(fsys.files["/tmp/a.txt"].data), "Files opened by any name should be written back")
+
+	runCommand(t, model, "w ./b.txt")
+	info, err := fsys.Stat("./b.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), info.Size())
+}
+
+func TestSaveAsCommand(t *testing.T) {
+	fsys := NewMemFileSystem(map[string]string{"a.txt": "content"})
+	model := NewEditor(WithFile("a.txt"), WithFileSystem(fsys)).(*editorModel)
+
+	runCommand(t, model, "saveas b.go")
+	assert.Equal(t, "b.go", model.filePath, ":saveas should rename the buffer")
+	assert.Equal(t, "b.go", model.highlighter.filename, ":saveas should update highlighting")
+	require.Contains(t, fsys.files, "b.go")
+	assert.Equal(t, "content", string(fsys.files["b.go"].data))
+}
+
+func TestEditCommand(t *testing.T) {
+	fsys := NewMemFileSystem(map[string]string{"a.txt": "alpha", "b.txt": "beta"})
+	model := NewEditor(WithFile("a.txt"), WithFileSystem(fsys)).(*editorModel)
+
+	model.buffer.insertAt(0, 0, "x")
+	msgs := runCommand(t, model, "e b.txt")
+	assert.Contains(t, msgs, tea.Msg(statusMessageMsg("E37: No write since last change (add ! to override)")))
+	assert.Equal(t, "xalpha", model.buffer.text(), "Modified buffer should not be abandoned without !")
+
+	runCommand(t, model, "e! b.txt")
+	assert.Equal(t, "beta", model.buffer.text(), ":e! should load the other file")
+	assert.Equal(t, "b.txt", model.filePath)
+
+	model.buffer.insertAt(0, 0, "y")
+	runCommand(t, model, "e!")
+	assert.Equal(t, "beta", model.buffer.text(), ":e! without a file should revert to the saved content")
+}
+
+func TestReadCommand(t *testing.T) {
+	fsys := NewMemFileSystem(map[string]string{"snippet.txt": "  one\ntwo\n"})
+	model := NewEditor(WithContent("first\nlast"), WithFileSystem(fsys)).(*editorModel)
+
+	runCommand(t, model, "r snippet.txt")
+	assert.Equal(t, "first\n  one\ntwo\nlast", model.buffer.text(), ":r should insert the file below the cursor")
+	assert.Equal(t, Cursor{1, 2}, model.cursor, "Cursor should move to the first inserted line")
+}
+
+func TestWriteQuitCommands(t *testing.T) {
+	fsys := NewMemFileSystem(map[string]string{"a.txt": "alpha"})
+	model := NewEditor(WithFile("a.txt"), WithFileSystem(fsys)).(*editorModel)
+
+	model.buffer.insertAt(0, 0, "x")
+	msgs := runCommand(t, model, "wq")
+	assert.Equal(t, "xalpha", string(fsys.files["a.txt"].data), ":wq should write the file")
+	assert.Contains(t, msgs, tea.Msg(QuitRequestedMsg{}), ":wq should request to quit")
+
+	fsys.files["a.txt"].data = []byte("changed on disk")
+	msgs = runCommand(t, model, "x")
+	assert.Equal(t, "changed on disk", string(fsys.files["a.txt"].data), ":x should not write an unmodified buffer")
+	assert.Contains(t, msgs, tea.Msg(QuitRequestedMsg{}), ":x should request to quit")
+}
+
+func TestReadOnlyFileSystem(t *testing.T) {
+	fsys := ReadOnlyFileSystem(fstest.MapFS{"a.txt": {Data: []byte("alpha")}})
+	model := NewEditor(WithFile("a.txt"), WithFileSystem(fsys)).(*editorModel)
+	assert.Equal(t, "alpha", model.buffer.text())
+
+	msgs := runCommand(t, model, "w")
+	assert.Contains(t, msgs, tea.Msg(statusMessageMsg("write a.txt: permission denied")),
+		"Writes to a read-only file system should fail")
+}
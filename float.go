@@ -0,0 +1,307 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// FloatID identifies a floating window opened with OpenFloat
+type FloatID int
+
+// FloatAnchor selects what the position of a floating window is relative to
+type FloatAnchor int
+
+const (
+	// AnchorEditor places the float at a cell of the area shared by the
+	// windows. Negative rows and columns count from the bottom and right
+	// edges.
+	AnchorEditor FloatAnchor = iota
+	// AnchorCursor places the float relative to the cursor
+	AnchorCursor
+	// AnchorBuffer places the float relative to a buffer position, hiding it
+	// while the position is scrolled out of view
+	AnchorBuffer
+)
+
+// FloatOptions configures a floating window
+type FloatOptions struct {
+	Anchor    FloatAnchor
+	Pos       Cursor          // Buffer position for AnchorBuffer
+	Row, Col  int             // Offset from the anchor
+	Width     int             // Width of the content, 0 fits the lines
+	Height    int             // Height of the content, 0 fits the lines
+	MaxWidth  int             // Limit of the fitted width, 0 for none
+	MaxHeight int             // Limit of the fitted height, 0 for none
+	Border    lipgloss.Border // Border drawn around the content, none when zero
+	Title     string          // Title drawn in the top border
+	ZIndex    int             // Floats with a higher index are drawn on top
+	Style     lipgloss.Style  // Style of the content, NormalFloat of the theme when unset
+
+	// CloseOnKey closes the float on the next key press
+	CloseOnKey bool
+}
+
+// floatWindow is an open floating window
+type floatWindow struct {
+	id     FloatID
+	lines  []string
+	opts   FloatOptions
+	scroll int // First line shown
+}
+
+// Z-indexes of the floats drawn by the editor itself
+const (
+	popupZIndex      = 50
+	completionZIndex = 100
+)
+
+// OpenFloat opens a floating window showing lines over the text. Floats
+// are clipped to the editor and stay open until closed with CloseFloat.
+func (m *editorModel) OpenFloat(lines []string, opts FloatOptions) FloatID {
+	m.nextFloatID++
+	m.floats = append(m.floats, &floatWindow{id: m.nextFloatID, lines: lines, opts: opts})
+	return m.nextFloatID
+}
+
+// SetFloatLines replaces the lines of a floating window, keeping its scroll
+// position where possible
+func (m *editorModel) SetFloatLines(id FloatID, lines []string) {
+	if f := m.float(id); f != nil {
+		f.lines = lines
+		f.scroll = m.clampFloatScroll(f, f.scroll)
+	}
+}
+
+// ScrollFloat scrolls the lines of a floating window by delta lines, down
+// when positive
+func (m *editorModel) ScrollFloat(id FloatID, delta int) {
+	if f := m.float(id); f != nil {
+		f.scroll = m.clampFloatScroll(f, f.scroll+delta)
+	}
+}
+
+// CloseFloat closes a floating window
+func (m *editorModel) CloseFloat(id FloatID) {
+	m.floats = slices.DeleteFunc(m.floats, func(f *floatWindow) bool { return f.id == id })
+}
+
+// float returns the open float with an ID, or nil
+func (m *editorModel) float(id FloatID) *floatWindow {
+	for _, f := range m.floats {
+		if f.id == id {
+			return f
+		}
+	}
+	return nil
+}
+
+// closeFloatsOnKey closes the floats that only last until a key press
+func (m *editorModel) closeFloatsOnKey() {
+	m.floats = slices.DeleteFunc(m.floats, func(f *floatWindow) bool { return f.opts.CloseOnKey })
+}
+
+// clampFloatScroll limits a scroll position to the lines of a float
+func (m *editorModel) clampFloatScroll(f *floatWindow, scroll int) int {
+	width, height := m.floatArea("")
+	if width == 0 || height == 0 {
+		return max(0, min(scroll, len(f.lines)-1))
+	}
+	_, h := f.size(width, height)
+	return max(0, min(scroll, len(f.lines)-h))
+}
+
+// floatArea returns the size of the area floats are drawn in: the windows,
+// or the rendered content while the size is not known
+func (m *editorModel) floatArea(content string) (int, int) {
+	if width, height := m.editorSize(); width > 0 && height > 0 {
+		return width, height
+	}
+	return lipgloss.Width(content), lipgloss.Height(content)
+}
+
+// hasBorder reports whether a border is drawn around the float
+func (f *floatWindow) hasBorder() bool {
+	return f.opts.Border != lipgloss.Border{}
+}
+
+// size returns the size of the content of a float in an area
+func (f *floatWindow) size(areaWidth, areaHeight int) (int, int) {
+	frame := 0
+	if f.hasBorder() {
+		frame = 2
+	}
+
+	width := f.opts.Width
+	if width <= 0 {
+		for _, line := range f.lines {
+			width = max(width, ansi.StringWidth(line))
+		}
+		width = max(width, ansi.StringWidth(f.opts.Title))
+		if f.opts.MaxWidth > 0 {
+			width = min(width, f.opts.MaxWidth)
+		}
+	}
+	height := f.opts.Height
+	if height <= 0 {
+		height = len(f.lines)
+		if f.opts.MaxHeight > 0 {
+			height = min(height, f.opts.MaxHeight)
+		}
+	}
+	return max(0, min(width, areaWidth-frame)), max(0, min(height, areaHeight-frame))
+}
+
+// renderFloat renders a float with its border for an area
+func (m *editorModel) renderFloat(f *floatWindow, areaWidth, areaHeight int) string {
+	width, height := f.size(areaWidth, areaHeight)
+	if width == 0 || height == 0 {
+		return ""
+	}
+	style := f.opts.Style
+	if !styled(style) {
+		style = m.theme.NormalFloat
+	}
+	scroll := max(0, min(f.scroll, len(f.lines)-height))
+
+	rows := make([]string, 0, height+2)
+	for i := range height {
+		line := ""
+		if scroll+i < len(f.lines) {
+			line = ansi.Truncate(f.lines[scroll+i], width, "…")
+		}
+		rows = append(rows, style.Render(line+strings.Repeat(" ", width-ansi.StringWidth(line))))
+	}
+	if !f.hasBorder() {
+		return strings.Join(rows, "\n")
+	}
+
+	// A thumb in the right border shows the position of long content
+	thumbStart, thumbEnd := 0, height
+	if len(f.lines) > height {
+		size := max(1, height*height/len(f.lines))
+		thumbStart = scroll * (height - size) / (len(f.lines) - height)
+		thumbEnd = thumbStart + size
+	}
+
+	b := f.opts.Border
+	borderStyle := m.theme.FloatBorder.Inherit(style)
+	title := ""
+	if f.opts.Title != "" {
+		title = ansi.Truncate(f.opts.Title, width, "…")
+	}
+	top := b.TopLeft + title + strings.Repeat(b.Top, width-ansi.StringWidth(title)) + b.TopRight
+	for i, row := range rows {
+		right := b.Right
+		if len(f.lines) > height && i >= thumbStart && i < thumbEnd {
+			right = "█"
+		}
+		rows[i] = borderStyle.Render(b.Left) + row + borderStyle.Render(right)
+	}
+	bottom := b.BottomLeft + strings.Repeat(b.Bottom, width) + b.BottomRight
+	return strings.Join(append(append([]string{borderStyle.Render(top)}, rows...), borderStyle.Render(bottom)), "\n")
+}
+
+// placeFloat returns the top left cell of a rendered float in an area, or
+// false when its anchor is not on screen. Floats below their anchor move
+// above it when there is no room.
+func (m *editorModel) placeFloat(f *floatWindow, width, height, areaWidth, areaHeight int) (int, int, bool) {
+	x, y := f.opts.Col, f.opts.Row
+	switch f.opts.Anchor {
+	case AnchorEditor:
+		if x < 0 {
+			x += areaWidth - width + 1
+		}
+		if y < 0 {
+			y += areaHeight - height + 1
+		}
+	case AnchorCursor, AnchorBuffer:
+		pos := m.cursor
+		if f.opts.Anchor == AnchorBuffer {
+			pos = f.opts.Pos
+		}
+		row, col, ok := m.screenPosition(pos)
+		if !ok || row >= areaHeight || (m.sharesArea() && (row >= m.height || col >= m.width)) {
+			return 0, 0, false
+		}
+		top, left := m.windowOrigin()
+		row, col = row+top, col+left
+		x, y = col+f.opts.Col, row+f.opts.Row
+		if f.opts.Row > 0 && y+height > areaHeight && row-height >= 0 {
+			y = row - height
+		}
+	}
+	return max(0, min(x, areaWidth-width)), max(0, min(y, areaHeight-height)), true
+}
+
+// screenPosition returns the row and column of the text area a buffer
+// position is drawn at, or false when it is scrolled out of view
+func (m *editorModel) screenPosition(pos Cursor) (int, int, bool) {
+	top := max(m.viewport.YOffset, 0)
+	if pos.Row < top || pos.Row >= m.buffer.lineCount() {
+		return 0, 0, false
+	}
+	line := m.buffer.Line(pos.Row)
+	col := bufferToVisualPosition(line, pos.Col, m.buffer.tabs.tabStop)
+	rows := m.displayLines(pos.Row)
+	i := len(rows) - 1
+	for j, r := range rows {
+		if col < r.end {
+			i = j
+			break
+		}
+	}
+	col -= rows[i].start
+	if i > 0 {
+		col += lipgloss.Width(m.breakPrefix(line))
+	}
+	return m.screenRowsBetween(top, pos.Row) + i, m.gutterWidth() + col - m.xOffset, true
+}
+
+// renderFloats draws the open floats and the completion menu over the
+// rendered content, lowest z-index first
+func (m *editorModel) renderFloats(content string) string {
+	width, height := m.floatArea(content)
+	if width == 0 || height == 0 {
+		return content
+	}
+	floats := append(slices.Clone(m.floats), m.completionFloats(width, height)...)
+	slices.SortStableFunc(floats, func(a, b *floatWindow) int { return a.opts.ZIndex - b.opts.ZIndex })
+	for _, f := range floats {
+		box := m.renderFloat(f, width, height)
+		if box == "" {
+			continue
+		}
+		if x, y, ok := m.placeFloat(f, lipgloss.Width(box), lipgloss.Height(box), width, height); ok {
+			content = overlay(content, box, x, y, width)
+		}
+	}
+	return content
+}
+
+// overlay draws block over base with its top left corner at column x of
+// row y, clipped to the rows of base and to width columns. ANSI styles of
+// both are preserved.
+func overlay(base, block string, x, y, width int) string {
+	rows := strings.Split(base, "\n")
+	for i, line := range strings.Split(block, "\n") {
+		row := y + i
+		if row < 0 || row >= len(rows) {
+			continue
+		}
+		line = ansi.Truncate(line, width-x, "")
+		under := rows[row]
+		underWidth := ansi.StringWidth(under)
+		left := ansi.Truncate(under, x, "")
+		left += strings.Repeat(" ", x-ansi.StringWidth(left))
+		right := ""
+		if end := x + ansi.StringWidth(line); end < underWidth {
+			right = ansi.Cut(under, end, underWidth)
+		}
+		rows[row] = left + ansi.ResetStyle + line + ansi.ResetStyle + right
+	}
+	return strings.Join(rows, "\n")
+}
@@ -0,0 +1,91 @@
+package vimtea
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+)
+
+// floatRows returns the rendered content with the floats as plain text rows
+func floatRows(model *editorModel) []string {
+	content := strings.TrimSuffix(model.renderFloats(model.renderContent()), "\n")
+	rows := strings.Split(ansi.Strip(content), "\n")
+	for i := range rows {
+		rows[i] = strings.TrimRight(rows[i], " ")
+	}
+	return rows
+}
+
+func TestFloatBorderAndTitle(t *testing.T) {
+	model := newWrapEditor("one\ntwo\nthree", 20, 5)
+
+	id := model.OpenFloat([]string{"hello", "world"}, FloatOptions{Row: 1, Col: 4, Border: lipgloss.RoundedBorder(), Title: "Hi"})
+	assert.Equal(t, []string{
+		"one",
+		"two ╭Hi───╮",
+		"thre│hello│",
+		"    │world│",
+		"    ╰─────╯",
+	}, floatRows(model))
+
+	model.SetFloatLines(id, []string{"bye"})
+	assert.Equal(t, "thre│bye│", floatRows(model)[2], "The lines of an open float should be replaceable")
+
+	model.CloseFloat(id)
+	assert.Equal(t, []string{"one", "two", "three", "", ""}, floatRows(model))
+}
+
+func TestFloatZIndexAndClipping(t *testing.T) {
+	model := newWrapEditor("", 12, 4)
+
+	model.OpenFloat([]string{"top", "top"}, FloatOptions{Row: 0, Col: 1, ZIndex: 10})
+	model.OpenFloat([]string{"bottom", "bottom"}, FloatOptions{Row: 1, Col: 0})
+	model.OpenFloat([]string{"a very long line that does not fit"}, FloatOptions{Row: -1, Col: -1})
+	assert.Equal(t, []string{
+		" top",
+		"btopom",
+		"bottom",
+		"a very long…",
+	}, floatRows(model), "Higher z-indexes should be drawn on top and floats clipped to the editor")
+}
+
+func TestFloatScroll(t *testing.T) {
+	model := newWrapEditor("", 10, 6)
+	lines := []string{"1", "2", "3", "4", "5", "6", "7", "8"}
+
+	id := model.OpenFloat(lines, FloatOptions{Height: 2, Border: lipgloss.NormalBorder()})
+	assert.Equal(t, []string{"┌─┐", "│1█", "│2│", "└─┘"}, floatRows(model)[:4])
+
+	model.ScrollFloat(id, 3)
+	assert.Equal(t, []string{"┌─┐", "│4█", "│5│", "└─┘"}, floatRows(model)[:4])
+
+	model.ScrollFloat(id, 100)
+	assert.Equal(t, []string{"┌─┐", "│7│", "│8█", "└─┘"}, floatRows(model)[:4], "Scrolling should stop at the last line")
+}
+
+func TestFloatAnchors(t *testing.T) {
+	model := newWrapEditor("alpha\nbeta\ngamma\ndelta", 20, 3)
+
+	model.OpenFloat([]string{"here"}, FloatOptions{Anchor: AnchorBuffer, Pos: Cursor{1, 2}, Row: 1})
+	cursor := model.OpenFloat([]string{"cur"}, FloatOptions{Anchor: AnchorCursor, Row: 1, Col: 1, CloseOnKey: true})
+	assert.Equal(t, []string{"alpha", "bcur", "gahere"}, floatRows(model))
+
+	sendKeys(model, "jjj")
+	assert.Nil(t, model.float(cursor), "Floats closing on keys should close")
+	assert.Equal(t, []string{"beta", "gahere", "delta"}, floatRows(model), "Floats should follow their buffer position")
+
+	sendKeys(model, "G")
+	model.viewport.YOffset = 2
+	assert.Equal(t, []string{"gamma", "delta", ""}, floatRows(model), "Floats should hide when their position is scrolled away")
+}
+
+func TestFloatWithoutSize(t *testing.T) {
+	model := NewEditor(WithContent("some text\nmore")).(*editorModel)
+	model.OpenFloat([]string{"float"}, FloatOptions{Row: 0, Col: 2})
+
+	content := "some text\nmore"
+	assert.Equal(t, "sofloatxt\nmore", ansi.Strip(model.renderFloats(content)), "Floats should fit the rendered content when the size is unknown")
+}
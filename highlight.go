@@ -2,22 +2,49 @@
 package vimtea
 
 import (
-	"bytes"
+	"container/list"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/alecthomas/chroma/v2"
 	"github.com/alecthomas/chroma/v2/lexers"
-	"github.com/alecthomas/chroma/v2/quick"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/lipgloss"
 )
 
+// highlightCacheSize is the number of highlighted rows kept in the cache
+const highlightCacheSize = 1024
+
 // syntaxHighlighter provides syntax highlighting functionality for the editor
-// using the Chroma library for language detection and highlighting
+// using the Chroma library for language detection and highlighting.
+// The whole document is tokenized so constructs spanning several lines,
+// such as block comments, are highlighted correctly. After an edit only the
+// lines from the change onwards are lexed again.
 type syntaxHighlighter struct {
-	filename    string            // File name used to determine language
-	language    string            // Detected language
-	syntaxTheme string            // Chroma theme to use for highlighting
-	enabled     bool              // Whether highlighting is enabled
-	cache       map[string]string // Cache of highlighted lines for performance
+	filename string                               // File name used to determine language
+	language string                               // Detected language
+	style    *chroma.Style                        // Chroma style of the syntax colors
+	enabled  bool                                 // Whether highlighting is enabled
+	filetype string                               // Language set explicitly, "" to detect it
+	analysed bool                                 // Whether the content was analysed to detect the language
+	lexer    chroma.Lexer                         // Lexer for the file, nil if the language is unknown
+	doc      highlightDoc                         // Tokens of the document
+	rows     *lruCache[int, []Span]               // Spans of document rows by row index
+	styles   map[chroma.TokenType]*lipgloss.Style // Styles of token types in the theme
+}
+
+// highlightDoc holds the tokens of a document split into lines
+type highlightDoc struct {
+	version int          // Buffer version the tokens were produced from
+	lines   []string     // Lines the tokens were produced from
+	state   []lineTokens // Tokens of each line
+}
+
+// lineTokens holds the tokens of one document line
+type lineTokens struct {
+	tokens []chroma.Token // Tokens of the line without the line break
+	safe   bool           // Whether lexing can restart at the start of the line
 }
 
 // yankHighlight provides visual feedback for yanked (copied) text
@@ -31,15 +58,20 @@ type yankHighlight struct {
 	IsLinewise bool          // Whether this is a line-wise operation
 }
 
-// newSyntaxHighlighter creates a new syntax highlighter with the specified theme and filename
+// newSyntaxHighlighter creates a new syntax highlighter with the specified style and filename
 // The filename is used to determine the language for syntax highlighting
-func newSyntaxHighlighter(syntaxTheme string, fileName string) *syntaxHighlighter {
-	return &syntaxHighlighter{
-		syntaxTheme: syntaxTheme,
-		enabled:     true,
-		filename:    fileName,
-		cache:       make(map[string]string),
+func newSyntaxHighlighter(style *chroma.Style, fileName string) *syntaxHighlighter {
+	if style == nil {
+		style = styles.Fallback
 	}
+	sh := &syntaxHighlighter{
+		style:   style,
+		enabled: true,
+		rows:    newLRUCache[int, []Span](highlightCacheSize),
+		styles:  make(map[chroma.TokenType]*lipgloss.Style),
+	}
+	sh.setFileName(fileName)
+	return sh
 }
 
 // newYankHighlight creates a new inactive yank highlight
@@ -51,43 +83,310 @@ func newYankHighlight() yankHighlight {
 	}
 }
 
-// HighlightLine applies syntax highlighting to a single line of text
-// It uses caching to improve performance for repeated lines
-func (sh *syntaxHighlighter) HighlightLine(line string) string {
-	// Skip highlighting if disabled or no filename is set
-	if !sh.enabled || sh.filename == "" {
-		return line
+// WithLanguage sets the language used for syntax highlighting, such as
+// "sql" or "json", instead of detecting it from the file name or content.
+// Names without a chroma lexer are kept as the filetype, e.g. for IndentRules.
+func WithLanguage(language string) EditorOption {
+	return func(o *options) {
+		o.Language = language
+	}
+}
+
+// RegisterLexer makes a custom chroma lexer available to all editors. It is
+// found by its name and aliases, e.g. with WithLanguage or :set filetype=,
+// and by the file name patterns in its config.
+func RegisterLexer(lexer chroma.Lexer) {
+	lexers.Register(lexer)
+}
+
+// RegisterStyle makes a custom chroma style available to all editors, e.g.
+// with WithDefaultSyntaxTheme or :colorscheme, which derives a Theme from it
+func RegisterStyle(style *chroma.Style) {
+	styles.Register(style)
+}
+
+// setFileName changes the file whose language is highlighted. The language
+// is detected again from the new name. The lexer is looked up once here
+// instead of for every line.
+func (sh *syntaxHighlighter) setFileName(fileName string) {
+	sh.filename = fileName
+	sh.setLanguage("")
+}
+
+// setLanguage sets the language by chroma name or alias. An empty name
+// detects the language from the file name, or from the content when the
+// file name does not match a lexer.
+func (sh *syntaxHighlighter) setLanguage(language string) {
+	sh.filetype = language
+	sh.analysed = false
+	sh.lexer = nil
+	sh.language = language
+	switch {
+	case language != "":
+		sh.useLexer(lexers.Get(language))
+	case sh.filename != "":
+		sh.useLexer(lexers.Match(sh.filename))
+	}
+	sh.reset()
+}
+
+// useLexer highlights with lexer, which may be nil
+func (sh *syntaxHighlighter) useLexer(lexer chroma.Lexer) {
+	if lexer == nil {
+		return
+	}
+	sh.lexer = chroma.Coalesce(lexer)
+	sh.language = lexer.Config().Name
+}
+
+// detect guesses the language from the content of the buffer when neither
+// the language nor the file name determine it. The content is analysed once.
+func (sh *syntaxHighlighter) detect(b *buffer) {
+	if sh.analysed || sh.lexer != nil || sh.filetype != "" {
+		return
+	}
+	text := strings.Join(b.lines, "\n")
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	sh.analysed = true
+	if lexer := lexers.Analyse(text); lexer != nil {
+		sh.useLexer(lexer)
+		sh.reset()
 	}
+}
+
+// setStyle changes the chroma style and drops everything rendered with the old one
+func (sh *syntaxHighlighter) setStyle(style *chroma.Style) {
+	sh.style = style
+	clear(sh.styles)
+	sh.rows.clear()
+}
+
+// reset drops all tokens and cached lines
+func (sh *syntaxHighlighter) reset() {
+	sh.doc = highlightDoc{}
+	sh.rows.clear()
+}
 
-	// Check cache for already highlighted lines
-	cacheKey := line
-	if cached, ok := sh.cache[cacheKey]; ok {
+// languageName returns the chroma name of the language of the file, the
+// filetype if it has no lexer, or "" if it is not recognised
+func (sh *syntaxHighlighter) languageName() string {
+	return sh.language
+}
+
+// fileType returns the language as a Vim filetype, e.g. "go"
+func (sh *syntaxHighlighter) fileType() string {
+	return strings.ToLower(sh.language)
+}
+
+// spans returns the syntax highlighting of a row of the document
+func (sh *syntaxHighlighter) spans(b *buffer, row int) []Span {
+	if !sh.enabled || b.lineLength(row) == 0 {
+		return nil
+	}
+	if sh.detect(b); sh.lexer == nil {
+		return nil
+	}
+
+	sh.sync(b)
+	if cached, ok := sh.rows.get(row); ok {
 		return cached
 	}
 
-	// Skip empty lines
-	if len(line) == 0 {
-		return line
+	var spans []Span
+	offset := 0
+	for _, tok := range sh.doc.state[row].tokens {
+		if style := sh.tokenStyle(tok.Type); style != nil {
+			spans = append(spans, Span{Start: offset, End: offset + len(tok.Value), Style: *style})
+		}
+		offset += len(tok.Value)
 	}
+	sh.rows.put(row, spans)
+	return spans
+}
 
-	lexer := lexers.Match(sh.filename)
+// tokenStyle converts the theme entry of a token type to a style. It
+// returns nil for token types the theme leaves unstyled.
+func (sh *syntaxHighlighter) tokenStyle(tokenType chroma.TokenType) *lipgloss.Style {
+	if style, ok := sh.styles[tokenType]; ok {
+		return style
+	}
 
-	if lexer == nil {
-		return line
+	entry := sh.style.Get(tokenType)
+	var style *lipgloss.Style
+	if entry.Colour.IsSet() || entry.Bold == chroma.Yes || entry.Italic == chroma.Yes || entry.Underline == chroma.Yes {
+		s := lipgloss.NewStyle()
+		if entry.Colour.IsSet() {
+			s = s.Foreground(lipgloss.Color(entry.Colour.String()))
+		}
+		if entry.Bold == chroma.Yes {
+			s = s.Bold(true)
+		}
+		if entry.Italic == chroma.Yes {
+			s = s.Italic(true)
+		}
+		if entry.Underline == chroma.Yes {
+			s = s.Underline(true)
+		}
+		style = &s
+	}
+	sh.styles[tokenType] = style
+	return style
+}
+
+// sync brings the tokens up to date with the buffer. Lines before the first
+// change keep their tokens, and lexing stops as soon as it is back in step
+// with the unchanged lines after the change.
+func (sh *syntaxHighlighter) sync(b *buffer) {
+	doc := &sh.doc
+	if doc.lines != nil && doc.version == b.version {
+		return
+	}
+
+	lines, old := b.lines, doc.lines
+	start := 0
+	for start < len(old) && start < len(lines) && old[start] == lines[start] {
+		start++
+	}
+	suffix := 0
+	for suffix < len(old)-start && suffix < len(lines)-start &&
+		old[len(old)-1-suffix] == lines[len(lines)-1-suffix] {
+		suffix++
 	}
 
-	// Apply syntax highlighting
-	buf := new(bytes.Buffer)
-	err := quick.Highlight(buf, line, lexer.Config().Name, "terminal16m", sh.syntaxTheme)
+	if doc.lines == nil || start < len(lines) || len(old) != len(lines) {
+		doc.state = sh.relex(lines, old, doc.state, start, suffix)
+		sh.rows.removeIf(func(row int) bool { return row >= start })
+	}
+	doc.lines = slices.Clone(lines)
+	doc.version = b.version
+}
+
+// relex lexes the document again from the last safe line before start.
+// Lexing restarts at least one line before the change so a wrong restart
+// point shows up as different tokens, in which case an earlier one is used.
+func (sh *syntaxHighlighter) relex(lines, old []string, state []lineTokens, start, suffix int) []lineTokens {
+	from := start - 1
+	for {
+		for from > 0 && !state[from].safe {
+			from--
+		}
+		from = max(from, 0)
+		if lexed, ok := sh.lexFrom(lines, old, state, from, start, suffix); ok {
+			return lexed
+		}
+		state[from].safe = false
+	}
+}
+
+// lexFrom lexes lines from the row from onwards, reusing the old tokens once
+// a safe line in the unchanged suffix is reached. It fails when a line
+// before start comes out different than before.
+func (sh *syntaxHighlighter) lexFrom(lines, old []string, state []lineTokens, from, start, suffix int) ([]lineTokens, bool) {
+	lexed := append(make([]lineTokens, 0, len(lines)), state[:from]...)
+	current := lineTokens{safe: true}
+	delta := len(lines) - len(old)
+
+	iter, err := sh.lexer.Tokenise(nil, strings.Join(lines[from:], "\n"))
 	if err != nil {
-		return line
+		iter = chroma.Literator()
 	}
+	for tok := iter(); tok != chroma.EOF; tok = iter() {
+		parts := strings.Split(tok.Value, "\n")
+		for i, part := range parts {
+			if part != "" {
+				current.tokens = append(current.tokens, chroma.Token{Type: tok.Type, Value: part})
+			}
+			if i == len(parts)-1 {
+				break
+			}
+
+			// The line ends here
+			row := len(lexed)
+			if from > 0 && row < start && !slices.Equal(current.tokens, state[row].tokens) {
+				return nil, false
+			}
+			lexed = append(lexed, current)
+			if len(lexed) == len(lines) {
+				return lexed, true
+			}
 
-	// Clean up the result by removing newlines
-	highlighted := strings.ReplaceAll(buf.String(), "\n", "")
+			// Lexing can restart on the next line only if the line break is
+			// not part of a string or comment spanning lines
+			current = lineTokens{safe: !tok.Type.InCategory(chroma.Comment) && !tok.Type.InSubCategory(chroma.String)}
 
-	// Cache the result for future use
-	sh.cache[cacheKey] = highlighted
+			row++
+			if row >= len(lines)-suffix && current.safe && state[row-delta].safe {
+				return append(lexed, state[row-delta:]...), true
+			}
+		}
+	}
+
+	for len(lexed) < len(lines) {
+		lexed = append(lexed, current)
+		current = lineTokens{}
+	}
+	return lexed, true
+}
+
+// lruCache holds at most size values and evicts the least recently used
+type lruCache[K comparable, V any] struct {
+	size    int
+	order   *list.List // Entries from most to least recently used
+	entries map[K]*list.Element
+}
+
+// lruEntry is an element of lruCache.order
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// newLRUCache creates an empty cache holding at most size entries
+func newLRUCache[K comparable, V any](size int) *lruCache[K, V] {
+	return &lruCache[K, V]{size: size, order: list.New(), entries: make(map[K]*list.Element)}
+}
+
+func (c *lruCache[K, V]) get(key K) (V, bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry[K, V]).value, true
+}
+
+func (c *lruCache[K, V]) put(key K, value V) {
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*lruEntry[K, V]).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key, value})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[K, V]).key)
+	}
+}
+
+// removeIf drops the entries whose key matches
+func (c *lruCache[K, V]) removeIf(match func(K) bool) {
+	for key, elem := range c.entries {
+		if match(key) {
+			c.order.Remove(elem)
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *lruCache[K, V]) clear() {
+	c.order.Init()
+	clear(c.entries)
+}
 
-	return highlighted
+func (c *lruCache[K, V]) len() int {
+	return c.order.Len()
 }
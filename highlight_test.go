@@ -1,69 +1,48 @@
 package vimtea
 
 import (
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/alecthomas/chroma/v2"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestSyntaxHighlighting(t *testing.T) {
-	testLine := "func testFunction() {}"
+	model := NewEditor(WithContent("func testFunction() {}"), WithFileName("test.go")).(*editorModel)
+	spans := model.highlighter.spans(model.buffer, 0)
+	require.NotEmpty(t, spans, "Highlighting should style the tokens of the line")
+	assert.Equal(t, Span{Start: 0, End: 4, Style: spans[0].Style}, spans[0], "The 'func' keyword should get its own span")
 
-	highlighter := newSyntaxHighlighter("monokai", "test.go")
+	model = NewEditor(WithContent("def test_function():"), WithFileName("test.py")).(*editorModel)
+	assert.NotEmpty(t, model.highlighter.spans(model.buffer, 0), "Python should be highlighted")
 
-	highlighted := highlighter.HighlightLine(testLine)
-
-	assert.Contains(t, highlighted, "\033[", "Highlighting should apply ANSI color codes")
-
-	assert.Contains(t, highlighted, "func", "Highlighting should preserve 'func' keyword")
-
-	highlighter = newSyntaxHighlighter("monokai", "test.py")
-	pythonLine := "def test_function():"
-	highlightedPython := highlighter.HighlightLine(pythonLine)
-
-	assert.Contains(t, highlightedPython, "def", "Python highlighting should preserve 'def' keyword")
-
-	highlighter = newSyntaxHighlighter("monokai", "README")
-	plainText := "This is plain text"
-	highlightedPlain := highlighter.HighlightLine(plainText)
-
-	assert.Equal(t, plainText, highlightedPlain, "Text with no recognized extension should be returned unchanged")
+	model = NewEditor(WithContent("This is plain text"), WithFileName("README")).(*editorModel)
+	assert.Empty(t, model.highlighter.spans(model.buffer, 0), "Text with no recognized extension should not be highlighted")
 }
 
 func TestHighlightCache(t *testing.T) {
-	testLine := "var x = 10;"
-
-	highlighter := newSyntaxHighlighter("monokai", "test.js")
-
-	highlighted1 := highlighter.HighlightLine(testLine)
+	model := NewEditor(WithContent("var x = 10;\nvar x = 20;"), WithFileName("test.js")).(*editorModel)
+	sh := model.highlighter
 
-	highlighted2 := highlighter.HighlightLine(testLine)
+	first := sh.spans(model.buffer, 0)
+	assert.Equal(t, 1, sh.rows.len(), "The spans of a row should be cached")
+	assert.Equal(t, first, sh.spans(model.buffer, 0), "Second highlight call should return same result")
 
-	assert.Equal(t, highlighted1, highlighted2, "Second highlight call should return same result")
-
-	testLine2 := "var x = 20;"
-	highlighted3 := highlighter.HighlightLine(testLine2)
-
-	assert.NotEqual(t, highlighted1, highlighted3, "Different lines should have different highlighting results")
+	model.buffer.setLine(0, "const y = 'a';")
+	assert.NotEqual(t, first, sh.spans(model.buffer, 0), "An edited line should be highlighted again")
 }
 
 func TestHighlightWithNoSyntax(t *testing.T) {
-	testCode := "Plain text without syntax highlighting"
-
-	highlighter := newSyntaxHighlighter("monokai", "")
-
-	highlighted := highlighter.HighlightLine(testCode)
-
-	assert.Equal(t, testCode, highlighted, "Text with empty filename should be returned unchanged")
-
-	highlighter = newSyntaxHighlighter("monokai", "test.go")
-	highlighter.enabled = false
-
-	highlighted = highlighter.HighlightLine(testCode)
+	model := NewEditor(WithContent("Plain text without syntax highlighting")).(*editorModel)
+	assert.Empty(t, model.highlighter.spans(model.buffer, 0), "Text with empty filename should not be highlighted")
 
-	assert.Equal(t, testCode, highlighted, "Text with disabled highlighting should be returned unchanged")
+	model = NewEditor(WithContent("func f() {}"), WithFileName("test.go")).(*editorModel)
+	model.highlighter.enabled = false
+	assert.Empty(t, model.highlighter.spans(model.buffer, 0), "Disabled highlighting should give no spans")
 }
 
 func TestYankHighlight(t *testing.T) {
@@ -90,3 +69,112 @@ func TestYankHighlight(t *testing.T) {
 
 	assert.Equal(t, ModeNormal, model.mode, "Mode should be ModeNormal after yanking")
 }
+
+func TestHighlightMultiLine(t *testing.T) {
+	model := NewEditor(WithContent("x := 1\n/* start\nx := 1\nend */\nx := 1"), WithFileName("main.go")).(*editorModel)
+	sh := model.highlighter
+	comment := sh.spans(model.buffer, 2)
+	code := sh.spans(model.buffer, 0)
+	assert.NotEqual(t, code, comment, "A line inside a block comment should be highlighted as a comment")
+	assert.Equal(t, code, sh.spans(model.buffer, 4), "Lines after the comment should be highlighted as code")
+
+	model.buffer.setLine(1, "// start")
+	assert.Equal(t, code, sh.spans(model.buffer, 2), "Removing the comment start should rehighlight the following lines")
+}
+
+func TestHighlightIncremental(t *testing.T) {
+	model := NewEditor(WithContent("package main\n\nfunc f() {\n\ts := `a\nb`\n\treturn\n}\n"), WithFileName("main.go")).(*editorModel)
+	sh := model.highlighter
+
+	edits := []func(b *buffer){
+		func(b *buffer) { b.setLine(2, "func g() {") },
+		func(b *buffer) { b.insertLine(5, "\t/* x") },
+		func(b *buffer) { b.setLine(6, "\treturn */") },
+		func(b *buffer) { b.deleteLine(4) },
+		func(b *buffer) { b.setLine(0, "/*") },
+	}
+	for i, edit := range edits {
+		sh.spans(model.buffer, 0)
+		edit(model.buffer)
+		sh.spans(model.buffer, 0)
+
+		fresh := newSyntaxHighlighter(sh.style, sh.filename)
+		fresh.sync(model.buffer)
+		assert.Equal(t, fresh.doc.state, sh.doc.state, "Edit %d should give the same tokens as lexing the whole document", i)
+	}
+
+	model = NewEditor(WithContent(strings.Repeat("x := 1\n", 100)), WithFileName("main.go")).(*editorModel)
+	sh = model.highlighter
+	sh.sync(model.buffer)
+	last := &sh.doc.state[99].tokens[0]
+	model.buffer.setLine(1, "y := 2")
+	sh.sync(model.buffer)
+	assert.Same(t, last, &sh.doc.state[99].tokens[0], "Lexing should stop once it is back in step after the edit")
+}
+
+func TestHighlightCacheBounded(t *testing.T) {
+	cache := newLRUCache[int, string](2)
+	cache.put(1, "a")
+	cache.put(2, "b")
+	cache.get(1)
+	cache.put(3, "c")
+
+	assert.Equal(t, 2, cache.len(), "The cache should not grow beyond its size")
+	_, ok := cache.get(2)
+	assert.False(t, ok, "The least recently used entry should be evicted")
+	value, ok := cache.get(1)
+	assert.True(t, ok)
+	assert.Equal(t, "a", value)
+
+	cache.removeIf(func(key int) bool { return key >= 3 })
+	assert.Equal(t, 1, cache.len())
+}
+
+func TestHighlightLanguageSelection(t *testing.T) {
+	model := NewEditor(WithContent("SELECT * FROM users;"), WithLanguage("sql")).(*editorModel)
+	assert.Equal(t, "SQL", model.highlighter.languageName(), "WithLanguage should select the lexer without a file name")
+	assert.NotEmpty(t, model.highlighter.spans(model.buffer, 0))
+
+	model = NewEditor(WithContent("#!/bin/bash\necho hello")).(*editorModel)
+	assert.NotEmpty(t, model.highlighter.spans(model.buffer, 1), "The language should be detected from the content")
+	assert.Equal(t, "Bash", model.highlighter.languageName())
+
+	runCommand(t, model, "set ft=python")
+	assert.Equal(t, "Python", model.highlighter.languageName())
+	value, err := model.GetOption("filetype")
+	require.NoError(t, err)
+	assert.Equal(t, "python", value)
+
+	runCommand(t, model, "set ft=flow")
+	assert.Equal(t, "flow", model.highlighter.languageName(), "Filetypes without a lexer should be kept")
+	assert.Empty(t, model.highlighter.spans(model.buffer, 1))
+}
+
+func TestHighlightCustomLexerAndStyle(t *testing.T) {
+	RegisterLexer(chroma.MustNewLexer(&chroma.Config{
+		Name:      "VimteaFlow",
+		Aliases:   []string{"vimteaflow"},
+		Filenames: []string{"*.vtflow"},
+	}, func() chroma.Rules {
+		return chroma.Rules{"root": {
+			{Pattern: `\bstep\b`, Type: chroma.Keyword},
+			{Pattern: `\s+`, Type: chroma.Whitespace},
+			{Pattern: `\w+`, Type: chroma.Name},
+		}}
+	}))
+	RegisterStyle(chroma.MustNewStyle("vimtea-test", chroma.StyleEntries{chroma.Keyword: "#ff0000"}))
+
+	model := NewEditor(WithContent("step run"), WithFileName("job.vtflow")).(*editorModel)
+	assert.Equal(t, "VimteaFlow", model.highlighter.languageName(), "Custom lexers should match their file patterns")
+
+	runCommand(t, model, "colorscheme vimtea-test")
+	assert.Equal(t, "vimtea-test", model.theme.Name)
+	assert.Equal(t, "vimtea-test", model.highlighter.style.Name)
+	spans := model.highlighter.spans(model.buffer, 0)
+	require.NotEmpty(t, spans)
+	assert.Equal(t, lipgloss.Color("#ff0000"), spans[0].Style.GetForeground(), "Spans should use the new color scheme")
+
+	msgs := runCommand(t, model, "colorscheme missing")
+	assert.Contains(t, msgs, statusMessageMsg("E185: Cannot find color scheme 'missing'"))
+	assert.Equal(t, "vimtea-test", model.highlighter.style.Name)
+}
@@ -0,0 +1,340 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultTabStop is the visual width of a tab character unless configured
+const defaultTabStop = 4
+
+// tabSettings holds the tab and indentation settings of a buffer
+type tabSettings struct {
+	tabStop     int  // Visual width of a tab character
+	shiftWidth  int  // Width of one indent level, 0 means tabStop
+	softTabStop int  // Width of <Tab> and <BS> in insert mode, 0 disables
+	expandTab   bool // Whether <Tab> inserts spaces instead of tab characters
+}
+
+// defaultTabSettings returns the settings used when nothing is configured
+func defaultTabSettings() tabSettings {
+	return tabSettings{tabStop: defaultTabStop}
+}
+
+// shift returns the effective width of one indent level
+func (t tabSettings) shift() int {
+	if t.shiftWidth > 0 {
+		return t.shiftWidth
+	}
+	return t.tabStop
+}
+
+// softTab returns the soft tab stop, or 0 when soft tabs are off
+func (t tabSettings) softTab() int {
+	return t.softTabStop
+}
+
+// fill returns the whitespace that spans the visual columns from start to end,
+// using tab characters where possible unless expandtab is set
+func (t tabSettings) fill(start, end int) string {
+	if end <= start {
+		return ""
+	}
+	if t.expandTab {
+		return strings.Repeat(" ", end-start)
+	}
+
+	var sb strings.Builder
+	for col := start; col < end; {
+		next := (col/t.tabStop + 1) * t.tabStop
+		if next > end {
+			sb.WriteString(strings.Repeat(" ", end-col))
+			break
+		}
+		sb.WriteByte('\t')
+		col = next
+	}
+	return sb.String()
+}
+
+// WithTabStop sets the visual width of a tab character
+func WithTabStop(width int) EditorOption {
+	return func(o *options) {
+		o.TabStop = width
+	}
+}
+
+// WithShiftWidth sets the width of one indent level.
+// Zero uses the tab stop.
+func WithShiftWidth(width int) EditorOption {
+	return func(o *options) {
+		o.ShiftWidth = width
+	}
+}
+
+// WithSoftTabStop makes <Tab> and <BS> in insert mode work in steps of width
+// columns, mixing tabs and spaces as needed. Zero disables soft tabs.
+func WithSoftTabStop(width int) EditorOption {
+	return func(o *options) {
+		o.SoftTabStop = width
+	}
+}
+
+// WithExpandTab makes <Tab> insert spaces instead of tab characters
+func WithExpandTab(enable bool) EditorOption {
+	return func(o *options) {
+		o.ExpandTab = enable
+	}
+}
+
+// whitespaceBefore returns the byte offset where the run of spaces and tabs
+// ending at col starts
+func whitespaceBefore(line string, col int) int {
+	start := col
+	for start > 0 && (line[start-1] == ' ' || line[start-1] == '\t') {
+		start--
+	}
+	return start
+}
+
+func handleInsertTab(model *editorModel) tea.Cmd {
+	model.buffer.saveUndoState(model.cursor)
+
+	tabs := model.buffer.tabs
+	line := model.buffer.Line(model.cursor.Row)
+	col := model.cursor.Col
+
+	// Without soft tabs or expandtab a literal tab is inserted
+	step := tabs.softTab()
+	if step == 0 {
+		if !tabs.expandTab {
+			model.buffer.setLine(model.cursor.Row, line[:col]+"\t"+line[col:])
+			model.cursor.Col++
+			return nil
+		}
+		step = tabs.tabStop
+	}
+
+	// Rewrite the whitespace before the cursor so it reaches the next stop
+	start := whitespaceBefore(line, col)
+	startVisual := bufferToVisualPosition(line, start, tabs.tabStop)
+	target := (bufferToVisualPosition(line, col, tabs.tabStop)/step + 1) * step
+	indent := tabs.fill(startVisual, target)
+
+	model.buffer.setLine(model.cursor.Row, line[:start]+indent+line[col:])
+	model.cursor.Col = start + len(indent)
+	return nil
+}
+
+// deleteSoftTab removes the whitespace before the cursor back to the
+// previous soft tab stop. It reports false when the character before the
+// cursor is not whitespace or soft tabs are disabled.
+func deleteSoftTab(model *editorModel) bool {
+	tabs := model.buffer.tabs
+	step := tabs.softTab()
+	line := model.buffer.Line(model.cursor.Row)
+	col := model.cursor.Col
+
+	start := whitespaceBefore(line, col)
+	if step == 0 || start == col {
+		return false
+	}
+
+	visual := bufferToVisualPosition(line, col, tabs.tabStop)
+	startVisual := bufferToVisualPosition(line, start, tabs.tabStop)
+	target := max((visual-1)/step*step, startVisual)
+
+	// Keep the whitespace that ends before the target and refill the rest
+	keep := start
+	for keep < col && bufferToVisualPosition(line, keep+1, tabs.tabStop) <= target {
+		keep++
+	}
+	keepVisual := bufferToVisualPosition(line, keep, tabs.tabStop)
+	indent := tabs.fill(keepVisual, target)
+
+	model.buffer.setLine(model.cursor.Row, line[:keep]+indent+line[col:])
+	model.cursor.Col = keep + len(indent)
+	return true
+}
+
+// IndentOptions describes the indentation settings of the buffer being indented
+type IndentOptions struct {
+	TabStop    int    // Visual width of a tab character
+	ShiftWidth int    // Width of one indent level
+	ExpandTab  bool   // Whether indentation uses spaces only
+	Language   string // Chroma language name of the buffer, if known
+	FileName   string // Name of the file being edited
+}
+
+// Indenter computes the indentation of lines for the = operator
+type Indenter interface {
+	// Indent returns the indentation width in columns for the line at row,
+	// or -1 to leave the line unchanged
+	Indent(b Buffer, row int, opts IndentOptions) int
+}
+
+// IndenterFunc adapts a function to the Indenter interface
+type IndenterFunc func(b Buffer, row int, opts IndentOptions) int
+
+// Indent calls f(b, row, opts)
+func (f IndenterFunc) Indent(b Buffer, row int, opts IndentOptions) int {
+	return f(b, row, opts)
+}
+
+// CopyIndenter indents every line like the nearest non-blank line above it
+var CopyIndenter Indenter = IndenterFunc(func(b Buffer, row int, opts IndentOptions) int {
+	for prev := row - 1; prev >= 0; prev-- {
+		if line := b.Line(prev); strings.TrimSpace(line) != "" {
+			return IndentWidth(line, opts.TabStop)
+		}
+	}
+	return 0
+})
+
+// IndentWidth returns the visual width of the leading whitespace of line
+func IndentWidth(line string, tabStop int) int {
+	return bufferToVisualPosition(line, len(line)-len(strings.TrimLeft(line, " \t")), tabStop)
+}
+
+// WithIndenter sets the Indenter used by the = operator and, with smart
+// indent, for new lines
+func WithIndenter(indenter Indenter) EditorOption {
+	return func(o *options) {
+		o.Indenter = indenter
+	}
+}
+
+// WithAutoIndent enables or disables copying the indentation of the current
+// line to new lines started with Enter, o and O
+func WithAutoIndent(enable bool) EditorOption {
+	return func(o *options) {
+		o.AutoIndent = enable
+	}
+}
+
+// setIndent replaces the leading whitespace of a line so that it is width columns wide
+func (m *editorModel) setIndent(row, width int) {
+	line := m.buffer.Line(row)
+	content := strings.TrimLeft(line, " \t")
+	if newLine := m.buffer.tabs.fill(0, width) + content; newLine != line {
+		m.buffer.setLine(row, newLine)
+	}
+}
+
+// shiftLines indents the lines from start to end by levels shift widths.
+// Negative levels dedent. Empty lines are not indented.
+func (m *editorModel) shiftLines(start, end, levels int) {
+	tabs := m.buffer.tabs
+	for row := start; row <= end; row++ {
+		line := m.buffer.Line(row)
+		if levels > 0 && line == "" {
+			continue
+		}
+		m.setIndent(row, max(0, IndentWidth(line, tabs.tabStop)+levels*tabs.shift()))
+	}
+}
+
+// reindentLines applies the Indenter to the lines from start to end.
+// Blank lines are left empty.
+func (m *editorModel) reindentLines(start, end int) {
+	opts := m.indentOptions()
+	indenter := m.currentIndenter()
+	for row := start; row <= end; row++ {
+		if strings.TrimSpace(m.buffer.Line(row)) == "" {
+			m.buffer.setLine(row, "")
+			continue
+		}
+		if width := indenter.Indent(m.GetBuffer(), row, opts); width >= 0 {
+			m.setIndent(row, width)
+		}
+	}
+}
+
+// lineRangeOperator returns a command that applies op to the range of
+// lines returned by lines
+func lineRangeOperator(op func(m *editorModel, start, end int), lines func(m *editorModel) (int, int)) Command {
+	return func(model *editorModel) tea.Cmd {
+		start, end := lines(model)
+
+		model.buffer.saveUndoState(model.cursor)
+		op(model, start, end)
+		model.cursor.Row = start
+		model.cursor.Col = 0
+		moveToFirstNonWhitespace(model)
+		model.ensureCursorVisible()
+		return nil
+	}
+}
+
+// countLines returns the count lines starting at the cursor line
+func countLines(m *editorModel) (int, int) {
+	end := min(m.cursor.Row+m.countPrefix-1, m.buffer.lineCount()-1)
+	m.countPrefix = 1
+	return m.cursor.Row, end
+}
+
+// visualRangeOperator returns a command that applies op to the lines of
+// the visual selection and returns to normal mode
+func visualRangeOperator(op func(m *editorModel, start, end int)) Command {
+	return func(model *editorModel) tea.Cmd {
+		selStart, selEnd := model.GetSelectionBoundary()
+		model.cursor = selStart
+		model.buffer.saveUndoState(model.cursor)
+		op(model, selStart.Row, selEnd.Row)
+
+		model.cursor.Col = 0
+		moveToFirstNonWhitespace(model)
+		return switchMode(model, ModeNormal)
+	}
+}
+
+// shiftBy returns an operator that shifts lines by the count prefix,
+// in the direction of sign
+func shiftBy(sign int) func(m *editorModel, start, end int) {
+	return func(m *editorModel, start, end int) {
+		levels := sign
+		if m.mode == ModeVisual {
+			levels *= m.countPrefix
+		}
+		m.shiftLines(start, end, levels)
+	}
+}
+
+// registerIndentBindings registers the indent operators: >>, << and ==
+// with counts, their forms with any motion or paragraph text object, such
+// as >j, >}, >ip and =%, and visual >, < and =
+func registerIndentBindings(m *editorModel) {
+	operators := []struct {
+		key  string
+		op   func(m *editorModel, start, end int)
+		help string
+	}{
+		{">", shiftBy(1), "Indent"},
+		{"<", shiftBy(-1), "Dedent"},
+		{"=", (*editorModel).reindentLines, "Reindent"},
+	}
+
+	for _, o := range operators {
+		m.registry.Add(o.key+o.key, lineRangeOperator(o.op, countLines), ModeNormal, o.help+" line")
+		for _, mo := range motions {
+			lines := func(m *editorModel) (int, int) { return m.motionLines(mo) }
+			m.registry.Add(o.key+mo.key, lineRangeOperator(o.op, lines), ModeNormal, o.help+" lines")
+		}
+		for _, obj := range lineTextObjects {
+			m.registry.Add(o.key+obj.key, lineRangeOperator(o.op, obj.lines), ModeNormal, o.help+" "+obj.help)
+		}
+		m.registry.Add(o.key, visualRangeOperator(o.op), ModeVisual, o.help+" selection")
+	}
+}
+
+// newLineIndent returns the indentation for a new line started next to row,
+// which is the indentation of row when autoindent is enabled
+func (m *editorModel) newLineIndent(row int) string {
+	if !m.autoIndent {
+		return ""
+	}
+	line := m.buffer.Line(row)
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
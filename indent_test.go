@@ -0,0 +1,230 @@
+package vimtea
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTabStopRendering(t *testing.T) {
+	assert.Equal(t, 8, visualLength("\tx", 0, 8)-1, "Tab should extend to the next tab stop")
+	assert.Equal(t, 4, bufferToVisualPosition("ab\tc", 3, 4))
+	assert.Equal(t, "ab      c", renderTabsFrom("ab\tc", 0, 8))
+
+	model := NewEditor(WithContent("\tx"), WithTabStop(8)).(*editorModel)
+	assert.Equal(t, 9, model.buffer.visualLineLength(0), "Buffer should use the configured tab stop")
+
+	runCommand(t, model, "set ts=2")
+	assert.Equal(t, 3, model.buffer.visualLineLength(0), ":set ts should change the tab width")
+}
+
+func TestInsertTab(t *testing.T) {
+	model := NewEditor(WithContent("x")).(*editorModel)
+	model.mode = ModeInsert
+
+	handleInsertTab(model)
+	assert.Equal(t, "\tx", model.buffer.Line(0), "Default should insert a literal tab")
+	assert.Equal(t, 1, model.cursor.Col)
+
+	model = NewEditor(WithContent("ab"), WithExpandTab(true), WithTabStop(4)).(*editorModel)
+	model.cursor.Col = 1
+	handleInsertTab(model)
+	assert.Equal(t, "a   b", model.buffer.Line(0), "expandtab should insert spaces up to the next tab stop")
+	assert.Equal(t, 4, model.cursor.Col)
+
+	model = NewEditor(WithContent(""), WithTabStop(8), WithSoftTabStop(4)).(*editorModel)
+	handleInsertTab(model)
+	assert.Equal(t, "    ", model.buffer.Line(0), "Soft tab should insert spaces before a full tab stop")
+	handleInsertTab(model)
+	assert.Equal(t, "\t", model.buffer.Line(0), "Soft tabs should combine into a tab character")
+	assert.Equal(t, 1, model.cursor.Col)
+}
+
+func TestBackspaceOverSoftTab(t *testing.T) {
+	model := NewEditor(WithContent("        x"), WithExpandTab(true), WithSoftTabStop(2)).(*editorModel)
+	model.mode = ModeInsert
+	model.cursor.Col = 8
+
+	handleInsertBackspace(model)
+	assert.Equal(t, "      x", model.buffer.Line(0), "Backspace should delete back to the previous soft tab stop")
+	assert.Equal(t, 6, model.cursor.Col)
+
+	model = NewEditor(WithContent("\tx"), WithTabStop(8), WithSoftTabStop(4)).(*editorModel)
+	model.cursor.Col = 1
+	handleInsertBackspace(model)
+	assert.Equal(t, "    x", model.buffer.Line(0), "A tab should be split into the remaining soft tab")
+	assert.Equal(t, 4, model.cursor.Col)
+
+	model = NewEditor(WithContent("ab  "), WithSoftTabStop(4)).(*editorModel)
+	model.cursor.Col = 4
+	handleInsertBackspace(model)
+	assert.Equal(t, "ab", model.buffer.Line(0), "Backspace should stop at the start of the whitespace")
+
+	model = NewEditor(WithContent("    "), WithExpandTab(true)).(*editorModel)
+	model.cursor.Col = 4
+	handleInsertBackspace(model)
+	assert.Equal(t, "   ", model.buffer.Line(0), "Without softtabstop backspace deletes one character")
+}
+
+func TestTabOptions(t *testing.T) {
+	model := NewEditor().(*editorModel)
+
+	runCommand(t, model, "set et sw=2 sts=4")
+	assert.Equal(t, tabSettings{tabStop: defaultTabStop, shiftWidth: 2, softTabStop: 4, expandTab: true}, model.buffer.tabs)
+
+	msgs := runCommand(t, model, "set sts=-1")
+	assert.Contains(t, msgs, tea.Msg(statusMessageMsg("E487: Argument must be positive")))
+	assert.Equal(t, 4, model.buffer.tabs.softTabStop, "softtabstop must not be negative")
+
+	msgs = runCommand(t, model, "set ts=0")
+	assert.NotEmpty(t, msgs)
+	assert.Equal(t, defaultTabStop, model.buffer.tabs.tabStop, "tabstop must be positive")
+}
+
+// sendKeys types each rune of keys as a separate key press
+func sendKeys(model *editorModel, keys string) {
+	for _, r := range keys {
+		model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+}
+
+func TestShiftOperators(t *testing.T) {
+	model := NewEditor(WithContent("a\nb\n\nc"), WithShiftWidth(2), WithExpandTab(true)).(*editorModel)
+
+	sendKeys(model, ">>")
+	assert.Equal(t, "  a\nb\n\nc", model.buffer.text(), ">> should indent the current line")
+	assert.Equal(t, Cursor{0, 2}, model.cursor, "Cursor should move to the first non-blank")
+
+	sendKeys(model, "3>>")
+	assert.Equal(t, "    a\n  b\n\nc", model.buffer.text(), "A count should shift that many lines, skipping empty ones")
+
+	sendKeys(model, "<j")
+	assert.Equal(t, "  a\nb\n\nc", model.buffer.text(), "<j should dedent the current and the next line")
+
+	sendKeys(model, "<<<<")
+	assert.Equal(t, "a\nb\n\nc", model.buffer.text(), "Dedent should stop at column zero")
+
+	sendKeys(model, ">G")
+	assert.Equal(t, "  a\n  b\n\n  c", model.buffer.text(), ">G should indent to the end of the buffer")
+
+	_, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'u'}})
+	collectMsgs(cmd)
+	assert.Equal(t, "a\nb\n\nc", model.buffer.text(), "A shift should be undone in one step")
+}
+
+func TestOperatorMotions(t *testing.T) {
+	model := NewEditor(WithContent("a b\nc\n\nd\ne"), WithShiftWidth(2), WithExpandTab(true)).(*editorModel)
+
+	sendKeys(model, ">w")
+	assert.Equal(t, "  a b\nc\n\nd\ne", model.buffer.text(), ">w should shift the line the word motion stays on")
+
+	sendKeys(model, "<}")
+	assert.Equal(t, "a b\nc\n\nd\ne", model.buffer.text())
+	sendKeys(model, ">}")
+	assert.Equal(t, "  a b\n  c\n\nd\ne", model.buffer.text(), ">} should shift to the end of the paragraph")
+
+	model.cursor = Cursor{4, 0}
+	sendKeys(model, ">ip")
+	assert.Equal(t, "  a b\n  c\n\n  d\n  e", model.buffer.text(), ">ip should shift the paragraph at the cursor")
+	assert.Equal(t, Cursor{3, 2}, model.cursor)
+
+	sendKeys(model, "<ap")
+	assert.Equal(t, "  a b\n  c\n\nd\ne", model.buffer.text(), "ap at the end of the buffer should take the empty lines before it")
+
+	model = NewEditor(WithContent("func f() {\nx()\n\ty()\n}\nz()"), WithTabStop(4)).(*editorModel)
+	sendKeys(model, "$=%")
+	assert.Equal(t, "func f() {\nx()\ny()\n}\nz()", model.buffer.text(), "=% should reindent to the matching bracket")
+
+	model.cursor = Cursor{3, 0}
+	sendKeys(model, "%")
+	assert.Equal(t, Cursor{0, 9}, model.cursor, "% should jump back to the opening bracket")
+	sendKeys(model, "}")
+	assert.Equal(t, Cursor{4, 2}, model.cursor, "} without an empty line should go to the end of the buffer")
+	sendKeys(model, "{")
+	assert.Equal(t, Cursor{0, 0}, model.cursor)
+}
+
+func TestShiftUsesTabs(t *testing.T) {
+	model := NewEditor(WithContent("x"), WithTabStop(8), WithShiftWidth(4)).(*editorModel)
+
+	sendKeys(model, ">>")
+	assert.Equal(t, "    x", model.buffer.Line(0))
+	sendKeys(model, ">>")
+	assert.Equal(t, "\tx", model.buffer.Line(0), "Indentation should use tabs without expandtab")
+}
+
+func TestVisualShift(t *testing.T) {
+	model := NewEditor(WithContent("a\nb\nc"), WithShiftWidth(1), WithExpandTab(true)).(*editorModel)
+
+	sendKeys(model, "Vj2>")
+	assert.Equal(t, "  a\n  b\nc", model.buffer.text(), "Visual > should shift the selection count times")
+	assert.Equal(t, ModeNormal, model.mode, "Visual shift should return to normal mode")
+
+	sendKeys(model, "Vj<")
+	assert.Equal(t, " a\n b\nc", model.buffer.text())
+}
+
+func TestReindentOperator(t *testing.T) {
+	model := NewEditor(WithContent("\tif x {\ny()\n      z()\n  \n}"), WithTabStop(4)).(*editorModel)
+	model.cursor.Row = 1
+
+	sendKeys(model, "=G")
+	assert.Equal(t, "\tif x {\n\ty()\n\tz()\n\n\t}", model.buffer.text(), "= should copy the previous indentation by default")
+
+	indenter := IndenterFunc(func(b Buffer, row int, opts IndentOptions) int {
+		return row * opts.ShiftWidth
+	})
+	model = NewEditor(WithContent("a\nb\nc"), WithIndenter(indenter), WithExpandTab(true), WithShiftWidth(2)).(*editorModel)
+	sendKeys(model, "3==")
+	assert.Equal(t, "a\n  b\n    c", model.buffer.text(), "= should use the configured Indenter")
+}
+
+func TestAutoIndent(t *testing.T) {
+	model := NewEditor(WithContent("\tfoo bar")).(*editorModel)
+	model.mode = ModeInsert
+	model.cursor.Col = 4
+
+	handleInsertEnterKey(model)
+	assert.Equal(t, "\tfoo\n\tbar", model.buffer.text(), "Enter should copy the indentation")
+	assert.Equal(t, Cursor{1, 1}, model.cursor)
+
+	switchMode(model, ModeNormal)
+	openLineBelow(model)
+	assert.Equal(t, "\tfoo\n\tbar\n\t", model.buffer.text(), "o should copy the indentation")
+
+	switchMode(model, ModeNormal)
+	model.cursor = Cursor{0, 0}
+	openLineAbove(model)
+	assert.Equal(t, "\t\n\tfoo\n\tbar\n\t", model.buffer.text(), "O should copy the indentation")
+
+	model = NewEditor(WithContent("  x"), WithAutoIndent(false)).(*editorModel)
+	openLineBelow(model)
+	assert.Equal(t, "  x\n", model.buffer.text(), "Without autoindent new lines start at column zero")
+}
+
+func TestAutoIndentRemoved(t *testing.T) {
+	model := NewEditor(WithContent("\tfoo")).(*editorModel)
+
+	sendKeys(model, "o")
+	assert.Equal(t, "\tfoo\n\t", model.buffer.text())
+	assert.Equal(t, Cursor{1, 1}, model.cursor, "o should leave the cursor after the indentation")
+	model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, "\tfoo\n", model.buffer.text(), "Leaving insert mode should remove the indentation if nothing was typed")
+	assert.Equal(t, Cursor{1, 0}, model.cursor)
+
+	sendKeys(model, "ggobar")
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	sendKeys(model, "baz")
+	model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, "\tfoo\n\tbar\n\n\tbaz\n", model.buffer.text(), "Enter should remove the indentation of an untouched line")
+
+	sendKeys(model, "ggo")
+	model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, "\tfoo\n\n\tbar\n\n\tbaz\n", model.buffer.text())
+	sendKeys(model, "ggox")
+	model.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, "\tfoo\n\t\n\n\tbar\n\n\tbaz\n", model.buffer.text(), "Indentation should stay once something was typed")
+}
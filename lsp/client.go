@@ -0,0 +1,381 @@
+// Package lsp connects vimtea editors to language servers using the
+// Language Server Protocol over stdio.
+//
+// A Client talks to one server process. Attach opens a document of an
+// editor on the server, keeps it in sync with incremental changes and adds
+// hover (K), go to definition (gd), completion items for <C-n> and the
+// :LspRename, :LspDocumentFormat and :LspCodeAction commands. Results and
+// server notifications such as diagnostics arrive as tea messages, which
+// the Session applies to the editor:
+//
+//	client, err := lsp.Start("gopls")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	if err := client.Initialize(ctx, lsp.FileURI(root)); err != nil {
+//		log.Fatal(err)
+//	}
+//	session := lsp.Attach(editor, client, "main.go", "go")
+//
+//	func (m model) Init() tea.Cmd {
+//		return tea.Batch(m.editor.Init(), m.session.Init())
+//	}
+//
+//	func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+//		_, cmd := m.editor.Update(msg)
+//		return m, tea.Batch(cmd, m.session.Update(msg))
+//	}
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// notificationBuffer is the number of server notifications kept until they
+// are received with Listen
+const notificationBuffer = 64
+
+// Client is a connection to a language server
+type Client struct {
+	conn *Conn
+	cmd  *exec.Cmd // Server process, nil for clients created with NewClient
+
+	notifications chan tea.Msg // Server notifications as messages
+
+	mu           sync.Mutex
+	capabilities ServerCapabilities
+	encoding     string // Negotiated position encoding
+	listener     bool   // Whether a session already listens for notifications
+}
+
+// DiagnosticsMsg is sent when the server publishes the diagnostics of a document
+type DiagnosticsMsg struct {
+	URI         DocumentURI
+	Diagnostics []Diagnostic
+}
+
+// ShowMessageMsg is sent when the server asks to show a message to the user
+type ShowMessageMsg struct {
+	Type    int // 1 error, 2 warning, 3 info, 4 log
+	Message string
+}
+
+// Start launches a language server as a subprocess and connects to its
+// standard input and output. The server still has to be initialized.
+func Start(command string, args ...string) (*Client, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Stderr = io.Discard
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	c := NewClient(stdout, stdin)
+	c.cmd = cmd
+	return c, nil
+}
+
+// NewClient creates a client for a server that reads requests from w and
+// writes responses to r, for example an in-process server over pipes
+func NewClient(r io.Reader, w io.WriteCloser) *Client {
+	c := &Client{
+		notifications: make(chan tea.Msg, notificationBuffer),
+		encoding:      EncodingUTF16,
+	}
+	c.conn = NewConn(r, w, w, c.handle)
+	return c
+}
+
+// FileURI returns the URI of a file path
+func FileURI(path string) DocumentURI {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return DocumentURI("file://" + filepath.ToSlash(path))
+}
+
+// Initialize performs the initialize handshake. Positions are exchanged in
+// UTF-8 when the server supports it and in UTF-16 otherwise.
+func (c *Client) Initialize(ctx context.Context, rootURI DocumentURI) error {
+	params := map[string]any{
+		"processId": os.Getpid(),
+		"rootUri":   rootURI,
+		"clientInfo": map[string]any{
+			"name": "vimtea",
+		},
+		"capabilities": map[string]any{
+			"general": map[string]any{
+				"positionEncodings": []string{EncodingUTF8, EncodingUTF16},
+			},
+			"textDocument": map[string]any{
+				"synchronization": map[string]any{"didSave": true},
+				"hover":           map[string]any{"contentFormat": []string{"plaintext", "markdown"}},
+				"completion": map[string]any{
+					"completionItem": map[string]any{"snippetSupport": true},
+				},
+				"publishDiagnostics": map[string]any{},
+				"rename":             map[string]any{},
+				"formatting":         map[string]any{},
+				"codeAction":         map[string]any{},
+				"definition":         map[string]any{},
+			},
+		},
+	}
+
+	var result InitializeResult
+	if err := c.conn.Call(ctx, "initialize", params, &result); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.capabilities = result.Capabilities
+	if result.Capabilities.PositionEncoding == EncodingUTF8 {
+		c.encoding = EncodingUTF8
+	}
+	c.mu.Unlock()
+	return c.conn.Notify("initialized", map[string]any{})
+}
+
+// Encoding returns the negotiated position encoding
+func (c *Client) Encoding() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.encoding
+}
+
+// Close asks the server to shut down and exit, and waits for the process
+func (c *Client) Close(ctx context.Context) error {
+	err := c.conn.Call(ctx, "shutdown", nil, nil)
+	if err == nil {
+		err = c.conn.Notify("exit", nil)
+	}
+	_ = c.conn.Close()
+	if c.cmd != nil {
+		if werr := c.cmd.Wait(); err == nil {
+			err = werr
+		}
+	}
+	if errors.Is(err, ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// Listen returns a command that waits for the next server notification.
+// Call it again after every notification to keep listening; Session does
+// this for the first session of a client.
+func (c *Client) Listen() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case msg := <-c.notifications:
+			return msg
+		case <-c.conn.Done():
+			return nil
+		}
+	}
+}
+
+// claimListener reports whether the caller is the first to listen
+func (c *Client) claimListener() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	first := !c.listener
+	c.listener = true
+	return first
+}
+
+// notify queues a server notification. The oldest one is dropped when
+// nobody listens, so the connection never blocks on the application.
+func (c *Client) notify(msg tea.Msg) {
+	for {
+		select {
+		case c.notifications <- msg:
+			return
+		default:
+			select {
+			case <-c.notifications:
+			default:
+			}
+		}
+	}
+}
+
+// handle answers requests and notifications from the server
+func (c *Client) handle(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "textDocument/publishDiagnostics":
+		var p PublishDiagnosticsParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		c.notify(DiagnosticsMsg{URI: p.URI, Diagnostics: p.Diagnostics})
+	case "window/showMessage":
+		var p ShowMessageParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		c.notify(ShowMessageMsg(p))
+	case "workspace/configuration":
+		// One null configuration per requested item
+		var p struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		_ = json.Unmarshal(params, &p)
+		return make([]any, len(p.Items)), nil
+	case "workspace/applyEdit":
+		var p struct {
+			Edit WorkspaceEdit `json:"edit"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		c.notify(WorkspaceEditMsg{Edit: &p.Edit})
+		return map[string]any{"applied": true}, nil
+	}
+	// Requests such as client/registerCapability and
+	// window/workDoneProgress/create only need an answer
+	return nil, nil
+}
+
+// DidOpen tells the server that a document was opened
+func (c *Client) DidOpen(item TextDocumentItem) error {
+	return c.conn.Notify("textDocument/didOpen", map[string]any{"textDocument": item})
+}
+
+// DidChange sends changes of a document. Servers that only accept full
+// documents get the last change, which must then replace the whole text.
+func (c *Client) DidChange(doc VersionedTextDocumentIdentifier, changes []TextDocumentContentChangeEvent) error {
+	return c.conn.Notify("textDocument/didChange", map[string]any{
+		"textDocument":   doc,
+		"contentChanges": changes,
+	})
+}
+
+// DidSave tells the server that a document was written
+func (c *Client) DidSave(doc TextDocumentIdentifier) error {
+	return c.conn.Notify("textDocument/didSave", map[string]any{"textDocument": doc})
+}
+
+// DidClose tells the server that a document was closed
+func (c *Client) DidClose(doc TextDocumentIdentifier) error {
+	return c.conn.Notify("textDocument/didClose", map[string]any{"textDocument": doc})
+}
+
+// incremental reports whether the server accepts incremental changes
+func (c *Client) incremental() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.capabilities.syncKind() != 1
+}
+
+// Hover requests information about the symbol at a position. The result
+// is nil when there is none.
+func (c *Client) Hover(ctx context.Context, params TextDocumentPositionParams) (*Hover, error) {
+	var result *Hover
+	err := c.conn.Call(ctx, "textDocument/hover", params, &result)
+	return result, err
+}
+
+// Definition requests the locations where the symbol at a position is defined
+func (c *Client) Definition(ctx context.Context, params TextDocumentPositionParams) ([]Location, error) {
+	var raw json.RawMessage
+	if err := c.conn.Call(ctx, "textDocument/definition", params, &raw); err != nil {
+		return nil, err
+	}
+	return parseLocations(raw)
+}
+
+// parseLocations reads a Location, a list of Locations or a list of LocationLinks
+func parseLocations(raw json.RawMessage) ([]Location, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var single Location
+	if err := json.Unmarshal(raw, &single); err == nil && single.URI != "" {
+		return []Location{single}, nil
+	}
+
+	var items []struct {
+		Location
+		TargetURI            DocumentURI `json:"targetUri"`
+		TargetSelectionRange Range       `json:"targetSelectionRange"`
+	}
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+	locations := make([]Location, 0, len(items))
+	for _, item := range items {
+		if item.TargetURI != "" {
+			locations = append(locations, Location{URI: item.TargetURI, Range: item.TargetSelectionRange})
+		} else {
+			locations = append(locations, item.Location)
+		}
+	}
+	return locations, nil
+}
+
+// Completion requests completion proposals at a position
+func (c *Client) Completion(ctx context.Context, params TextDocumentPositionParams) (*CompletionList, error) {
+	var result CompletionList
+	err := c.conn.Call(ctx, "textDocument/completion", params, &result)
+	return &result, err
+}
+
+// Rename requests the edits that rename the symbol at a position
+func (c *Client) Rename(ctx context.Context, params TextDocumentPositionParams, newName string) (*WorkspaceEdit, error) {
+	var result *WorkspaceEdit
+	err := c.conn.Call(ctx, "textDocument/rename", map[string]any{
+		"textDocument": params.TextDocument,
+		"position":     params.Position,
+		"newName":      newName,
+	}, &result)
+	return result, err
+}
+
+// Formatting requests the edits that format a whole document
+func (c *Client) Formatting(ctx context.Context, doc TextDocumentIdentifier, options FormattingOptions) ([]TextEdit, error) {
+	var result []TextEdit
+	err := c.conn.Call(ctx, "textDocument/formatting", map[string]any{
+		"textDocument": doc,
+		"options":      options,
+	}, &result)
+	return result, err
+}
+
+// CodeActions requests the fixes and refactorings available for a range
+func (c *Client) CodeActions(ctx context.Context, doc TextDocumentIdentifier, rng Range, diagnostics []Diagnostic) ([]CodeAction, error) {
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+	var result []CodeAction
+	err := c.conn.Call(ctx, "textDocument/codeAction", map[string]any{
+		"textDocument": doc,
+		"range":        rng,
+		"context":      map[string]any{"diagnostics": diagnostics},
+	}, &result)
+	return result, err
+}
+
+// ExecuteCommand asks the server to run a command, typically of a code action
+func (c *Client) ExecuteCommand(ctx context.Context, command Command) error {
+	return c.conn.Call(ctx, "workspace/executeCommand", map[string]any{
+		"command":   command.Command,
+		"arguments": command.Arguments,
+	}, nil)
+}
@@ -0,0 +1,273 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrClosed is returned for requests on a connection that has been closed
+var ErrClosed = errors.New("lsp: connection closed")
+
+// message is a JSON-RPC 2.0 request, notification or response
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *ResponseError   `json:"error,omitempty"`
+}
+
+// ResponseError is an error returned by the server for a request
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error returns the message of the error
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("lsp: %s (%d)", e.Message, e.Code)
+}
+
+// Handler handles a request or notification sent by the other side of a
+// connection. The result is sent back for requests and ignored for
+// notifications.
+type Handler func(method string, params json.RawMessage) (any, error)
+
+// Conn is a JSON-RPC 2.0 connection with the base protocol framing of the
+// Language Server Protocol: every message is preceded by a Content-Length
+// header. It is used by Client and can also serve fake servers in tests.
+type Conn struct {
+	r       *bufio.Reader
+	w       io.Writer
+	closer  io.Closer
+	handler Handler
+
+	writeMu sync.Mutex // Serializes writes of whole messages
+
+	mu      sync.Mutex
+	nextID  int
+	pending map[string]chan *message
+	err     error // Set when the connection has stopped reading
+	done    chan struct{}
+}
+
+// NewConn creates a connection reading from r and writing to w. Requests
+// and notifications from the other side are passed to handler, one at a
+// time in the order they arrive. Closing the connection closes c if it is
+// not nil.
+func NewConn(r io.Reader, w io.Writer, c io.Closer, handler Handler) *Conn {
+	conn := &Conn{
+		r:       bufio.NewReader(r),
+		w:       w,
+		closer:  c,
+		handler: handler,
+		pending: make(map[string]chan *message),
+		done:    make(chan struct{}),
+	}
+	go conn.readLoop()
+	return conn
+}
+
+// Call sends a request and stores its result in result, which may be nil
+// to discard it. It returns when the response arrives or ctx is done.
+func (c *Conn) Call(ctx context.Context, method string, params, result any) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	ch := make(chan *message, 1)
+	c.pending[string(id)] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, string(id))
+		c.mu.Unlock()
+	}()
+
+	if err := c.send(&message{ID: &id, Method: method}, params); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	case <-c.done:
+		return c.Err()
+	case <-ctx.Done():
+		_ = c.Notify("$/cancelRequest", map[string]any{"id": id})
+		return ctx.Err()
+	}
+}
+
+// Notify sends a notification
+func (c *Conn) Notify(method string, params any) error {
+	return c.send(&message{Method: method}, params)
+}
+
+// Err returns why the connection stopped reading, or nil while it is open
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Done returns a channel that is closed when the connection stops reading
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close closes the underlying stream
+func (c *Conn) Close() error {
+	if c.closer == nil {
+		return nil
+	}
+	return c.closer.Close()
+}
+
+// send writes a message with the given params
+func (c *Conn) send(msg *message, params any) error {
+	msg.JSONRPC = "2.0"
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = data
+	}
+	return c.write(msg)
+}
+
+// write frames and writes a message
+func (c *Conn) write(msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err = fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(data)); err == nil {
+		_, err = c.w.Write(data)
+	}
+	if errors.Is(err, io.ErrClosedPipe) || errors.Is(err, os.ErrClosed) {
+		return ErrClosed
+	}
+	return err
+}
+
+// readLoop reads messages until the stream ends, delivering responses to
+// their callers and passing requests and notifications to the handler
+func (c *Conn) readLoop() {
+	var err error
+	for {
+		var msg *message
+		if msg, err = c.read(); err != nil {
+			break
+		}
+
+		switch {
+		case msg.Method == "" && msg.ID != nil:
+			c.mu.Lock()
+			ch := c.pending[string(*msg.ID)]
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- msg
+			}
+		case msg.Method != "":
+			c.handle(msg)
+		}
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe) {
+		err = ErrClosed
+	}
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// handle passes a request or notification to the handler and answers
+// requests with its result
+func (c *Conn) handle(msg *message) {
+	var result any
+	var err error
+	if c.handler != nil {
+		result, err = c.handler(msg.Method, msg.Params)
+	} else if msg.ID != nil {
+		err = &ResponseError{Code: -32601, Message: "method not found: " + msg.Method}
+	}
+	if msg.ID == nil {
+		return
+	}
+
+	resp := &message{JSONRPC: "2.0", ID: msg.ID}
+	if err != nil {
+		var respErr *ResponseError
+		if !errors.As(err, &respErr) {
+			respErr = &ResponseError{Code: -32603, Message: err.Error()}
+		}
+		resp.Error = respErr
+	} else {
+		data, merr := json.Marshal(result)
+		if merr != nil {
+			resp.Error = &ResponseError{Code: -32603, Message: merr.Error()}
+		} else {
+			resp.Result = data
+		}
+	}
+	_ = c.write(resp)
+}
+
+// read reads one framed message
+func (c *Conn) read() (*message, error) {
+	length := -1
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("lsp: invalid Content-Length: %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("lsp: missing Content-Length header")
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		return nil, err
+	}
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("lsp: invalid message: %w", err)
+	}
+	return &msg, nil
+}
@@ -0,0 +1,109 @@
+package lsp
+
+import (
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/kujtimiihoxha/vimtea"
+)
+
+// Position encodings of the protocol, the unit in which characters of a
+// Position are counted
+const (
+	EncodingUTF8  = "utf-8"
+	EncodingUTF16 = "utf-16"
+)
+
+// toPosition converts a buffer position with a byte column to a protocol
+// position in the given encoding
+func toPosition(lines []string, pos vimtea.Cursor, encoding string) Position {
+	if encoding == EncodingUTF8 || pos.Row < 0 || pos.Row >= len(lines) {
+		return Position{Line: pos.Row, Character: pos.Col}
+	}
+	line := lines[pos.Row]
+	col := min(max(pos.Col, 0), len(line))
+	units := 0
+	for _, r := range line[:col] {
+		units += utf16.RuneLen(r)
+	}
+	return Position{Line: pos.Row, Character: units}
+}
+
+// fromPosition converts a protocol position in the given encoding to a
+// buffer position with a byte column
+func fromPosition(lines []string, pos Position, encoding string) vimtea.Cursor {
+	if encoding == EncodingUTF8 || pos.Line < 0 || pos.Line >= len(lines) {
+		return vimtea.Cursor{Row: pos.Line, Col: pos.Character}
+	}
+	line := lines[pos.Line]
+	units := 0
+	for i, r := range line {
+		if units >= pos.Character {
+			return vimtea.Cursor{Row: pos.Line, Col: i}
+		}
+		if r == utf8.RuneError {
+			units++
+		} else {
+			units += utf16.RuneLen(r)
+		}
+	}
+	return vimtea.Cursor{Row: pos.Line, Col: len(line)}
+}
+
+// toRange converts a buffer range with an exclusive end to a protocol range
+func toRange(lines []string, start, end vimtea.Cursor, encoding string) Range {
+	return Range{Start: toPosition(lines, start, encoding), End: toPosition(lines, end, encoding)}
+}
+
+// toTextEdits converts protocol edits to buffer edits
+func toTextEdits(lines []string, edits []TextEdit, encoding string) []vimtea.TextEdit {
+	result := make([]vimtea.TextEdit, 0, len(edits))
+	for _, edit := range edits {
+		result = append(result, vimtea.TextEdit{
+			Start:   fromPosition(lines, edit.Range.Start, encoding),
+			End:     fromPosition(lines, edit.Range.End, encoding),
+			NewText: edit.NewText,
+		})
+	}
+	return result
+}
+
+// toDiagnostics converts protocol diagnostics to editor diagnostics, whose
+// ranges include their end
+func toDiagnostics(lines []string, diagnostics []Diagnostic, encoding string) []vimtea.Diagnostic {
+	result := make([]vimtea.Diagnostic, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		start := fromPosition(lines, d.Range.Start, encoding)
+		end := fromPosition(lines, d.Range.End, encoding)
+		// Move the exclusive end back onto the last character
+		if end.Col > 0 {
+			end.Col--
+		} else if end.Row > start.Row {
+			end.Row--
+			end.Col = max(len(lineAt(lines, end.Row))-1, 0)
+		}
+		if end.Row < start.Row || (end.Row == start.Row && end.Col < start.Col) {
+			end = start
+		}
+
+		severity := vimtea.DiagnosticSeverity(d.Severity)
+		if d.Severity == 0 {
+			severity = vimtea.SeverityError
+		}
+		result = append(result, vimtea.Diagnostic{
+			Range:    vimtea.TextRange{Start: start, End: end},
+			Severity: severity,
+			Message:  d.Message,
+			Source:   d.Source,
+		})
+	}
+	return result
+}
+
+// lineAt returns a line, or "" for rows outside the document
+func lineAt(lines []string, row int) string {
+	if row < 0 || row >= len(lines) {
+		return ""
+	}
+	return lines[row]
+}
@@ -0,0 +1,61 @@
+package lsp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kujtimiihoxha/vimtea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPositionEncodings(t *testing.T) {
+	lines := []string{"a😀é b", "x"}
+
+	// 😀 is 4 bytes and 2 UTF-16 code units, é is 2 bytes and 1 code unit
+	assert.Equal(t, Position{Line: 0, Character: 4}, toPosition(lines, vimtea.Cursor{Row: 0, Col: 7}, EncodingUTF16))
+	assert.Equal(t, Position{Line: 0, Character: 7}, toPosition(lines, vimtea.Cursor{Row: 0, Col: 7}, EncodingUTF8))
+	assert.Equal(t, vimtea.Cursor{Row: 0, Col: 7}, fromPosition(lines, Position{Line: 0, Character: 4}, EncodingUTF16))
+	assert.Equal(t, vimtea.Cursor{Row: 0, Col: 9}, fromPosition(lines, Position{Line: 0, Character: 99}, EncodingUTF16), "Characters past the end should clamp to the line")
+	assert.Equal(t, vimtea.Cursor{Row: 2, Col: 0}, fromPosition(lines, Position{Line: 2, Character: 0}, EncodingUTF16), "Lines past the end are kept for the editor to clamp")
+}
+
+func TestParseLocations(t *testing.T) {
+	location := `{"uri":"file:///a.go","range":{"start":{"line":1,"character":2},"end":{"line":1,"character":4}}}`
+	link := `{"targetUri":"file:///b.go","targetRange":{"start":{"line":0,"character":0},"end":{"line":5,"character":0}},` +
+		`"targetSelectionRange":{"start":{"line":3,"character":5},"end":{"line":3,"character":9}}}`
+
+	for name, tc := range map[string]struct {
+		raw  string
+		want []Location
+	}{
+		"null":     {raw: "null"},
+		"location": {raw: location, want: []Location{{URI: "file:///a.go", Range: rangeOf(1, 2, 1, 4)}}},
+		"list":     {raw: "[" + location + "]", want: []Location{{URI: "file:///a.go", Range: rangeOf(1, 2, 1, 4)}}},
+		"links":    {raw: "[" + link + "]", want: []Location{{URI: "file:///b.go", Range: rangeOf(3, 5, 3, 9)}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			locations, err := parseLocations(json.RawMessage(tc.raw))
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, locations)
+		})
+	}
+}
+
+func TestHoverContents(t *testing.T) {
+	for name, tc := range map[string]struct {
+		raw  string
+		want string
+	}{
+		"markup":        {raw: `{"kind":"markdown","value":"**bold**"}`, want: "**bold**"},
+		"string":        {raw: `"plain"`, want: "plain"},
+		"marked string": {raw: `{"language":"go","value":"func f()"}`, want: "func f()"},
+		"list":          {raw: `["first",{"language":"go","value":"second"}]`, want: "first\n\nsecond"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var contents HoverContents
+			require.NoError(t, json.Unmarshal([]byte(tc.raw), &contents))
+			assert.Equal(t, tc.want, contents.Value)
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package lsp
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// The types below are the subset of the Language Server Protocol 3.17
+// that the client uses. Field names follow the specification.
+
+// DocumentURI is the URI of a document, e.g. "file:///home/me/main.go"
+type DocumentURI string
+
+// Position is a zero-based line and character offset. Characters are
+// counted in the position encoding negotiated during initialization.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a range in a document, the end exclusive
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range in a document
+type Location struct {
+	URI   DocumentURI `json:"uri"`
+	Range Range       `json:"range"`
+}
+
+// TextEdit replaces a range of a document
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// TextDocumentIdentifier identifies a document
+type TextDocumentIdentifier struct {
+	URI DocumentURI `json:"uri"`
+}
+
+// VersionedTextDocumentIdentifier identifies a version of a document
+type VersionedTextDocumentIdentifier struct {
+	URI     DocumentURI `json:"uri"`
+	Version int         `json:"version"`
+}
+
+// TextDocumentItem is a document opened with didOpen
+type TextDocumentItem struct {
+	URI        DocumentURI `json:"uri"`
+	LanguageID string      `json:"languageId"`
+	Version    int         `json:"version"`
+	Text       string      `json:"text"`
+}
+
+// TextDocumentContentChangeEvent is a change sent with didChange. Without a
+// range the text replaces the whole document.
+type TextDocumentContentChangeEvent struct {
+	Range *Range `json:"range,omitempty"`
+	Text  string `json:"text"`
+}
+
+// TextDocumentPositionParams identifies a position in a document
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+// DiagnosticSeverity is the severity of a diagnostic
+type DiagnosticSeverity int
+
+// Diagnostic severities
+const (
+	SeverityError       DiagnosticSeverity = 1
+	SeverityWarning     DiagnosticSeverity = 2
+	SeverityInformation DiagnosticSeverity = 3
+	SeverityHint        DiagnosticSeverity = 4
+)
+
+// Diagnostic is a problem reported by the server
+type Diagnostic struct {
+	Range    Range              `json:"range"`
+	Severity DiagnosticSeverity `json:"severity,omitempty"`
+	Code     any                `json:"code,omitempty"`
+	Source   string             `json:"source,omitempty"`
+	Message  string             `json:"message"`
+}
+
+// PublishDiagnosticsParams are sent with textDocument/publishDiagnostics
+type PublishDiagnosticsParams struct {
+	URI         DocumentURI  `json:"uri"`
+	Version     *int         `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// MarkupContent is text in plain text or markdown format
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// Hover is the result of textDocument/hover
+type Hover struct {
+	Contents HoverContents `json:"contents"`
+	Range    *Range        `json:"range,omitempty"`
+}
+
+// HoverContents holds hover text, which servers send as MarkupContent, a
+// string, a MarkedString or a list of strings and MarkedStrings
+type HoverContents struct {
+	Value string
+}
+
+// UnmarshalJSON reads any of the forms of hover contents
+func (h *HoverContents) UnmarshalJSON(data []byte) error {
+	var markup struct {
+		Kind     string `json:"kind"`
+		Language string `json:"language"`
+		Value    string `json:"value"`
+	}
+	var list []json.RawMessage
+	var text string
+
+	switch {
+	case json.Unmarshal(data, &text) == nil:
+		h.Value = text
+	case json.Unmarshal(data, &list) == nil:
+		parts := make([]string, 0, len(list))
+		for _, item := range list {
+			var part HoverContents
+			if err := part.UnmarshalJSON(item); err != nil {
+				return err
+			}
+			parts = append(parts, part.Value)
+		}
+		h.Value = strings.Join(parts, "\n\n")
+	default:
+		if err := json.Unmarshal(data, &markup); err != nil {
+			return err
+		}
+		h.Value = markup.Value
+	}
+	return nil
+}
+
+// MarshalJSON writes the contents as MarkupContent
+func (h HoverContents) MarshalJSON() ([]byte, error) {
+	return json.Marshal(MarkupContent{Kind: "markdown", Value: h.Value})
+}
+
+// CompletionItemKind is the kind of a completion item, e.g. function
+type CompletionItemKind int
+
+// completionItemKinds are the names of the completion item kinds
+var completionItemKinds = []string{
+	"", "text", "method", "function", "constructor", "field", "variable",
+	"class", "interface", "module", "property", "unit", "value", "enum",
+	"keyword", "snippet", "color", "file", "reference", "folder",
+	"enum member", "constant", "struct", "event", "operator", "type parameter",
+}
+
+// String returns the name of the kind, or "" for unknown kinds
+func (k CompletionItemKind) String() string {
+	if k < 0 || int(k) >= len(completionItemKinds) {
+		return ""
+	}
+	return completionItemKinds[k]
+}
+
+// CompletionItem is a proposal of textDocument/completion
+type CompletionItem struct {
+	Label         string             `json:"label"`
+	Kind          CompletionItemKind `json:"kind,omitempty"`
+	Detail        string             `json:"detail,omitempty"`
+	Documentation *HoverContents     `json:"documentation,omitempty"`
+	SortText      string             `json:"sortText,omitempty"`
+	FilterText    string             `json:"filterText,omitempty"`
+	InsertText    string             `json:"insertText,omitempty"`
+	// InsertTextFormat is 2 when the text is a snippet
+	InsertTextFormat int       `json:"insertTextFormat,omitempty"`
+	TextEdit         *TextEdit `json:"textEdit,omitempty"`
+}
+
+// CompletionList is the result of textDocument/completion, which servers
+// may also send as a plain list of items
+type CompletionList struct {
+	IsIncomplete bool             `json:"isIncomplete"`
+	Items        []CompletionItem `json:"items"`
+}
+
+// UnmarshalJSON reads a completion list or a list of items
+func (l *CompletionList) UnmarshalJSON(data []byte) error {
+	var items []CompletionItem
+	if json.Unmarshal(data, &items) == nil {
+		*l = CompletionList{Items: items}
+		return nil
+	}
+	type list CompletionList
+	return json.Unmarshal(data, (*list)(l))
+}
+
+// WorkspaceEdit describes changes to several documents
+type WorkspaceEdit struct {
+	Changes         map[DocumentURI][]TextEdit `json:"changes,omitempty"`
+	DocumentChanges []TextDocumentEdit         `json:"documentChanges,omitempty"`
+}
+
+// TextDocumentEdit are edits to a version of a document
+type TextDocumentEdit struct {
+	TextDocument VersionedTextDocumentIdentifier `json:"textDocument"`
+	Edits        []TextEdit                      `json:"edits"`
+}
+
+// Edits returns the edits of a document from both forms of the workspace edit
+func (e *WorkspaceEdit) Edits(uri DocumentURI) []TextEdit {
+	if e == nil {
+		return nil
+	}
+	edits := append([]TextEdit(nil), e.Changes[uri]...)
+	for _, change := range e.DocumentChanges {
+		if change.TextDocument.URI == uri {
+			edits = append(edits, change.Edits...)
+		}
+	}
+	return edits
+}
+
+// Command is a command the server can execute with workspace/executeCommand
+type Command struct {
+	Title     string `json:"title"`
+	Command   string `json:"command"`
+	Arguments []any  `json:"arguments,omitempty"`
+}
+
+// CodeAction is a fix or refactoring proposed for a range
+type CodeAction struct {
+	Title       string         `json:"title"`
+	Kind        string         `json:"kind,omitempty"`
+	Diagnostics []Diagnostic   `json:"diagnostics,omitempty"`
+	IsPreferred bool           `json:"isPreferred,omitempty"`
+	Edit        *WorkspaceEdit `json:"edit,omitempty"`
+	Command     *Command       `json:"command,omitempty"`
+}
+
+// UnmarshalJSON reads a code action or a bare command
+func (a *CodeAction) UnmarshalJSON(data []byte) error {
+	// A Command has a string "command" field instead of an object
+	var probe struct {
+		Command json.RawMessage `json:"command"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return err
+	}
+	if len(probe.Command) > 0 && probe.Command[0] == '"' {
+		var command Command
+		if err := json.Unmarshal(data, &command); err != nil {
+			return err
+		}
+		*a = CodeAction{Title: command.Title, Command: &command}
+		return nil
+	}
+
+	type action CodeAction
+	return json.Unmarshal(data, (*action)(a))
+}
+
+// FormattingOptions describe the indentation used for formatting
+type FormattingOptions struct {
+	TabSize      int  `json:"tabSize"`
+	InsertSpaces bool `json:"insertSpaces"`
+}
+
+// ServerCapabilities is the part of the server capabilities the client uses
+type ServerCapabilities struct {
+	PositionEncoding string          `json:"positionEncoding,omitempty"`
+	TextDocumentSync json.RawMessage `json:"textDocumentSync,omitempty"`
+}
+
+// syncKind returns the document synchronization kind of the server:
+// 0 none, 1 full documents, 2 incremental changes
+func (c ServerCapabilities) syncKind() int {
+	var kind int
+	if json.Unmarshal(c.TextDocumentSync, &kind) == nil {
+		return kind
+	}
+	var options struct {
+		Change int `json:"change"`
+	}
+	if json.Unmarshal(c.TextDocumentSync, &options) == nil {
+		return options.Change
+	}
+	return 0
+}
+
+// InitializeResult is the result of initialize
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+}
+
+// ShowMessageParams are sent with window/showMessage
+type ShowMessageParams struct {
+	Type    int    `json:"type"`
+	Message string `json:"message"`
+}
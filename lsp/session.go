@@ -0,0 +1,498 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/kujtimiihoxha/vimtea"
+)
+
+// requestTimeout bounds how long a request waits for the server
+const requestTimeout = 10 * time.Second
+
+// HoverMsg is sent with the result of a hover request. Hover is nil when
+// the server has no information about the symbol.
+type HoverMsg struct {
+	URI   DocumentURI
+	Hover *Hover
+}
+
+// DefinitionMsg is sent with the locations of a definition. Locations in
+// other documents are left to the application.
+type DefinitionMsg struct {
+	URI       DocumentURI
+	Locations []Location
+}
+
+// RenameMsg is sent with the edits of a rename
+type RenameMsg struct {
+	URI  DocumentURI
+	Edit *WorkspaceEdit
+}
+
+// FormattingMsg is sent with the edits that format a document
+type FormattingMsg struct {
+	URI   DocumentURI
+	Edits []TextEdit
+}
+
+// CodeActionsMsg is sent with the code actions available at the cursor.
+// Apply is the 1-based number of the action to apply, or 0 to list them.
+type CodeActionsMsg struct {
+	URI     DocumentURI
+	Actions []CodeAction
+	Apply   int
+}
+
+// WorkspaceEditMsg is sent when the server asks to apply an edit, for
+// example while executing the command of a code action
+type WorkspaceEditMsg struct {
+	Edit *WorkspaceEdit
+}
+
+// ErrorMsg is sent when a request fails
+type ErrorMsg struct {
+	URI    DocumentURI
+	Method string // Method of the failed request
+	Err    error
+}
+
+// Error returns the message of the error
+func (e ErrorMsg) Error() string {
+	return fmt.Sprintf("%s: %v", e.Method, e.Err)
+}
+
+// Session keeps a document of an editor open on a language server
+type Session struct {
+	editor vimtea.Editor
+	client *Client
+	uri    DocumentURI
+	buffer int // Number of the editor buffer holding the document
+
+	version int      // Version of the document last sent to the server
+	lines   []string // Document as the server knows it
+
+	diagnostics []Diagnostic // Diagnostics received while the buffer was not current
+	stale       bool         // Whether diagnostics wait for the buffer to be shown
+
+	listen bool // Whether this session receives the client's notifications
+}
+
+// Attach opens the editor's buffer on the server as the document at path,
+// keeps it synchronized and registers the language server bindings and
+// commands. The session follows the buffer that is current when it is
+// attached: its events, bindings, commands and messages do nothing in
+// other buffers, and diagnostics wait until the buffer is shown again.
+// The client must be initialized.
+func Attach(editor vimtea.Editor, client *Client, path, languageID string) *Session {
+	s := &Session{
+		editor:  editor,
+		client:  client,
+		uri:     FileURI(path),
+		buffer:  editor.CurrentBuffer().Number,
+		version: 1,
+		lines:   slices.Clone(editor.GetBuffer().Lines()),
+		listen:  client.claimListener(),
+	}
+	_ = client.DidOpen(TextDocumentItem{
+		URI:        s.uri,
+		LanguageID: languageID,
+		Version:    s.version,
+		Text:       editor.GetBuffer().Text(),
+	})
+
+	editor.AddAutocmd(vimtea.EventTextChanged, "", func(b vimtea.Buffer, args vimtea.AutocmdArgs) tea.Cmd {
+		if !s.current() {
+			return nil
+		}
+		return s.didChange(b, args.Change)
+	})
+	editor.AddAutocmd(vimtea.EventBufWritePost, "", func(vimtea.Buffer, vimtea.AutocmdArgs) tea.Cmd {
+		if !s.current() {
+			return nil
+		}
+		return s.notifyErr("textDocument/didSave", client.DidSave(TextDocumentIdentifier{URI: s.uri}))
+	})
+	enter := func(vimtea.Buffer, vimtea.AutocmdArgs) tea.Cmd {
+		if s.stale && s.current() {
+			s.setDiagnostics(s.diagnostics)
+		}
+		return nil
+	}
+	editor.AddAutocmd(vimtea.EventBufEnter, "", enter)
+	editor.AddAutocmd(vimtea.EventWinEnter, "", enter)
+
+	editor.AddBinding(vimtea.KeyBinding{
+		Key:         "K",
+		Mode:        vimtea.ModeNormal,
+		Description: "Show language server hover information",
+		Handler:     s.inBuffer(s.Hover),
+	})
+	editor.AddBinding(vimtea.KeyBinding{
+		Key:         "gd",
+		Mode:        vimtea.ModeNormal,
+		Description: "Go to definition",
+		Handler:     s.inBuffer(s.Definition),
+	})
+
+	editor.AddCompletionSource(s)
+
+	editor.AddCommand("LspHover", func(vimtea.Buffer, []string) tea.Cmd { return s.inBuffer(s.Hover)(nil) })
+	editor.AddCommand("LspDefinition", func(vimtea.Buffer, []string) tea.Cmd { return s.inBuffer(s.Definition)(nil) })
+	editor.AddCommand("LspRename", func(_ vimtea.Buffer, args []string) tea.Cmd {
+		if !s.current() {
+			return nil
+		}
+		if len(args) != 1 {
+			return editor.SetStatusMessage("E471: Argument required")
+		}
+		return s.Rename(args[0])
+	})
+	editor.AddCommand("LspDocumentFormat", func(vimtea.Buffer, []string) tea.Cmd { return s.inBuffer(s.Format)(nil) })
+	editor.AddCommand("LspCodeAction", func(_ vimtea.Buffer, args []string) tea.Cmd {
+		if !s.current() {
+			return nil
+		}
+		if len(args) == 0 {
+			return s.CodeActions(0)
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return editor.SetStatusMessage(fmt.Sprintf("E474: Invalid argument: %s", args[0]))
+		}
+		return s.CodeActions(n)
+	})
+	return s
+}
+
+// URI returns the URI of the document
+func (s *Session) URI() DocumentURI {
+	return s.uri
+}
+
+// current reports whether the session's buffer is the current buffer of
+// the editor
+func (s *Session) current() bool {
+	return s.editor.CurrentBuffer().Number == s.buffer
+}
+
+// inBuffer returns a binding handler that runs fn only in the session's
+// buffer
+func (s *Session) inBuffer(fn func() tea.Cmd) func(vimtea.Buffer) tea.Cmd {
+	return func(vimtea.Buffer) tea.Cmd {
+		if !s.current() {
+			return nil
+		}
+		return fn()
+	}
+}
+
+// Init returns the command that listens for server notifications. Only the
+// first session of a client listens, so attach further sessions freely.
+func (s *Session) Init() tea.Cmd {
+	if !s.listen {
+		return nil
+	}
+	return s.client.Listen()
+}
+
+// Close closes the document on the server
+func (s *Session) Close() error {
+	return s.client.DidClose(TextDocumentIdentifier{URI: s.uri})
+}
+
+// didChange sends a buffer modification to the server and updates the
+// document as the server knows it
+func (s *Session) didChange(b vimtea.Buffer, change vimtea.TextChange) tea.Cmd {
+	event := TextDocumentContentChangeEvent{Text: b.Text()}
+	if s.client.incremental() {
+		rng := toRange(s.lines, change.Start, change.End, s.client.Encoding())
+		event = TextDocumentContentChangeEvent{Range: &rng, Text: change.Inserted}
+	}
+	s.version++
+	s.lines = slices.Clone(b.Lines())
+
+	doc := VersionedTextDocumentIdentifier{URI: s.uri, Version: s.version}
+	return s.notifyErr("textDocument/didChange", s.client.DidChange(doc, []TextDocumentContentChangeEvent{event}))
+}
+
+// notifyErr turns the error of a notification into an ErrorMsg command
+func (s *Session) notifyErr(method string, err error) tea.Cmd {
+	if err == nil {
+		return nil
+	}
+	return func() tea.Msg { return ErrorMsg{URI: s.uri, Method: method, Err: err} }
+}
+
+// request runs a request in a command and delivers its message, or an
+// ErrorMsg if it fails
+func (s *Session) request(method string, fn func(ctx context.Context) (tea.Msg, error)) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		msg, err := fn(ctx)
+		if err != nil {
+			return ErrorMsg{URI: s.uri, Method: method, Err: err}
+		}
+		return msg
+	}
+}
+
+// cursorParams returns the document and cursor position for a request
+func (s *Session) cursorParams() TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: s.uri},
+		Position:     toPosition(s.editor.GetBuffer().Lines(), s.editor.GetCursor(), s.client.Encoding()),
+	}
+}
+
+// Hover requests information about the symbol under the cursor
+func (s *Session) Hover() tea.Cmd {
+	params := s.cursorParams()
+	return s.request("textDocument/hover", func(ctx context.Context) (tea.Msg, error) {
+		hover, err := s.client.Hover(ctx, params)
+		return HoverMsg{URI: s.uri, Hover: hover}, err
+	})
+}
+
+// Definition requests the definition of the symbol under the cursor
+func (s *Session) Definition() tea.Cmd {
+	params := s.cursorParams()
+	return s.request("textDocument/definition", func(ctx context.Context) (tea.Msg, error) {
+		locations, err := s.client.Definition(ctx, params)
+		return DefinitionMsg{URI: s.uri, Locations: locations}, err
+	})
+}
+
+// Complete asks the server for completion proposals. Attach adds the
+// session as a source of the editor's completion menu.
+func (s *Session) Complete(req vimtea.CompletionRequest) tea.Cmd {
+	if !s.current() {
+		return nil
+	}
+	params := TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: s.uri},
+		Position:     toPosition(req.Buffer.Lines(), req.Cursor, s.client.Encoding()),
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		list, err := s.client.Completion(ctx, params)
+		if err != nil {
+			// The menu still needs an answer to stop waiting
+			return tea.BatchMsg{
+				func() tea.Msg { return req.Result(nil) },
+				func() tea.Msg { return ErrorMsg{URI: s.uri, Method: "textDocument/completion", Err: err} },
+			}
+		}
+		return req.Result(completionItems(list))
+	}
+}
+
+// completionItems converts protocol completion items to menu items. Text
+// edits are inserted over the word before the cursor rather than their range.
+func completionItems(list *CompletionList) []vimtea.CompletionItem {
+	items := make([]vimtea.CompletionItem, 0, len(list.Items))
+	for _, item := range list.Items {
+		text := item.InsertText
+		if item.TextEdit != nil {
+			text = item.TextEdit.NewText
+		}
+		var documentation string
+		if item.Documentation != nil {
+			documentation = item.Documentation.Value
+		}
+		items = append(items, vimtea.CompletionItem{
+			Label:         item.Label,
+			Kind:          item.Kind.String(),
+			Detail:        item.Detail,
+			Documentation: documentation,
+			InsertText:    text,
+			Snippet:       item.InsertTextFormat == 2,
+		})
+	}
+	return items
+}
+
+// Rename renames the symbol under the cursor
+func (s *Session) Rename(newName string) tea.Cmd {
+	params := s.cursorParams()
+	return s.request("textDocument/rename", func(ctx context.Context) (tea.Msg, error) {
+		edit, err := s.client.Rename(ctx, params, newName)
+		return RenameMsg{URI: s.uri, Edit: edit}, err
+	})
+}
+
+// Format formats the document with the indentation options of the buffer
+func (s *Session) Format() tea.Cmd {
+	tabSize, _ := s.editor.GetOption("tabstop")
+	expandTab, _ := s.editor.GetOption("expandtab")
+	options := FormattingOptions{}
+	options.TabSize, _ = tabSize.(int)
+	options.InsertSpaces, _ = expandTab.(bool)
+
+	doc := TextDocumentIdentifier{URI: s.uri}
+	return s.request("textDocument/formatting", func(ctx context.Context) (tea.Msg, error) {
+		edits, err := s.client.Formatting(ctx, doc, options)
+		return FormattingMsg{URI: s.uri, Edits: edits}, err
+	})
+}
+
+// CodeActions requests the code actions for the cursor line and its
+// diagnostics. With apply > 0 the action with that number is applied,
+// otherwise the actions are listed.
+func (s *Session) CodeActions(apply int) tea.Cmd {
+	lines := s.editor.GetBuffer().Lines()
+	row := s.editor.GetCursor().Row
+	encoding := s.client.Encoding()
+	rng := toRange(lines, vimtea.Cursor{Row: row}, vimtea.Cursor{Row: row, Col: len(lineAt(lines, row))}, encoding)
+
+	var diagnostics []Diagnostic
+	for _, d := range s.editor.GetDiagnostics() {
+		if d.Range.Start.Row > row || d.Range.End.Row < row {
+			continue
+		}
+		end := d.Range.End
+		end.Col++
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    toRange(lines, d.Range.Start, end, encoding),
+			Severity: DiagnosticSeverity(d.Severity),
+			Source:   d.Source,
+			Message:  d.Message,
+		})
+	}
+
+	doc := TextDocumentIdentifier{URI: s.uri}
+	return s.request("textDocument/codeAction", func(ctx context.Context) (tea.Msg, error) {
+		actions, err := s.client.CodeActions(ctx, doc, rng, diagnostics)
+		return CodeActionsMsg{URI: s.uri, Actions: actions, Apply: apply}, err
+	})
+}
+
+// applyAction applies the edit of a code action and executes its command
+func (s *Session) applyAction(action CodeAction) tea.Cmd {
+	s.applyWorkspaceEdit(action.Edit)
+	if action.Command == nil {
+		return nil
+	}
+	command := *action.Command
+	return s.request("workspace/executeCommand", func(ctx context.Context) (tea.Msg, error) {
+		return nil, s.client.ExecuteCommand(ctx, command)
+	})
+}
+
+// applyWorkspaceEdit applies the edits of a workspace edit to this document
+func (s *Session) applyWorkspaceEdit(edit *WorkspaceEdit) {
+	s.applyEdits(edit.Edits(s.uri))
+}
+
+// applyEdits applies protocol edits to the buffer
+func (s *Session) applyEdits(edits []TextEdit) {
+	lines := s.editor.GetBuffer().Lines()
+	s.editor.ApplyEdits(toTextEdits(lines, edits, s.client.Encoding()))
+}
+
+// setDiagnostics shows the diagnostics of the document in its buffer, or
+// keeps them until the buffer is shown when another buffer is current
+func (s *Session) setDiagnostics(diagnostics []Diagnostic) {
+	if !s.current() {
+		s.diagnostics, s.stale = diagnostics, true
+		return
+	}
+	s.diagnostics, s.stale = nil, false
+	s.editor.SetDiagnostics(toDiagnostics(s.editor.GetBuffer().Lines(), diagnostics, s.client.Encoding()))
+}
+
+// Update applies language server messages of this session's document to
+// the editor: diagnostics, hover popups, jumps to definitions, completions
+// and edits. Messages that change or show the document are ignored while
+// another buffer is current. Pass every message to it after the editor's
+// own Update.
+func (s *Session) Update(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+	case DiagnosticsMsg:
+		if msg.URI == s.uri {
+			s.setDiagnostics(msg.Diagnostics)
+		}
+		return s.relisten()
+
+	case ShowMessageMsg:
+		return tea.Batch(s.editor.SetStatusMessage(msg.Message), s.relisten())
+
+	case WorkspaceEditMsg:
+		if s.current() {
+			s.applyWorkspaceEdit(msg.Edit)
+		}
+		return s.relisten()
+
+	case ErrorMsg:
+		if msg.URI == s.uri {
+			return s.editor.SetStatusMessage(msg.Error())
+		}
+
+	case HoverMsg:
+		if msg.URI != s.uri || !s.current() {
+			return nil
+		}
+		if msg.Hover == nil || strings.TrimSpace(msg.Hover.Contents.Value) == "" {
+			return s.editor.SetStatusMessage("No information available")
+		}
+		s.editor.ShowPopup(strings.Split(strings.TrimSpace(msg.Hover.Contents.Value), "\n"))
+
+	case DefinitionMsg:
+		if msg.URI != s.uri || !s.current() {
+			return nil
+		}
+		if len(msg.Locations) == 0 {
+			return s.editor.SetStatusMessage("No definition found")
+		}
+		location := msg.Locations[0]
+		if location.URI != s.uri {
+			return s.editor.SetStatusMessage(fmt.Sprintf("Definition in %s:%d", location.URI, location.Range.Start.Line+1))
+		}
+		s.editor.SetCursor(fromPosition(s.editor.GetBuffer().Lines(), location.Range.Start, s.client.Encoding()))
+
+	case RenameMsg:
+		if msg.URI == s.uri && s.current() {
+			s.applyWorkspaceEdit(msg.Edit)
+		}
+
+	case FormattingMsg:
+		if msg.URI == s.uri && s.current() {
+			s.applyEdits(msg.Edits)
+		}
+
+	case CodeActionsMsg:
+		if msg.URI != s.uri || !s.current() {
+			return nil
+		}
+		if len(msg.Actions) == 0 {
+			return s.editor.SetStatusMessage("No code actions available")
+		}
+		if msg.Apply > 0 {
+			if msg.Apply > len(msg.Actions) {
+				return s.editor.SetStatusMessage(fmt.Sprintf("E474: Invalid argument: %d", msg.Apply))
+			}
+			return s.applyAction(msg.Actions[msg.Apply-1])
+		}
+		lines := make([]string, 0, len(msg.Actions))
+		for i, action := range msg.Actions {
+			lines = append(lines, fmt.Sprintf("%d. %s", i+1, action.Title))
+		}
+		s.editor.ShowPopup(lines)
+	}
+	return nil
+}
+
+// relisten keeps listening for notifications after one was received
+func (s *Session) relisten() tea.Cmd {
+	if !s.listen {
+		return nil
+	}
+	return s.client.Listen()
+}
@@ -0,0 +1,493 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/kujtimiihoxha/vimtea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// notification is a document notification received by the fake server
+type notification struct {
+	method string
+	params json.RawMessage
+}
+
+// fakeServer is an in-process language server connected to a client over
+// pipes. It answers requests from results and records document notifications.
+type fakeServer struct {
+	conn          *Conn
+	capabilities  map[string]any
+	results       map[string]any
+	requests      chan notification // Requests with their params, by method
+	notifications chan notification
+}
+
+func newFakeServer(t *testing.T, capabilities map[string]any) (*fakeServer, *Client) {
+	t.Helper()
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+
+	server := &fakeServer{
+		capabilities:  capabilities,
+		results:       map[string]any{},
+		requests:      make(chan notification, 16),
+		notifications: make(chan notification, 64),
+	}
+	server.conn = NewConn(serverR, serverW, serverW, server.handle)
+	client := NewClient(clientR, clientW)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, client.Initialize(ctx, FileURI(".")))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = client.Close(ctx)
+		_ = server.conn.Close()
+	})
+	return server, client
+}
+
+func (s *fakeServer) handle(method string, params json.RawMessage) (any, error) {
+	switch {
+	case method == "initialize":
+		return map[string]any{"capabilities": s.capabilities}, nil
+	case strings.HasPrefix(method, "textDocument/did"):
+		s.notifications <- notification{method, params}
+		return nil, nil
+	case method == "workspace/executeCommand":
+		// Ask the client to apply an edit while the command runs
+		go func() {
+			_ = s.conn.Call(context.Background(), "workspace/applyEdit", map[string]any{
+				"edit": s.results["workspace/applyEdit"],
+			}, nil)
+		}()
+	}
+	if !strings.HasPrefix(method, "textDocument/") && !strings.HasPrefix(method, "workspace/") {
+		return nil, nil
+	}
+	select {
+	case s.requests <- notification{method, params}:
+	default:
+	}
+	return s.results[method], nil
+}
+
+// publish sends a notification to the client
+func (s *fakeServer) publish(t *testing.T, method string, params any) {
+	t.Helper()
+	require.NoError(t, s.conn.Notify(method, params))
+}
+
+// next returns the next document notification
+func (s *fakeServer) next(t *testing.T) notification {
+	t.Helper()
+	select {
+	case n := <-s.notifications:
+		return n
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a notification")
+		return notification{}
+	}
+}
+
+// didChange decodes the next notification as didChange params
+func (s *fakeServer) didChange(t *testing.T) (int, []TextDocumentContentChangeEvent) {
+	t.Helper()
+	n := s.next(t)
+	require.Equal(t, "textDocument/didChange", n.method)
+	var params struct {
+		TextDocument   VersionedTextDocumentIdentifier  `json:"textDocument"`
+		ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+	}
+	require.NoError(t, json.Unmarshal(n.params, &params))
+	return params.TextDocument.Version, params.ContentChanges
+}
+
+func rangeOf(startLine, startChar, endLine, endChar int) Range {
+	return Range{Start: Position{startLine, startChar}, End: Position{endLine, endChar}}
+}
+
+func keys(s string) []tea.KeyMsg {
+	var msgs []tea.KeyMsg
+	for _, r := range s {
+		msgs = append(msgs, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return msgs
+}
+
+// press sends keys to the editor and returns the messages of the commands
+// they produced
+func press(editor vimtea.Editor, msgs ...tea.KeyMsg) []tea.Msg {
+	var result []tea.Msg
+	for _, msg := range msgs {
+		_, cmd := editor.Update(msg)
+		result = append(result, collectMsgs(cmd)...)
+	}
+	return result
+}
+
+// command runs an ex command and returns the messages it produced
+func command(editor vimtea.Editor, line string) []tea.Msg {
+	var result []tea.Msg
+	msgs := press(editor, append(keys(":"+line), tea.KeyMsg{Type: tea.KeyEnter})...)
+	for _, msg := range msgs {
+		if _, ok := msg.(vimtea.CommandMsg); ok {
+			_, cmd := editor.Update(msg)
+			result = append(result, collectMsgs(cmd)...)
+		}
+	}
+	return result
+}
+
+func collectMsgs(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	msg := cmd()
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		var msgs []tea.Msg
+		for _, c := range batch {
+			msgs = append(msgs, collectMsgs(c)...)
+		}
+		return msgs
+	}
+	if msg == nil {
+		return nil
+	}
+	return []tea.Msg{msg}
+}
+
+// find returns the first message of type T
+func find[T any](t *testing.T, msgs []tea.Msg) T {
+	t.Helper()
+	for _, msg := range msgs {
+		if m, ok := msg.(T); ok {
+			return m
+		}
+	}
+	var zero T
+	t.Fatalf("no %T in %v", zero, msgs)
+	return zero
+}
+
+// deliver passes a message to the editor and session like an application
+// would and returns the messages the editor produced
+func deliver(editor vimtea.Editor, session *Session, msg tea.Msg) []tea.Msg {
+	_, cmd := editor.Update(msg)
+	msgs := collectMsgs(cmd)
+	if cmd := session.Update(msg); cmd != nil {
+		if _, listen := msg.(DiagnosticsMsg); !listen {
+			msgs = append(msgs, collectMsgs(cmd)...)
+		}
+	}
+	return msgs
+}
+
+// statusLine delivers messages to the editor and returns its last row
+func statusLine(editor vimtea.Editor, msgs []tea.Msg) string {
+	for _, msg := range msgs {
+		editor.Update(msg)
+	}
+	rows := strings.Split(editor.View(), "\n")
+	return rows[len(rows)-1]
+}
+
+func TestSessionSynchronizesDocument(t *testing.T) {
+	server, client := newFakeServer(t, map[string]any{"textDocumentSync": 2})
+	editor := vimtea.NewEditor(vimtea.WithContent("héllo\nworld"))
+	Attach(editor, client, "doc.txt", "plaintext")
+
+	open := server.next(t)
+	require.Equal(t, "textDocument/didOpen", open.method)
+	var params struct {
+		TextDocument TextDocumentItem `json:"textDocument"`
+	}
+	require.NoError(t, json.Unmarshal(open.params, &params))
+	assert.Equal(t, TextDocumentItem{URI: FileURI("doc.txt"), LanguageID: "plaintext", Version: 1, Text: "héllo\nworld"}, params.TextDocument)
+
+	press(editor, keys("x")...)
+	version, changes := server.didChange(t)
+	assert.Equal(t, 2, version)
+	require.Len(t, changes, 1)
+	assert.Equal(t, rangeOf(0, 0, 0, 1), *changes[0].Range, "Deleting a character should send its range")
+	assert.Equal(t, "", changes[0].Text)
+
+	press(editor, keys("A!")...)
+	version, changes = server.didChange(t)
+	assert.Equal(t, 3, version)
+	assert.Equal(t, rangeOf(0, 4, 0, 4), *changes[0].Range, "Columns should be counted in UTF-16 code units")
+	assert.Equal(t, "!", changes[0].Text)
+
+	press(editor, tea.KeyMsg{Type: tea.KeyEsc})
+	press(editor, keys("jdd")...)
+	_, changes = server.didChange(t)
+	assert.Equal(t, rangeOf(0, 5, 1, 5), *changes[0].Range, "Deleting the last line should remove the line break before it")
+	assert.Equal(t, "", changes[0].Text)
+}
+
+func TestSessionFullSync(t *testing.T) {
+	server, client := newFakeServer(t, map[string]any{"textDocumentSync": map[string]any{"openClose": true, "change": 1}})
+	editor := vimtea.NewEditor(vimtea.WithContent("one\ntwo"))
+	Attach(editor, client, "doc.txt", "plaintext")
+	server.next(t)
+
+	press(editor, keys("x")...)
+	_, changes := server.didChange(t)
+	require.Len(t, changes, 1)
+	assert.Nil(t, changes[0].Range, "Servers without incremental sync should get the whole document")
+	assert.Equal(t, "ne\ntwo", changes[0].Text)
+}
+
+func TestSessionOtherBuffers(t *testing.T) {
+	server, client := newFakeServer(t, map[string]any{"textDocumentSync": 2})
+	fsys := vimtea.NewMemFileSystem(map[string]string{"a.go": "package a", "b.txt": "notes"})
+	editor := vimtea.NewEditor(vimtea.WithFile("a.go"), vimtea.WithFileSystem(fsys))
+	Attach(editor, client, "a.go", "go")
+	server.next(t)
+
+	_, err := editor.OpenBuffer("b.txt")
+	require.NoError(t, err)
+	press(editor, keys("x")...)
+	command(editor, "w")
+	press(editor, keys("K")...)
+	command(editor, "LspHover")
+	assert.Empty(t, server.requests, "Bindings and commands should do nothing in other buffers")
+
+	require.NoError(t, editor.SwitchBuffer(1))
+	press(editor, keys("x")...)
+	version, changes := server.didChange(t)
+	assert.Equal(t, 2, version, "Changes and writes of other buffers should not be sent")
+	require.Len(t, changes, 1)
+	assert.Equal(t, rangeOf(0, 0, 0, 1), *changes[0].Range)
+}
+
+func TestSessionMessagesInOtherBuffers(t *testing.T) {
+	_, client := newFakeServer(t, map[string]any{})
+	fsys := vimtea.NewMemFileSystem(map[string]string{"a.go": "package a", "b.txt": "notes"})
+	editor := vimtea.NewEditor(vimtea.WithFile("a.go"), vimtea.WithFileSystem(fsys))
+	editor.SetSize(40, 10)
+	session := Attach(editor, client, "a.go", "go")
+
+	_, err := editor.OpenBuffer("b.txt")
+	require.NoError(t, err)
+	edit := []TextEdit{{Range: rangeOf(0, 0, 0, 0), NewText: "XX"}}
+	deliver(editor, session, DiagnosticsMsg{URI: session.URI(), Diagnostics: []Diagnostic{{Range: rangeOf(0, 0, 0, 1), Message: "bad"}}})
+	deliver(editor, session, FormattingMsg{URI: session.URI(), Edits: edit})
+	deliver(editor, session, RenameMsg{URI: session.URI(), Edit: &WorkspaceEdit{Changes: map[DocumentURI][]TextEdit{session.URI(): edit}}})
+	session.Update(WorkspaceEditMsg{Edit: &WorkspaceEdit{Changes: map[DocumentURI][]TextEdit{session.URI(): edit}}})
+	deliver(editor, session, CodeActionsMsg{URI: session.URI(), Actions: []CodeAction{{Title: "Fix", Edit: &WorkspaceEdit{Changes: map[DocumentURI][]TextEdit{session.URI(): edit}}}}, Apply: 1})
+	deliver(editor, session, DefinitionMsg{URI: session.URI(), Locations: []Location{{URI: session.URI(), Range: rangeOf(0, 3, 0, 3)}}})
+	deliver(editor, session, HoverMsg{URI: session.URI(), Hover: &Hover{Contents: HoverContents{Value: "package a"}}})
+	assert.Equal(t, "notes", editor.GetBuffer().Text(), "Edits of the document should not change other buffers")
+	assert.Empty(t, editor.GetDiagnostics(), "Diagnostics of the document should not be shown in other buffers")
+	assert.Equal(t, vimtea.Cursor{}, editor.GetCursor())
+	assert.NotContains(t, editor.View(), "package a")
+
+	require.NoError(t, editor.SwitchBuffer(1))
+	assert.Equal(t, "package a", editor.GetBuffer().Text())
+	assert.Equal(t, []vimtea.Diagnostic{
+		{Range: vimtea.TextRange{End: vimtea.Cursor{}}, Severity: vimtea.SeverityError, Message: "bad"},
+	}, editor.GetDiagnostics(), "Diagnostics should be shown when the buffer is shown again")
+}
+
+func TestSessionDiagnostics(t *testing.T) {
+	server, client := newFakeServer(t, map[string]any{"positionEncoding": "utf-8"})
+	editor := vimtea.NewEditor(vimtea.WithContent("let x = 1\nlet y"))
+	session := Attach(editor, client, "doc.txt", "plaintext")
+	server.next(t)
+
+	server.publish(t, "textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI: session.URI(),
+		Diagnostics: []Diagnostic{
+			{Range: rangeOf(0, 4, 0, 5), Severity: SeverityWarning, Message: "unused variable", Source: "fake"},
+			{Range: rangeOf(1, 5, 2, 0), Message: "expected '='"},
+		},
+	})
+	msg := session.Init()()
+	require.IsType(t, DiagnosticsMsg{}, msg)
+	assert.NotNil(t, session.Update(msg), "The session should keep listening after a notification")
+
+	assert.Equal(t, []vimtea.Diagnostic{
+		{Range: vimtea.TextRange{Start: vimtea.Cursor{Row: 0, Col: 4}, End: vimtea.Cursor{Row: 0, Col: 4}}, Severity: vimtea.SeverityWarning, Message: "unused variable", Source: "fake"},
+		{Range: vimtea.TextRange{Start: vimtea.Cursor{Row: 1, Col: 5}, End: vimtea.Cursor{Row: 1, Col: 5}}, Severity: vimtea.SeverityError, Message: "expected '='"},
+	}, editor.GetDiagnostics(), "Ranges should become inclusive and an unset severity an error")
+
+	second := Attach(vimtea.NewEditor(), client, "other.txt", "plaintext")
+	assert.Nil(t, second.Init(), "Only the first session of a client should listen")
+}
+
+func TestSessionHoverAndDefinition(t *testing.T) {
+	server, client := newFakeServer(t, map[string]any{})
+	editor := vimtea.NewEditor(vimtea.WithContent("func main() {\n\tgreet()\n}\n\nfunc greet() {}"))
+	editor.SetSize(40, 12)
+	session := Attach(editor, client, "main.go", "go")
+
+	server.results["textDocument/hover"] = map[string]any{
+		"contents": map[string]any{"kind": "markdown", "value": "func greet()\n\nGreets the world."},
+	}
+	press(editor, keys("j")...)
+	msgs := press(editor, keys("wK")...)
+	hover := find[HoverMsg](t, msgs)
+	request := <-server.requests
+	assert.Equal(t, "textDocument/hover", request.method)
+	assert.Contains(t, string(request.params), `"position":{"line":1,"character":1}`)
+
+	deliver(editor, session, hover)
+	assert.Contains(t, editor.View(), "Greets the world.", "Hover contents should be shown in a popup")
+
+	server.results["textDocument/hover"] = nil
+	msgs = press(editor, keys("K")...)
+	assert.Contains(t, statusLine(editor, deliver(editor, session, find[HoverMsg](t, msgs))), "No information available")
+
+	server.results["textDocument/definition"] = []map[string]any{{
+		"targetUri":            session.URI(),
+		"targetRange":          rangeOf(4, 0, 4, 15),
+		"targetSelectionRange": rangeOf(4, 5, 4, 10),
+	}}
+	msgs = press(editor, keys("gd")...)
+	deliver(editor, session, find[DefinitionMsg](t, msgs))
+	assert.Equal(t, vimtea.Cursor{Row: 4, Col: 5}, editor.GetCursor(), "gd should jump to the definition")
+
+	server.results["textDocument/definition"] = Location{URI: "file:///other.go", Range: rangeOf(9, 0, 9, 1)}
+	msgs = press(editor, keys("gd")...)
+	status := statusLine(editor, deliver(editor, session, find[DefinitionMsg](t, msgs)))
+	assert.Contains(t, status, "Definition in file:///other.go:10")
+	assert.Equal(t, vimtea.Cursor{Row: 4, Col: 5}, editor.GetCursor(), "Definitions in other documents are left to the application")
+}
+
+func TestSessionEdits(t *testing.T) {
+	server, client := newFakeServer(t, map[string]any{})
+	editor := vimtea.NewEditor(vimtea.WithContent("x:=1\nprint(x)"))
+	session := Attach(editor, client, "main.go", "go")
+	server.next(t)
+
+	server.results["textDocument/rename"] = WorkspaceEdit{
+		Changes: map[DocumentURI][]TextEdit{session.URI(): {
+			{Range: rangeOf(0, 0, 0, 1), NewText: "count"},
+			{Range: rangeOf(1, 6, 1, 7), NewText: "count"},
+		}},
+	}
+	msgs := command(editor, "LspRename count")
+	deliver(editor, session, find[RenameMsg](t, msgs))
+	assert.Equal(t, "count:=1\nprint(count)", editor.GetBuffer().Text())
+
+	press(editor, keys("u")...)
+	assert.Equal(t, "x:=1\nprint(x)", editor.GetBuffer().Text(), "A rename should be a single undo step")
+	press(editor, tea.KeyMsg{Type: tea.KeyCtrlR})
+
+	// Edits reach the server with the next update of the editor, here
+	// combined with the undo and redo
+	_, changes := server.didChange(t)
+	assert.Equal(t, []TextDocumentContentChangeEvent{{Range: &Range{End: Position{1, 7}}, Text: "count:=1\nprint(count"}}, changes)
+
+	server.results["textDocument/formatting"] = []TextEdit{{Range: rangeOf(0, 5, 0, 5), NewText: " "}, {Range: rangeOf(0, 7, 0, 7), NewText: " "}}
+	msgs = command(editor, "LspDocumentFormat")
+	deliver(editor, session, find[FormattingMsg](t, msgs))
+	assert.Equal(t, "count := 1\nprint(count)", editor.GetBuffer().Text())
+	request := <-server.requests
+	for request.method != "textDocument/formatting" {
+		request = <-server.requests
+	}
+	assert.Contains(t, string(request.params), `"options":{"tabSize":4,"insertSpaces":false}`)
+
+	assert.Contains(t, statusLine(editor, command(editor, "LspRename")), "E471: Argument required")
+}
+
+func TestSessionCodeActions(t *testing.T) {
+	server, client := newFakeServer(t, map[string]any{})
+	editor := vimtea.NewEditor(vimtea.WithContent("import \"fmt\"\nfunc main() {}"))
+	editor.SetSize(40, 10)
+	session := Attach(editor, client, "main.go", "go")
+
+	server.results["textDocument/codeAction"] = []any{
+		CodeAction{
+			Title: "Remove unused import",
+			Kind:  "quickfix",
+			Edit:  &WorkspaceEdit{Changes: map[DocumentURI][]TextEdit{session.URI(): {{Range: rangeOf(0, 0, 1, 0)}}}},
+		},
+		Command{Title: "Organize imports", Command: "source.organizeImports"},
+	}
+	server.results["workspace/applyEdit"] = WorkspaceEdit{
+		DocumentChanges: []TextDocumentEdit{{
+			TextDocument: VersionedTextDocumentIdentifier{URI: session.URI()},
+			Edits:        []TextEdit{{Range: rangeOf(0, 0, 0, 0), NewText: "// organized\n"}},
+		}},
+	}
+
+	msgs := command(editor, "LspCodeAction")
+	actions := find[CodeActionsMsg](t, msgs)
+	require.Len(t, actions.Actions, 2)
+	assert.Equal(t, "source.organizeImports", actions.Actions[1].Command.Command, "Bare commands should become code actions")
+	deliver(editor, session, actions)
+	view := editor.View()
+	assert.Contains(t, view, "1. Remove unused import")
+	assert.Contains(t, view, "2. Organize imports")
+
+	msgs = command(editor, "LspCodeAction 2")
+	deliver(editor, session, find[CodeActionsMsg](t, msgs))
+	edit := session.Init()()
+	require.IsType(t, WorkspaceEditMsg{}, edit, "The server should apply its edit through the client")
+	session.Update(edit)
+	assert.Equal(t, "// organized\nimport \"fmt\"\nfunc main() {}", editor.GetBuffer().Text())
+
+	server.results["textDocument/codeAction"] = []CodeAction{{
+		Title: "Remove unused import",
+		Edit:  &WorkspaceEdit{Changes: map[DocumentURI][]TextEdit{session.URI(): {{Range: rangeOf(1, 0, 2, 0)}}}},
+	}}
+	msgs = command(editor, "LspCodeAction 1")
+	deliver(editor, session, find[CodeActionsMsg](t, msgs))
+	assert.Equal(t, "// organized\nfunc main() {}", editor.GetBuffer().Text(), "A numbered action should be applied")
+}
+
+func TestSessionCompletion(t *testing.T) {
+	server, client := newFakeServer(t, map[string]any{})
+	editor := vimtea.NewEditor(vimtea.WithContent("fmt.Pr"))
+	editor.SetSize(60, 10)
+	Attach(editor, client, "main.go", "go")
+
+	server.results["textDocument/completion"] = CompletionList{Items: []CompletionItem{
+		{Label: "Println", Kind: 3, Detail: "func(a ...any)", Documentation: &HoverContents{Value: "Println formats using the default formats."}},
+		{Label: "Printf", Kind: 3, InsertText: "Printf(${1:format})", InsertTextFormat: 2},
+	}}
+	press(editor, keys("A")...)
+	msgs := press(editor, tea.KeyMsg{Type: tea.KeyCtrlN})
+	result := find[vimtea.CompletionResultMsg](t, msgs)
+	assert.Equal(t, []vimtea.CompletionItem{
+		{Label: "Println", Kind: "function", Detail: "func(a ...any)", Documentation: "Println formats using the default formats."},
+		{Label: "Printf", Kind: "function", InsertText: "Printf(${1:format})", Snippet: true},
+	}, result.Items, "Proposals should become items of the completion menu")
+
+	editor.Update(result)
+	view := editor.View()
+	assert.Contains(t, view, "Println function")
+	assert.Contains(t, view, "Printf  function")
+
+	press(editor, tea.KeyMsg{Type: tea.KeyCtrlN}, tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, "fmt.Printf(format)", editor.GetBuffer().Text(), "Snippet proposals should be expanded")
+	assert.Equal(t, vimtea.Cursor{Row: 0, Col: 17}, editor.GetCursor(), "The first placeholder should be selected")
+}
+
+func TestClientErrors(t *testing.T) {
+	_, client := newFakeServer(t, map[string]any{})
+	editor := vimtea.NewEditor(vimtea.WithContent("text"))
+	editor.SetSize(60, 5)
+	session := Attach(editor, client, "doc.txt", "plaintext")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, client.Close(ctx))
+
+	msg := session.Hover()()
+	require.IsType(t, ErrorMsg{}, msg)
+	assert.ErrorIs(t, msg.(ErrorMsg).Err, ErrClosed)
+	assert.Contains(t, statusLine(editor, deliver(editor, session, msg)), "textDocument/hover: lsp: connection closed")
+}
@@ -0,0 +1,82 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"fmt"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// registerMarkBindings registers m{a-z} to set a mark of the buffer and
+// '{a-z} and `{a-z} to jump to its line or position
+func registerMarkBindings(m *editorModel) {
+	for c := 'a'; c <= 'z'; c++ {
+		name := string(c)
+		m.registry.Add("m"+name, setMark(name), ModeNormal, "Set mark "+name)
+		for _, mode := range []EditorMode{ModeNormal, ModeVisual} {
+			m.registry.Add("'"+name, jumpToMark(name, true), mode, "Jump to the line of mark "+name)
+			m.registry.Add("`"+name, jumpToMark(name, false), mode, "Jump to mark "+name)
+		}
+	}
+	m.commands.Register("marks", listMarks)
+}
+
+// setMark returns a command that sets a mark at the cursor
+func setMark(name string) Command {
+	return func(model *editorModel) tea.Cmd {
+		if model.marks == nil {
+			model.marks = make(map[string]Cursor)
+		}
+		model.marks[name] = model.cursor
+		return nil
+	}
+}
+
+// jumpToMark returns a command that moves the cursor to a mark, or to the
+// first non-blank character of its line when linewise
+func jumpToMark(name string, linewise bool) Command {
+	return func(model *editorModel) tea.Cmd {
+		pos, ok := model.marks[name]
+		if !ok {
+			return SetStatusMsg("E20: Mark not set")
+		}
+		model.SetCursor(pos)
+		if linewise {
+			moveToFirstNonWhitespace(model)
+		}
+		model.ensureCursorVisible()
+		return nil
+	}
+}
+
+// shiftMarks moves the marks with the text after a change of the buffer
+func (m *editorModel) shiftMarks(change TextChange) {
+	for name, pos := range m.marks {
+		m.marks[name] = change.shiftPos(pos)
+	}
+}
+
+// listMarks implements :marks, showing the marks of the buffer
+func listMarks(model *editorModel) tea.Cmd {
+	if len(model.marks) == 0 {
+		return SetStatusMsg("E283: No marks matching")
+	}
+	names := make([]string, 0, len(model.marks))
+	for name := range model.marks {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	lines := []string{"mark line  col text"}
+	for _, name := range names {
+		pos := model.marks[name]
+		text := ""
+		if pos.Row < model.buffer.lineCount() {
+			text = model.buffer.Line(pos.Row)
+		}
+		lines = append(lines, fmt.Sprintf(" %s %6d %4d %s", name, pos.Row+1, pos.Col, text))
+	}
+	model.showPopup(lines, false)
+	return nil
+}
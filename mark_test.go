@@ -0,0 +1,38 @@
+package vimtea
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarks(t *testing.T) {
+	fsys := NewMemFileSystem(map[string]string{"a.txt": "one\n  two three\nfour", "b.txt": "beta"})
+	model := NewEditor(WithFile("a.txt"), WithFileSystem(fsys)).(*editorModel)
+
+	sendKeys(model, "jwwma")
+	assert.Equal(t, Cursor{1, 6}, model.cursor)
+	sendKeys(model, "gg'a")
+	assert.Equal(t, Cursor{1, 2}, model.cursor, "' should jump to the first non-blank of the mark's line")
+	sendKeys(model, "gg`a")
+	assert.Equal(t, Cursor{1, 6}, model.cursor, "` should jump to the mark's position")
+
+	sendKeys(model, "ggOzero")
+	pressKey(model, tea.KeyEsc)
+	sendKeys(model, "`a")
+	assert.Equal(t, Cursor{2, 6}, model.cursor, "Marks should move with their text")
+
+	runCommand(t, model, "w")
+	runCommand(t, model, "e b.txt")
+	msgs := runCommand(t, model, "marks")
+	assert.Contains(t, msgs, statusMessageMsg("E283: No marks matching"), "Marks should belong to their buffer")
+	sendKeys(model, "`a")
+	assert.Equal(t, Cursor{0, 0}, model.cursor)
+
+	runCommand(t, model, "b 1")
+	runCommand(t, model, "marks")
+	require.NotNil(t, model.float(model.popup))
+	assert.Equal(t, []string{"mark line  col text", " a      3    6   two three"}, model.float(model.popup).lines)
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/alecthomas/chroma/v2/styles"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -59,6 +60,9 @@ type Editor interface {
 	// GetCursor returns the current cursor position
 	GetCursor() Cursor
 
+	// SetCursor moves the cursor to a position, which is kept inside the buffer
+	SetCursor(pos Cursor)
+
 	// GetSelectionBoundary returns the start and end cursors of the current selection
 	// in visual mode. It ensures the start cursor is always before the end cursor.
 	GetSelectionBoundary() (Cursor, Cursor)
@@ -75,21 +79,167 @@ type Editor interface {
 	// SetSize updates the editor's dimensions when the terminal window is resized
 	SetSize(width, height int) (tea.Model, tea.Cmd)
 
+	// SetOffset sets the screen cell of the top left corner of the editor
+	// when it is embedded in a larger layout, for mapping mouse events
+	SetOffset(x, y int)
+
 	// Tick sends a tick message to the editor
 	Tick() tea.Cmd
 
 	// Reset restores the editor to its initial state
 	Reset() tea.Cmd
+
+	// AddAutocmd registers a handler for an editor event such as InsertEnter or
+	// BufWritePre. The pattern filters by file name, e.g. "*.go,*.mod".
+	AddAutocmd(event AutocmdEvent, pattern string, fn AutocmdFn)
+
+	// SetOption changes an option such as "number" or "tabstop".
+	// Buffer-local options are set on the current buffer.
+	SetOption(name string, value any) error
+
+	// GetOption returns the current value of an option
+	GetOption(name string) (any, error)
+
+	// DefineOption registers a custom option that can be changed with :set
+	DefineOption(def OptionDef) error
+
+	// AddSpanProvider adds a provider of spans drawn over the syntax
+	// highlighting, e.g. semantic highlights from an external analyzer
+	AddSpanProvider(provider SpanProvider)
+
+	// SetTheme switches the colors of the UI and the syntax highlighting
+	SetTheme(theme Theme)
+
+	// GetTheme returns the current theme
+	GetTheme() Theme
+
+	// PlaceSign adds a sign to the sign column and returns its ID.
+	// A sign with the ID of an existing sign replaces it.
+	PlaceSign(sign Sign) int
+
+	// UnplaceSign removes the sign with the given ID
+	UnplaceSign(id int)
+
+	// UnplaceSigns removes all signs of a group, or all signs when group is empty
+	UnplaceSigns(group string)
+
+	// GetSigns returns the placed signs at their current lines
+	GetSigns() []Sign
+
+	// SetDiagnostics replaces the diagnostics of the buffer, drawn as
+	// underlines, signs and virtual text after the end of their line
+	SetDiagnostics(diagnostics []Diagnostic)
+
+	// GetDiagnostics returns the diagnostics at their current positions
+	GetDiagnostics() []Diagnostic
+
+	// ApplyEdits applies non-overlapping edits as a single undo step.
+	// Positions refer to the buffer before any of the edits.
+	ApplyEdits(edits []TextEdit)
+
+	// ShowPopup shows lines of text in a box next to the cursor until the
+	// next key press
+	ShowPopup(lines []string)
+
+	// AddCompletionSource adds a source of items for the completion menu
+	// opened with <C-n> and <C-p> in insert mode
+	AddCompletionSource(source CompletionSource)
+
+	// InsertSnippet inserts a snippet at the cursor as a single undo step
+	// and enters insert mode on its first tabstop. Tab and shift+tab jump
+	// between the tabstops.
+	InsertSnippet(snippet string) tea.Cmd
+
+	// AddSnippet registers a snippet expanded with <Tab> after its trigger
+	// word in insert mode
+	AddSnippet(trigger, body string)
+
+	// OpenFloat opens a floating window showing lines over the text until
+	// it is closed
+	OpenFloat(lines []string, opts FloatOptions) FloatID
+
+	// SetFloatLines replaces the lines of a floating window
+	SetFloatLines(id FloatID, lines []string)
+
+	// ScrollFloat scrolls the lines of a floating window by delta lines
+	ScrollFloat(id FloatID, delta int)
+
+	// CloseFloat closes a floating window
+	CloseFloat(id FloatID)
+
+	// SplitWindow splits the current window and makes the new window,
+	// which shows the same buffer, current
+	SplitWindow(vertical bool) WindowID
+
+	// Windows returns the open windows, left to right and top to bottom
+	Windows() []WindowInfo
+
+	// CurrentWindow returns the ID of the window that has the focus
+	CurrentWindow() WindowID
+
+	// SetCurrentWindow moves the focus to a window
+	SetCurrentWindow(id WindowID) error
+
+	// CloseWindow closes a window. Unsaved changes of a buffer no other
+	// window shows are only discarded when force is set.
+	CloseWindow(id WindowID, force bool) error
+
+	// ResizeWindow changes the number of text rows and columns of a window
+	ResizeWindow(id WindowID, width, height int) error
+
+	// OpenBuffer shows the buffer of a file in the current window, reading
+	// the file into a new buffer when no buffer has it yet, and returns the
+	// buffer number
+	OpenBuffer(path string) (int, error)
+
+	// SwitchBuffer shows a buffer of the buffer list in the current window
+	SwitchBuffer(number int) error
+
+	// DeleteBuffer removes a buffer from the buffer list. Unsaved changes
+	// are only discarded when force is set.
+	DeleteBuffer(number int, force bool) error
+
+	// Buffers returns the buffer list ordered by number
+	Buffers() []BufferInfo
+
+	// CurrentBuffer returns the buffer shown in the current window
+	CurrentBuffer() BufferInfo
+
+	// NewTab opens a tab page after the current one showing the buffer of
+	// a file, or a new empty buffer when path is empty
+	NewTab(path string) error
+
+	// Tabs returns the tab pages in the order of the tabline
+	Tabs() []TabInfo
+
+	// CurrentTab returns the position of the current tab page, from 1
+	CurrentTab() int
+
+	// SetCurrentTab makes the tab page at a position, from 1, current
+	SetCurrentTab(n int) error
+
+	// CloseTab closes the tab page at a position, from 1. Unsaved changes
+	// no other tab page shows are only hidden when force is set.
+	CloseTab(n int, force bool) error
 }
 
 // editorModel implements the Editor interface and maintains the editor state
 type editorModel struct {
-	buffer         *buffer // Text buffer with undo/redo
-	cursor         Cursor  // Current cursor position
-	yankBuffer     string  // Clipboard
-	lastOp         string  // Last operation performed (for repeating with .)
-	fullScreen     bool    // Whether to use the full terminal screen
-	initialContent string  // Initial content used to create the editor
+	buffer         *buffer    // Text buffer with undo/redo
+	cursor         Cursor     // Current cursor position
+	yankBuffer     string     // Clipboard
+	lastOp         string     // Last operation performed (for repeating with .)
+	fullScreen     bool       // Whether to use the full terminal screen
+	initialContent string     // Initial content used to create the editor
+	filePath       string     // File read and written by the file commands
+	fs             FileSystem // File system used by the file commands
+	quitOnRequest  bool       // Whether QuitRequestedMsg quits the program
+
+	indenter      Indenter       // Indenter set with WithIndenter, or nil
+	smartIndenter *SmartIndenter // Indenter used with smartindent when none is set
+	autoIndent    bool           // Whether new lines copy the current indentation
+	smartIndent   bool           // Whether new lines are indented by the indenter
+	addedIndent   addedIndent    // Indentation of the last opened line, removed if nothing is typed
 
 	mode              EditorMode // Current mode
 	enableCommandMode bool       // Whether command mode is enabled
@@ -102,9 +252,16 @@ type editorModel struct {
 
 	countPrefix int // Numeric prefix for commands like "10j"
 
-	relativeNumbers bool // Whether to show relative line numbers
+	showNumbers     bool   // Whether to show line numbers
+	wrap            bool   // Whether long lines wrap onto several screen rows
+	lineBreak       bool   // Whether wrapping breaks at word boundaries
+	breakIndent     bool   // Whether wrapped rows keep the indentation of their line
+	showBreak       string // Marker shown at the start of wrapped rows
+	sideScrollOff   int    // Columns kept left and right of the cursor without wrap
+	relativeNumbers bool   // Whether to show relative line numbers
 
 	viewport        viewport.Model // For scrolling
+	xOffset         int            // First visible visual column when lines do not wrap
 	width           int            // Window width
 	height          int            // Window height
 	statusMessage   string         // Current status message
@@ -114,39 +271,91 @@ type editorModel struct {
 	enableStatusBar bool           // Whether to show the status bar
 	waitReplace     bool           // Whether waiting for replace character
 
-	lineNumberStyle        lipgloss.Style
-	currentLineNumberStyle lipgloss.Style
-	textStyle              lipgloss.Style
-	statusStyle            lipgloss.Style
-	cursorStyle            lipgloss.Style
-	commandStyle           lipgloss.Style
-	selectedStyle          lipgloss.Style
-
-	highlighter *syntaxHighlighter
+	theme Theme // Colors of the UI and the syntax highlighting
+
+	highlighter   *syntaxHighlighter
+	spanProviders []SpanProvider // Providers of spans drawn over the syntax highlighting
+	signs         []Sign         // Signs of the buffer in the order they were placed
+	nextSignID    int            // Last ID assigned to a sign
+	signColumn    string         // When the sign column is shown, see WithSignColumn
+	diagnostics   []Diagnostic   // Diagnostics of the buffer ordered by start
+	floats        []*floatWindow // Open floating windows in the order they were opened
+	nextFloatID   FloatID        // Last ID assigned to a float
+	popup         FloatID        // Float of the last popup, closed on the next key press
+
+	win          *window     // Current window, whose state is held in the fields above
+	layout       *layoutNode // Windows on screen
+	nextWindowID WindowID    // Last ID assigned to a window
+	tabPages     []*tabPage  // Tab pages in the order of the tabline
+	tab          *tabPage    // Current tab page, whose layout is held in the fields above
+
+	docs             []*document       // Buffer list ordered by number
+	nextBufferNumber int               // Last number assigned to a buffer
+	marks            map[string]Cursor // Marks of the current buffer by name
+	areaWidth        int               // Width of the area shared by the windows
+	areaHeight       int               // Height of the area shared by the windows
+	offsetX, offsetY int               // Screen cell of the top left corner of the editor
+	mouse            mouseState        // Clicks and drags of the left mouse button
+
+	completionSources []CompletionSource // Sources of completion items besides the buffer words
+	completion        *completion        // Open completion menu
+	nextCompletionID  int                // Last ID assigned to a completion request
+
+	snippets map[string]string // Snippet bodies by trigger word
+	snippet  *snippetSession   // Snippet whose tabstops are being visited
 
 	yankHighlight yankHighlight
 
 	registry *BindingRegistry // Registry for key bindings
 	commands *CommandRegistry // Registry for commands
+	options  *OptionRegistry  // Registry for :set options
+
+	lastState    editorState      // Snapshot used to detect changes between updates
+	onChange     []OnChangeFn     // Callbacks for buffer content changes
+	onCursorMove []OnCursorMoveFn // Callbacks for cursor movement
+	onModeChange []OnModeChangeFn // Callbacks for mode changes
+
+	autocmds        *AutocmdRegistry // Registry for autocommands
+	pendingCmds     []tea.Cmd        // Commands returned by autocommands, flushed after each update
+	lastActivity    time.Time        // Time of the last keypress, used for CursorHold
+	cursorHoldFired bool             // Whether CursorHold already fired since the last keypress
+	updateTime      time.Duration    // Idle time before CursorHold fires
 }
 
 // options holds configuration options for creating a new editor
 type options struct {
-	Content                string         // Initial content for the editor
-	EnableCommandMode      bool           // Whether to enable command mode
-	EnableStatusBar        bool           // Whether to show the status bar
-	DefaultSyntaxTheme     string         // Syntax highlighting theme
-	BlinkInterval          time.Duration  // Cursor blink interval
-	TextStyle              lipgloss.Style // Style for regular text
-	LineNumberStyle        lipgloss.Style // Style for line numbers
-	CurrentLineNumberStyle lipgloss.Style // Style for current line number
-	StatusStyle            lipgloss.Style // Style for status bar
-	CursorStyle            lipgloss.Style // Style for cursor
-	CommandStyle           lipgloss.Style // Style for command line
-	SelectedStyle          lipgloss.Style // Style for selected text
-	FileName               string         // Filename for syntax highlighting
-	RelativeNumbers        bool           // Whether to show relative line numbers
-	FullScreen             bool           // Whether to use the full terminal screen
+	Content           string             // Initial content for the editor
+	EnableCommandMode bool               // Whether to enable command mode
+	EnableStatusBar   bool               // Whether to show the status bar
+	BlinkInterval     time.Duration      // Cursor blink interval
+	Theme             Theme              // Colors of the UI and the syntax highlighting
+	FileName          string             // Filename for syntax highlighting
+	RelativeNumbers   bool               // Whether to show relative line numbers
+	FullScreen        bool               // Whether to use the full terminal screen
+	OnChange          []OnChangeFn       // Callbacks for buffer content changes
+	OnCursorMove      []OnCursorMoveFn   // Callbacks for cursor movement
+	OnModeChange      []OnModeChangeFn   // Callbacks for mode changes
+	UpdateTime        time.Duration      // Idle time before CursorHold fires
+	File              string             // File to load and write
+	FileSystem        FileSystem         // File system used by the file commands
+	QuitOnRequest     bool               // Whether QuitRequestedMsg quits the program
+	TabStop           int                // Visual width of a tab character
+	ShiftWidth        int                // Width of one indent level
+	SoftTabStop       int                // Width of <Tab> and <BS> in insert mode
+	ExpandTab         bool               // Whether <Tab> inserts spaces
+	Indenter          Indenter           // Computes indentation for the = operator
+	AutoIndent        bool               // Whether new lines copy the current indentation
+	SmartIndent       bool               // Whether new lines are indented by the Indenter
+	Wrap              bool               // Whether long lines wrap
+	LineBreak         bool               // Whether wrapping breaks at word boundaries
+	BreakIndent       bool               // Whether wrapped rows keep the line's indentation
+	ShowBreak         string             // Marker shown at the start of wrapped rows
+	SideScrollOff     int                // Columns kept beside the cursor without wrap
+	SpanProviders     []SpanProvider     // Providers of spans drawn over the syntax highlighting
+	Language          string             // Language for syntax highlighting, detected if empty
+	SignColumn        string             // When the sign column is shown
+	CompletionSources []CompletionSource // Sources of completion items besides the buffer words
+	Snippets          map[string]string  // Snippet bodies by trigger word
 }
 
 // EditorOption is a function that modifies the editor options
@@ -155,21 +364,20 @@ type EditorOption func(*options)
 // NewEditor creates a new editor instance with the provided options
 func NewEditor(opts ...EditorOption) Editor {
 	options := &options{
-		Content:                "",
-		EnableCommandMode:      true,
-		EnableStatusBar:        true,
-		DefaultSyntaxTheme:     "catppuccin-macchiato",
-		BlinkInterval:          1 * time.Second,
-		TextStyle:              textStyle,
-		LineNumberStyle:        lineNumberStyle,
-		CurrentLineNumberStyle: currentLineNumberStyle,
-		StatusStyle:            statusStyle,
-		CursorStyle:            cursorStyle,
-		CommandStyle:           commandStyle,
-		SelectedStyle:          selectedStyle,
-		FileName:               "",
-		RelativeNumbers:        false,
-		FullScreen:             false,
+		Content:           "",
+		EnableCommandMode: true,
+		EnableStatusBar:   true,
+		BlinkInterval:     1 * time.Second,
+		Theme:             DefaultTheme(),
+		SignColumn:        "auto",
+		FileName:          "",
+		RelativeNumbers:   false,
+		FullScreen:        false,
+		UpdateTime:        defaultUpdateTime,
+		FileSystem:        OSFileSystem(),
+		TabStop:           defaultTabStop,
+		AutoIndent:        true,
+		Wrap:              true,
 	}
 
 	// Apply all options
@@ -180,33 +388,73 @@ func NewEditor(opts ...EditorOption) Editor {
 	cpErr := clipboard.Init()
 
 	m := &editorModel{
-		buffer:                 newBuffer(options.Content),
-		mode:                   ModeNormal,
-		fullScreen:             options.FullScreen,
-		enableCommandMode:      options.EnableCommandMode,
-		enableStatusBar:        options.EnableStatusBar,
-		cursor:                 newCursor(0, 0),
-		keySequence:            []string{},
-		viewport:               viewport.New(0, 0),
-		cursorBlink:            true,
-		lastBlinkTime:          time.Now(),
-		blinkInterval:          options.BlinkInterval,
-		lineNumberStyle:        options.LineNumberStyle,
-		currentLineNumberStyle: options.CurrentLineNumberStyle,
-		textStyle:              options.TextStyle,
-		statusStyle:            options.StatusStyle,
-		cursorStyle:            options.CursorStyle,
-		commandStyle:           options.CommandStyle,
-		selectedStyle:          options.SelectedStyle,
-		relativeNumbers:        options.RelativeNumbers,
-		countPrefix:            1,
-
-		highlighter:    newSyntaxHighlighter(options.DefaultSyntaxTheme, options.FileName),
-		yankHighlight:  newYankHighlight(),
-		registry:       newBindingRegistry(),
-		commands:       newCommandRegistry(),
-		initialContent: options.Content,
+		buffer:            newBuffer(options.Content),
+		mode:              ModeNormal,
+		fullScreen:        options.FullScreen,
+		enableCommandMode: options.EnableCommandMode,
+		enableStatusBar:   options.EnableStatusBar,
+		cursor:            newCursor(0, 0),
+		keySequence:       []string{},
+		viewport:          viewport.New(0, 0),
+		cursorBlink:       true,
+		lastBlinkTime:     time.Now(),
+		blinkInterval:     options.BlinkInterval,
+		theme:             options.Theme,
+		showNumbers:       true,
+		wrap:              options.Wrap,
+		lineBreak:         options.LineBreak,
+		breakIndent:       options.BreakIndent,
+		showBreak:         options.ShowBreak,
+		sideScrollOff:     options.SideScrollOff,
+		relativeNumbers:   options.RelativeNumbers,
+		countPrefix:       1,
+
+		highlighter:       newSyntaxHighlighter(options.Theme.Syntax, options.FileName),
+		yankHighlight:     newYankHighlight(),
+		spanProviders:     options.SpanProviders,
+		signColumn:        options.SignColumn,
+		completionSources: options.CompletionSources,
+		snippets:          options.Snippets,
+		registry:          newBindingRegistry(),
+		commands:          newCommandRegistry(),
+		options:           newOptionRegistry(),
+		initialContent:    options.Content,
+		onChange:          options.OnChange,
+		onCursorMove:      options.OnCursorMove,
+		onModeChange:      options.OnModeChange,
+		autocmds:          newAutocmdRegistry(),
+		lastActivity:      time.Now(),
+		updateTime:        options.UpdateTime,
+		fs:                options.FileSystem,
+		quitOnRequest:     options.QuitOnRequest,
+		indenter:          options.Indenter,
+		smartIndenter:     NewSmartIndenter(),
+		autoIndent:        options.AutoIndent,
+		smartIndent:       options.SmartIndent,
+	}
+
+	m.initWindows()
+
+	if options.TabStop < 1 {
+		options.TabStop = defaultTabStop
+	}
+	m.buffer.tabs = tabSettings{
+		tabStop:     options.TabStop,
+		shiftWidth:  options.ShiftWidth,
+		softTabStop: max(options.SoftTabStop, 0),
+		expandTab:   options.ExpandTab,
 	}
+
+	if options.File != "" {
+		if err := m.loadFile(options.File); err != nil {
+			m.statusMessage = err.Error()
+		}
+		m.initialContent = m.buffer.text()
+	}
+	if options.Language != "" {
+		m.highlighter.setLanguage(options.Language)
+	}
+	m.snapshotState()
 	go func() {
 		if cpErr != nil {
 			ch := clipboard.Watch(context.Background(), clipboard.FmtText)
@@ -216,8 +464,9 @@ func NewEditor(opts ...EditorOption) Editor {
 		}
 	}()
 
-	// Register default key bindings
+	// Register default key bindings and options
 	registerBindings(m)
+	registerOptions(m)
 	return m
 }
 
@@ -236,6 +485,17 @@ func (m *editorModel) Init() tea.Cmd {
 // Update handles messages and updates the editor state
 // This is part of the tea.Model interface
 func (m *editorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	cursor := m.cursor
+	cmd := m.update(msg)
+	if m.cursor != cursor {
+		m.ensureCursorVisibleX()
+	}
+	notify := m.notifyChanges()
+	return m, tea.Batch(cmd, notify, m.flushPendingCmds())
+}
+
+// update dispatches a message to the matching handler and returns its command
+func (m *editorModel) update(msg tea.Msg) tea.Cmd {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
@@ -243,10 +503,24 @@ func (m *editorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Reset cursor blink on keypress
 		m.cursorBlink = true
 		m.lastBlinkTime = time.Now()
-		return m.handleKeypress(msg)
+		m.lastActivity = m.lastBlinkTime
+		m.cursorHoldFired = false
+		m.closeFloatsOnKey()
+		_, cmd = m.handleKeypress(msg)
+		m.refreshSnippet()
+		m.refreshCompletion()
+	case tea.MouseMsg:
+		m.cursorBlink = true
+		m.lastBlinkTime = time.Now()
+		m.lastActivity = m.lastBlinkTime
+		m.cursorHoldFired = false
+		cmd = m.handleMouse(msg)
+		m.refreshSnippet()
+		m.refreshCompletion()
+
 	case tea.WindowSizeMsg:
 		if m.fullScreen {
-			return m.SetSize(msg.Width, msg.Height)
+			_, cmd = m.SetSize(msg.Width, msg.Height)
 		}
 
 	case cursorBlinkMsg:
@@ -261,11 +535,21 @@ func (m *editorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.yankHighlight.Active && now.Sub(m.yankHighlight.StartTime) >= m.yankHighlight.Duration {
 			m.yankHighlight.Active = false
 		}
+
+		m.checkCursorHold(now)
 		cmd = cursorBlinkCmd()
 
 	case statusMessageMsg:
 		m.statusMessage = string(msg)
 
+	case CompletionResultMsg:
+		m.addCompletionResult(msg)
+
+	case QuitRequestedMsg:
+		if m.quitOnRequest {
+			cmd = tea.Quit
+		}
+
 	case UndoRedoMsg:
 		if msg.Success {
 			m.cursor = msg.NewCursor
@@ -279,18 +563,30 @@ func (m *editorModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case CommandMsg:
-		// Execute registered command
+		// Leave command mode before running the command so it can
+		// switch modes or set a status message itself
+		modeCmd := switchMode(m, ModeNormal)
+		commandLine := m.commandBuffer
+		if commandLine == "" {
+			commandLine = msg.Command
+		}
+		m.fireAutocmd(EventCmdExecute, AutocmdArgs{Command: commandLine})
+
+		// Execute registered command, falling back to the name without "!"
 		registeredCmd := m.commands.Get(msg.Command)
+		if registeredCmd == nil && strings.HasSuffix(msg.Command, "!") {
+			registeredCmd = m.commands.Get(strings.TrimSuffix(msg.Command, "!"))
+		}
 		if registeredCmd != nil {
 			cmd = registeredCmd(m)
 		} else {
 			m.statusMessage = "Unknown command"
 		}
 		m.commandBuffer = ""
-		m.mode = ModeNormal
+		cmd = tea.Batch(modeCmd, cmd)
 	}
 
-	return m, cmd
+	return cmd
 }
 
 // GetSelectionBoundary returns the start and end cursors of the current selection
@@ -319,21 +615,16 @@ func (m *editorModel) GetSelectionBoundary() (Cursor, Cursor) {
 
 // SetSize updates the editor's dimensions when the terminal window is resized
 func (m *editorModel) SetSize(width, height int) (tea.Model, tea.Cmd) {
-	m.width = width
-	m.height = height
+	m.areaWidth = width
+	m.areaHeight = height
 
 	// Adjust height for status bar
 	if m.enableStatusBar {
-		m.height = height - 2
+		m.areaHeight = height - 2
 	}
 
-	// Update viewport dimensions
-	m.viewport.Width = width
-	m.viewport.Height = height
-
-	if m.enableStatusBar {
-		m.viewport.Height = height - 2
-	}
+	// Share the area between the windows
+	m.layoutWindows()
 
 	// Ensure cursor is visible after resize
 	m.ensureCursorVisible()
@@ -348,6 +639,16 @@ func (m *editorModel) handleKeypress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handlePrefixKeypress(ModeNormal)(msg)
 
 	case ModeInsert:
+		// The open completion menu takes its keys first
+		if m.completion != nil {
+			if handled, cmd := m.completionKey(msg.String()); handled {
+				return m, cmd
+			}
+		}
+		if m.snippet != nil && m.snippet.selecting && m.snippetSelectKey(msg) {
+			return m, nil
+		}
+
 		// Check for registered keybindings first
 		if binding := m.registry.FindExact(msg.String(), ModeInsert); binding != nil {
 			cmd := binding.Command(m)
@@ -503,6 +804,14 @@ func (m *editorModel) handlePrefixKeypress(mode EditorMode) func(msg tea.KeyMsg)
 	}
 }
 
+// fileName returns the name of the file being edited, if any
+func (m *editorModel) fileName() string {
+	if m.filePath != "" {
+		return m.filePath
+	}
+	return m.highlighter.filename
+}
+
 // GetBuffer returns a wrapped buffer that provides the Buffer interface
 func (m *editorModel) GetBuffer() Buffer {
 	return &wrappedBuffer{m}
@@ -512,6 +821,14 @@ func (m *editorModel) GetCursor() Cursor {
 	return m.cursor.Clone()
 }
 
+// SetCursor moves the cursor to a position, which is kept inside the buffer
+func (m *editorModel) SetCursor(pos Cursor) {
+	m.cursor = m.clampPosition(pos)
+	m.adjustCursorPosition()
+	m.desiredCol = m.cursor.Col
+	m.ensureCursorVisible()
+}
+
 // AddBinding registers a new key binding with the editor
 func (m *editorModel) AddBinding(binding KeyBinding) {
 	m.registry.Add(binding.Key, func(em *editorModel) tea.Cmd {
@@ -586,7 +903,9 @@ func (m *editorModel) Reset() tea.Cmd {
 	m.buffer.saveUndoState(m.cursor)
 
 	// Reset buffer to initial content
-	m.buffer = newBuffer(m.initialContent)
+	b := newBuffer(m.initialContent)
+	b.fileFormat, b.encoding, b.bom = m.buffer.fileFormat, m.buffer.encoding, m.buffer.bom
+	m.replaceBuffer(b)
 
 	// Reset cursor position
 	m.cursor = newCursor(0, 0)
@@ -609,6 +928,22 @@ func (m *editorModel) Reset() tea.Cmd {
 	return SetStatusMsg("Editor reset")
 }
 
+// replaceBuffer swaps in a new buffer. The version continues from the
+// old buffer so change notifications stay monotonic, and the tab
+// settings carry over. Signs and diagnostics belong to the old buffer and
+// are removed.
+func (m *editorModel) replaceBuffer(b *buffer) {
+	b.version = m.buffer.version
+	b.tabs = m.buffer.tabs
+	b.replaceContent(m.buffer)
+	b.touch()
+	m.buffer = b
+	m.signs = nil
+	m.diagnostics = nil
+	m.completion = nil
+	m.snippet = nil
+}
+
 // statusMessageMsg is a message type for updating the status message
 type statusMessageMsg string
 
@@ -633,11 +968,11 @@ func WithEnableStatusBar(enable bool) EditorOption {
 	}
 }
 
-// WithDefaultSyntaxTheme sets the syntax highlighting theme
+// WithDefaultSyntaxTheme sets the chroma style of the syntax highlighting
 // Available themes include "catppuccin-macchiato" and others
 func WithDefaultSyntaxTheme(theme string) EditorOption {
 	return func(o *options) {
-		o.DefaultSyntaxTheme = theme
+		o.Theme.Syntax = styles.Get(theme)
 	}
 }
 
@@ -651,49 +986,49 @@ func WithBlinkInterval(interval time.Duration) EditorOption {
 // WithTextStyle sets the style for regular text
 func WithTextStyle(style lipgloss.Style) EditorOption {
 	return func(o *options) {
-		o.TextStyle = style
+		o.Theme.Normal = style
 	}
 }
 
 // WithLineNumberStyle sets the style for line numbers
 func WithLineNumberStyle(style lipgloss.Style) EditorOption {
 	return func(o *options) {
-		o.LineNumberStyle = style
+		o.Theme.LineNr = style
 	}
 }
 
 // WithCurrentLineNumberStyle sets the style for the current line number
 func WithCurrentLineNumberStyle(style lipgloss.Style) EditorOption {
 	return func(o *options) {
-		o.CurrentLineNumberStyle = style
+		o.Theme.CursorLineNr = style
 	}
 }
 
 // WithStatusStyle sets the style for the status bar
 func WithStatusStyle(style lipgloss.Style) EditorOption {
 	return func(o *options) {
-		o.StatusStyle = style
+		o.Theme.StatusLine = style
 	}
 }
 
 // WithCursorStyle sets the style for the cursor
 func WithCursorStyle(style lipgloss.Style) EditorOption {
 	return func(o *options) {
-		o.CursorStyle = style
+		o.Theme.Cursor = style
 	}
 }
 
 // WithCommandStyle sets the style for the command line
 func WithCommandStyle(style lipgloss.Style) EditorOption {
 	return func(o *options) {
-		o.CommandStyle = style
+		o.Theme.CommandLine = style
 	}
 }
 
 // WithSelectedStyle sets the style for selected text
 func WithSelectedStyle(style lipgloss.Style) EditorOption {
 	return func(o *options) {
-		o.SelectedStyle = style
+		o.Theme.Visual = style
 	}
 }
 
@@ -0,0 +1,202 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// motion is a cursor movement of normal and visual mode. Operators such as
+// > and = apply to the lines it moves over.
+type motion struct {
+	key       string
+	command   Command
+	help      string
+	exclusive bool // Whether the character moved to is left out of the range
+}
+
+// motions are the cursor movements accepted after an operator
+var motions = []motion{
+	{"h", moveCursorLeft, "Move cursor left", true},
+	{"j", moveCursorDown, "Move cursor down", false},
+	{"k", moveCursorUp, "Move cursor up", false},
+	{"l", moveCursorRight, "Move cursor right", true},
+	{"w", moveToNextWordStart, "Move to next word", true},
+	{"b", moveToPrevWordStart, "Move to previous word", true},
+	{" ", moveCursorRightOrNextLine, "Move cursor right", true},
+	{"0", moveToStartOfLine, "Move to start of line", true},
+	{"^", moveToFirstNonWhitespace, "Move to first non-whitespace character", true},
+	{"$", moveToEndOfLine, "Move to end of line", false},
+	{"gg", moveToStartOfDocument, "Move to document start", false},
+	{"G", moveToEndOfDocument, "Move to document end", false},
+	{"gj", moveDisplayLineDown, "Move down one display line", false},
+	{"gk", moveDisplayLineUp, "Move up one display line", false},
+	{"g0", moveToDisplayLineStart, "Move to start of display line", true},
+	{"g$", moveToDisplayLineEnd, "Move to end of display line", false},
+	{"}", moveToNextParagraph, "Move to next paragraph", true},
+	{"{", moveToPrevParagraph, "Move to previous paragraph", true},
+	{"%", moveToMatchingBracket, "Move to matching bracket", false},
+	{"up", moveCursorUp, "Move cursor up", false},
+	{"down", moveCursorDown, "Move cursor down", false},
+	{"left", moveCursorLeft, "Move cursor left", true},
+	{"right", moveCursorRight, "Move cursor right", true},
+}
+
+// textObject selects whole lines around the cursor for an operator
+type textObject struct {
+	key   string
+	lines func(m *editorModel) (start, end int)
+	help  string
+}
+
+// lineTextObjects are the text objects accepted after a line operator
+var lineTextObjects = []textObject{
+	{"ip", innerParagraph, "inner paragraph"},
+	{"ap", aroundParagraph, "a paragraph"},
+}
+
+// motionLines runs a motion from the cursor and returns the lines it moved
+// over, leaving the cursor where it was. An exclusive motion that ends at
+// the start of a later line does not include that line.
+func (m *editorModel) motionLines(mo motion) (int, int) {
+	from := m.cursor
+	mo.command(m)
+	to := m.cursor
+	m.cursor = from
+
+	start, end := min(from.Row, to.Row), max(from.Row, to.Row)
+	if mo.exclusive && to.Row > from.Row && to.Col == 0 {
+		end--
+	}
+	return start, end
+}
+
+// blankLine reports whether a line is empty, which separates paragraphs
+func (m *editorModel) blankLine(row int) bool {
+	return m.buffer.lineLength(row) == 0
+}
+
+// moveToNextParagraph moves to the next empty line after a paragraph, or
+// to the end of the buffer
+func moveToNextParagraph(model *editorModel) tea.Cmd {
+	last := model.buffer.lineCount() - 1
+	withCountPrefix(model, func() {
+		row := model.cursor.Row
+		for row < last && model.blankLine(row) {
+			row++
+		}
+		for row < last && !model.blankLine(row) {
+			row++
+		}
+		model.cursor.Row = row
+		model.cursor.Col = 0
+		if !model.blankLine(row) {
+			model.cursor.Col = model.buffer.lineLength(row) - 1
+		}
+	})
+	model.desiredCol = model.cursor.Col
+	model.ensureCursorVisible()
+	return nil
+}
+
+// moveToPrevParagraph moves to the previous empty line before a paragraph,
+// or to the start of the buffer
+func moveToPrevParagraph(model *editorModel) tea.Cmd {
+	withCountPrefix(model, func() {
+		row := model.cursor.Row
+		for row > 0 && model.blankLine(row) {
+			row--
+		}
+		for row > 0 && !model.blankLine(row) {
+			row--
+		}
+		model.cursor = Cursor{row, 0}
+	})
+	model.desiredCol = 0
+	model.ensureCursorVisible()
+	return nil
+}
+
+// brackets maps each bracket to the one that matches it
+var brackets = map[byte]byte{'(': ')', '[': ']', '{': '}', ')': '(', ']': '[', '}': '{'}
+
+// moveToMatchingBracket finds the first bracket at or after the cursor on
+// its line and moves to the bracket matching it
+func moveToMatchingBracket(model *editorModel) tea.Cmd {
+	line := model.buffer.Line(model.cursor.Row)
+	col := model.cursor.Col
+	for col < len(line) && brackets[line[col]] == 0 {
+		col++
+	}
+	if col >= len(line) {
+		return nil
+	}
+
+	open := line[col]
+	step := 1
+	if strings.IndexByte(")]}", open) >= 0 {
+		step = -1
+	}
+	depth := 0
+	for row := model.cursor.Row; row >= 0 && row < model.buffer.lineCount(); row += step {
+		text := model.buffer.Line(row)
+		if row != model.cursor.Row {
+			col = 0
+			if step < 0 {
+				col = len(text) - 1
+			}
+		}
+		for ; col >= 0 && col < len(text); col += step {
+			switch text[col] {
+			case open:
+				depth++
+			case brackets[open]:
+				if depth--; depth == 0 {
+					model.cursor = Cursor{row, col}
+					model.desiredCol = col
+					model.ensureCursorVisible()
+					return nil
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// innerParagraph returns the lines of the paragraph, or of the run of empty
+// lines, at the cursor
+func innerParagraph(m *editorModel) (int, int) {
+	blank := m.blankLine(m.cursor.Row)
+	start, end := m.cursor.Row, m.cursor.Row
+	for start > 0 && m.blankLine(start-1) == blank {
+		start--
+	}
+	for end < m.buffer.lineCount()-1 && m.blankLine(end+1) == blank {
+		end++
+	}
+	return start, end
+}
+
+// aroundParagraph returns the paragraph at the cursor with the empty lines
+// after it, or before it when it ends the buffer
+func aroundParagraph(m *editorModel) (int, int) {
+	start, end := innerParagraph(m)
+	last := m.buffer.lineCount() - 1
+	if m.blankLine(start) {
+		for end < last && !m.blankLine(end+1) {
+			end++
+		}
+		return start, end
+	}
+	if end < last {
+		for end < last && m.blankLine(end+1) {
+			end++
+		}
+		return start, end
+	}
+	for start > 0 && m.blankLine(start-1) {
+		start--
+	}
+	return start, end
+}
@@ -0,0 +1,253 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// mouseTime is the longest time between the clicks of a double click
+	mouseTime = 500 * time.Millisecond
+	// wheelLines is the number of lines scrolled by a step of the wheel
+	wheelLines = 3
+	// wheelColumns is the number of columns scrolled by a horizontal step
+	// of the wheel
+	wheelColumns = 6
+)
+
+// mouseState tracks the presses of the left mouse button
+type mouseState struct {
+	dragging bool      // The last press was in the text and the button is held
+	lastTime time.Time // Time of the last press
+	x, y     int       // Cell of the last press in the editor
+	clicks   int       // Presses in a row at the same cell
+}
+
+// SetOffset sets the screen cell of the top left corner of the editor
+// when it is embedded in a larger layout. Mouse events are relative to
+// the screen and are mapped to the editor through it.
+func (m *editorModel) SetOffset(x, y int) {
+	m.offsetX, m.offsetY = x, y
+}
+
+// handleMouse handles a mouse event: the left button positions the
+// cursor, selects by dragging and selects words and lines with double and
+// triple clicks, and the wheel scrolls the window under the pointer. Only
+// motion with the button held is used, as reported in cell motion mode.
+func (m *editorModel) handleMouse(msg tea.MouseMsg) tea.Cmd {
+	if m.mode == ModeCommand {
+		return nil
+	}
+	x, y := msg.X-m.offsetX, msg.Y-m.offsetY
+	switch {
+	case tea.MouseEvent(msg).IsWheel():
+		m.mouseWheel(x, y, msg.Button)
+	case msg.Button != tea.MouseButtonLeft:
+	case msg.Action == tea.MouseActionPress:
+		m.closeFloatsOnKey()
+		return m.mousePress(x, y)
+	case msg.Action == tea.MouseActionMotion:
+		return m.mouseDrag(x, y)
+	case msg.Action == tea.MouseActionRelease:
+		m.mouse.dragging = false
+	}
+	return nil
+}
+
+// mousePress handles a press of the left button at a cell of the editor
+func (m *editorModel) mousePress(x, y int) tea.Cmd {
+	now := time.Now()
+	if x == m.mouse.x && y == m.mouse.y && now.Sub(m.mouse.lastTime) <= mouseTime {
+		m.mouse.clicks++
+	} else {
+		m.mouse.clicks = 1
+	}
+	m.mouse.x, m.mouse.y, m.mouse.lastTime = x, y, now
+	m.mouse.dragging = false
+
+	if y < m.tablineHeight() {
+		m.clickTabline(x)
+		return nil
+	}
+	w, status := m.windowAt(y, x)
+	if w == nil {
+		return nil
+	}
+	m.enterWindow(w)
+	if status {
+		return nil
+	}
+	m.mouse.dragging = true
+	row, col := m.windowOrigin()
+	m.cursor = m.mousePosition(y-row, x-col)
+	m.adjustCursorPosition()
+	m.desiredCol = m.cursor.Col
+
+	switch (m.mouse.clicks - 1) % 3 {
+	case 1:
+		// Double click selects the word
+		start, end := getWordBoundary(m)
+		cmd := beginVisualSelection(m)
+		m.visualStart = Cursor{m.cursor.Row, start}
+		m.cursor.Col = max(start, end-1)
+		m.desiredCol = m.cursor.Col
+		return cmd
+	case 2:
+		// Triple click selects the line
+		return beginVisualLineSelection(m)
+	}
+	if m.mode == ModeVisual {
+		return switchMode(m, ModeNormal)
+	}
+	return nil
+}
+
+// mouseDrag extends the selection to the cell under the pointer while the
+// left button is held, scrolling when the pointer leaves the window
+func (m *editorModel) mouseDrag(x, y int) tea.Cmd {
+	if !m.mouse.dragging {
+		return nil
+	}
+	row, col := m.windowOrigin()
+	row, col = y-row, x-col
+	if row < 0 {
+		m.scrollLines(-1)
+		row = 0
+	} else if row >= m.height {
+		m.scrollLines(1)
+		row = m.height - 1
+	}
+	start := m.cursor
+	start.Col = min(start.Col, max(0, m.buffer.lineLength(start.Row)-1))
+	m.cursor = m.mousePosition(row, max(0, min(col, m.width-1)))
+	m.adjustCursorPosition()
+	m.desiredCol = m.cursor.Col
+	if m.mode == ModeVisual || m.cursor == start {
+		return nil
+	}
+	cmd := beginVisualSelection(m)
+	m.visualStart = start
+	return cmd
+}
+
+// mouseWheel scrolls the window under the pointer without moving the focus
+func (m *editorModel) mouseWheel(x, y int, button tea.MouseButton) {
+	w, _ := m.windowAt(y, x)
+	if w == nil {
+		return
+	}
+	current := m.win
+	if w != current {
+		m.storeWindow()
+		m.loadWindow(w)
+	}
+	switch button {
+	case tea.MouseButtonWheelUp:
+		m.scrollLines(-wheelLines)
+	case tea.MouseButtonWheelDown:
+		m.scrollLines(wheelLines)
+	case tea.MouseButtonWheelLeft:
+		m.scrollHorizontal(-wheelColumns)
+	case tea.MouseButtonWheelRight:
+		m.scrollHorizontal(wheelColumns)
+	}
+	if w != current {
+		m.storeWindow()
+		m.loadWindow(current)
+	}
+}
+
+// windowAt returns the window at a cell of the editor, and whether the
+// cell is on its status line, or nil
+func (m *editorModel) windowAt(row, col int) (*window, bool) {
+	if !m.sharesArea() {
+		if row >= 0 && row < m.height && col >= 0 && col < m.width {
+			return m.win, false
+		}
+		return nil, false
+	}
+	for _, w := range m.windows() {
+		if col < w.col || col >= w.col+w.width || row < w.row {
+			continue
+		}
+		if row < w.row+w.height {
+			return w, false
+		}
+		if row == w.row+w.height && m.hasSplits() {
+			return w, true
+		}
+	}
+	return nil, false
+}
+
+// mousePosition maps a cell of the current window, relative to its top
+// left corner, to the buffer position drawn there. Cells in the gutter map
+// to the start of their row and cells after the end of a row to its last
+// character.
+func (m *editorModel) mousePosition(row, col int) Cursor {
+	last := m.buffer.lineCount() - 1
+	pos := Cursor{min(max(m.viewport.YOffset, 0), last), 0}
+	rows := m.displayLines(pos.Row)
+	part := 0
+	for ; row > 0; row-- {
+		if part+1 < len(rows) {
+			part++
+		} else if pos.Row < last {
+			pos.Row++
+			rows = m.displayLines(pos.Row)
+			part = 0
+		} else {
+			break
+		}
+	}
+
+	line := m.buffer.Line(pos.Row)
+	col -= m.gutterWidth()
+	if part > 0 {
+		col -= lipgloss.Width(m.breakPrefix(line))
+	}
+	r := rows[part]
+	visual := r.start + max(col, 0)
+	if !m.wrap {
+		visual += m.xOffset
+	}
+	if part < len(rows)-1 {
+		visual = min(visual, max(r.end-1, r.start))
+	}
+	pos.Col = visualToBufferPosition(line, visual, m.buffer.tabs.tabStop)
+	return pos
+}
+
+// scrollLines scrolls the view count lines down, or up when count is
+// negative, and moves the cursor if it would leave the screen
+func (m *editorModel) scrollLines(count int) {
+	m.viewport.YOffset = max(0, min(m.viewport.YOffset+count, m.buffer.lineCount()-1))
+	row := m.cursor.Row
+	if m.cursor.Row < m.viewport.YOffset {
+		m.cursor.Row = m.viewport.YOffset
+	} else if last := m.lastVisibleRow(); m.cursor.Row > last {
+		m.cursor.Row = last
+	}
+	if m.cursor.Row != row {
+		m.cursor.Col = m.desiredCol
+		m.adjustCursorPosition()
+	}
+}
+
+// lastVisibleRow returns the last buffer line that is fully on screen
+func (m *editorModel) lastVisibleRow() int {
+	rows := 0
+	for row := m.viewport.YOffset; row < m.buffer.lineCount(); row++ {
+		rows += len(m.displayLines(row))
+		if rows > m.height {
+			return max(row-1, m.viewport.YOffset)
+		}
+		if rows == m.height {
+			return row
+		}
+	}
+	return m.buffer.lineCount() - 1
+}
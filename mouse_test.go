@@ -0,0 +1,134 @@
+package vimtea
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sendMouse sends a mouse event at a cell of the screen
+func sendMouse(model *editorModel, x, y int, button tea.MouseButton, action tea.MouseAction) {
+	model.Update(tea.MouseMsg{X: x, Y: y, Button: button, Action: action})
+}
+
+// click presses and releases the left button at a cell of the screen
+func click(model *editorModel, x, y int) {
+	sendMouse(model, x, y, tea.MouseButtonLeft, tea.MouseActionPress)
+	sendMouse(model, x, y, tea.MouseButtonLeft, tea.MouseActionRelease)
+}
+
+func TestMouseClick(t *testing.T) {
+	model := NewEditor(WithContent("one\n\tx = 1\nthree"), WithRelativeNumbers(false)).(*editorModel)
+	model.width, model.height = 30, 5
+	gutter := model.gutterWidth()
+	require.Positive(t, gutter)
+
+	click(model, gutter+2, 2)
+	assert.Equal(t, Cursor{2, 2}, model.cursor, "A click should position the cursor after the gutter")
+	click(model, gutter+4, 1)
+	assert.Equal(t, Cursor{1, 1}, model.cursor, "Columns should be mapped through tab expansion")
+	click(model, gutter+20, 0)
+	assert.Equal(t, Cursor{0, 2}, model.cursor, "A click after the end of a line should go to its last character")
+	click(model, 0, 4)
+	assert.Equal(t, Cursor{2, 0}, model.cursor, "A click below the text should go to the last line")
+
+	model.SetOffset(10, 3)
+	click(model, 10+gutter+1, 3+1)
+	assert.Equal(t, Cursor{1, 0}, model.cursor, "Clicks should be mapped through the offset of the editor")
+	click(model, 2, 1)
+	assert.Equal(t, Cursor{1, 0}, model.cursor, "Clicks outside the editor should be ignored")
+
+	sendKeys(model, "i")
+	click(model, 10+gutter+20, 3+2)
+	assert.Equal(t, Cursor{2, 5}, model.cursor, "Insert mode should allow the position after the line")
+	assert.Equal(t, ModeInsert, model.mode)
+}
+
+func TestMouseSelect(t *testing.T) {
+	model := newWrapEditor("foo bar baz\nsecond line\nthird", 30, 5)
+
+	sendMouse(model, 1, 0, tea.MouseButtonLeft, tea.MouseActionPress)
+	sendMouse(model, 3, 1, tea.MouseButtonLeft, tea.MouseActionMotion)
+	sendMouse(model, 3, 1, tea.MouseButtonLeft, tea.MouseActionRelease)
+	assert.Equal(t, ModeVisual, model.mode, "Dragging should start a visual selection")
+	start, end := model.GetSelectionBoundary()
+	assert.Equal(t, Cursor{0, 1}, start)
+	assert.Equal(t, Cursor{1, 3}, end)
+
+	sendMouse(model, 5, 2, tea.MouseButtonNone, tea.MouseActionMotion)
+	assert.Equal(t, Cursor{1, 3}, model.cursor, "Motion without a button held should be ignored")
+
+	click(model, 5, 0)
+	assert.Equal(t, ModeNormal, model.mode, "A click should end the selection")
+	click(model, 5, 0)
+	assert.Equal(t, ModeVisual, model.mode)
+	start, end = model.GetSelectionBoundary()
+	assert.Equal(t, Cursor{0, 4}, start, "A double click should select the word")
+	assert.Equal(t, Cursor{0, 6}, end)
+
+	click(model, 5, 0)
+	assert.True(t, model.isVisualLine, "A triple click should select the line")
+	start, end = model.GetSelectionBoundary()
+	assert.Equal(t, Cursor{0, 0}, start)
+	assert.Equal(t, Cursor{0, 10}, end)
+}
+
+func TestMouseWheel(t *testing.T) {
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = strings.Repeat("x", 30)
+	}
+	model := newWrapEditor(strings.Join(lines, "\n"), 10, 5, WithWrap(false))
+
+	sendMouse(model, 0, 0, tea.MouseButtonWheelDown, tea.MouseActionPress)
+	assert.Equal(t, 3, model.viewport.YOffset, "The wheel should scroll three lines")
+	assert.Equal(t, 3, model.cursor.Row, "The cursor should stay on screen")
+	sendMouse(model, 0, 0, tea.MouseButtonWheelUp, tea.MouseActionPress)
+	assert.Equal(t, 0, model.viewport.YOffset)
+	assert.Equal(t, 3, model.cursor.Row, "The cursor should not move while it is on screen")
+
+	click(model, 3, 4)
+	sendMouse(model, 3, 4, tea.MouseButtonWheelRight, tea.MouseActionPress)
+	assert.Equal(t, 6, model.xOffset, "The horizontal wheel should scroll columns")
+	click(model, 1, 4)
+	assert.Equal(t, Cursor{4, 7}, model.cursor, "Clicks should be mapped through the horizontal scroll")
+}
+
+func TestMouseWrap(t *testing.T) {
+	model := newWrapEditor("aaaa bbbb cccc\nnext", 6, 5, WithWrap(true), WithShowBreak("> "))
+
+	click(model, 3, 1)
+	assert.Equal(t, Cursor{0, 7}, model.cursor, "Wrapped rows should be mapped after the showbreak marker")
+	click(model, 5, 0)
+	assert.Equal(t, Cursor{0, 5}, model.cursor)
+	click(model, 0, 3)
+	assert.Equal(t, Cursor{1, 0}, model.cursor)
+}
+
+func TestMouseWindows(t *testing.T) {
+	model := newWrapEditor("one\ntwo\nthree\nfour", 41, 10)
+	first := model.CurrentWindow()
+	second := model.SplitWindow(true)
+	model.SetCursor(Cursor{1, 0})
+
+	click(model, 22, 2)
+	assert.Equal(t, first, model.CurrentWindow(), "A click should focus the window under the pointer")
+	assert.Equal(t, Cursor{2, 1}, model.cursor)
+
+	sendMouse(model, 3, 0, tea.MouseButtonWheelDown, tea.MouseActionPress)
+	assert.Equal(t, first, model.CurrentWindow(), "The wheel should not move the focus")
+	assert.Equal(t, 3, model.Windows()[0].Cursor.Row, "The wheel should scroll the window under the pointer")
+
+	click(model, 3, 9)
+	assert.Equal(t, second, model.CurrentWindow(), "A click on a status line should focus its window")
+	assert.Equal(t, Cursor{3, 0}, model.cursor)
+
+	runCommand(t, model, "tabnew")
+	click(model, 3, 2)
+	assert.Equal(t, Cursor{0, 0}, model.cursor, "Rows should be mapped below the tabline")
+	click(model, 2, 0)
+	assert.Equal(t, 1, model.CurrentTab())
+}
@@ -0,0 +1,533 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// OptionType is the value type of an editor option
+type OptionType int
+
+const (
+	// OptionBool options hold a bool and are toggled with :set name / :set noname
+	OptionBool OptionType = iota
+	// OptionInt options hold an int
+	OptionInt
+	// OptionString options hold a string
+	OptionString
+	// OptionList options hold a []string, written as a comma separated list
+	OptionList
+)
+
+// OptionScope determines whether an option applies to the whole editor or
+// to a single buffer
+type OptionScope int
+
+const (
+	// ScopeGlobal options have one value for the whole editor
+	ScopeGlobal OptionScope = iota
+	// ScopeBuffer options have a separate value for every buffer
+	ScopeBuffer
+)
+
+// OptionDef describes an option that can be changed with :set and SetOption
+type OptionDef struct {
+	Name      string          // Full option name, e.g. "tabstop"
+	ShortName string          // Optional abbreviation, e.g. "ts"
+	Type      OptionType      // Value type
+	Scope     OptionScope     // Global or buffer-local
+	Default   any             // Default value, of the Go type matching Type
+	Validate  func(any) error // Optional check run before a new value is stored
+
+	get func(m *editorModel) any    // Reads options backed by editor state
+	set func(m *editorModel, v any) // Writes options backed by editor state
+}
+
+// OptionChangedMsg is sent after an option value has changed
+type OptionChangedMsg struct {
+	Name     string      // Full option name
+	Scope    OptionScope // Scope of the option
+	OldValue any         // Value before the change
+	NewValue any         // Value after the change
+}
+
+// OptionRegistry stores option definitions and global option values
+type OptionRegistry struct {
+	defs    map[string]*OptionDef // Definitions by full name
+	aliases map[string]string     // Short names to full names
+	values  map[string]any        // Global values that differ from the default
+}
+
+// newOptionRegistry creates a new empty option registry
+func newOptionRegistry() *OptionRegistry {
+	return &OptionRegistry{
+		defs:    make(map[string]*OptionDef),
+		aliases: make(map[string]string),
+		values:  make(map[string]any),
+	}
+}
+
+// Define registers a new option
+func (r *OptionRegistry) Define(def OptionDef) error {
+	if def.Name == "" {
+		return fmt.Errorf("option name is required")
+	}
+	if _, err := coerceOption(&def, def.Default); err != nil {
+		return fmt.Errorf("invalid default for %s: %w", def.Name, err)
+	}
+	r.defs[def.Name] = &def
+	if def.ShortName != "" {
+		r.aliases[def.ShortName] = def.Name
+	}
+	return nil
+}
+
+// Lookup finds an option definition by full or short name
+func (r *OptionRegistry) Lookup(name string) *OptionDef {
+	if full, ok := r.aliases[name]; ok {
+		name = full
+	}
+	return r.defs[name]
+}
+
+// Names returns the full names of all options in alphabetical order
+func (r *OptionRegistry) Names() []string {
+	names := make([]string, 0, len(r.defs))
+	for name := range r.defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// registerOptions defines the built-in options
+func registerOptions(m *editorModel) {
+	for _, def := range []OptionDef{
+		{
+			Name: "number", ShortName: "nu", Type: OptionBool, Default: true,
+			get: func(m *editorModel) any { return m.showNumbers },
+			set: func(m *editorModel, v any) { m.showNumbers = v.(bool) },
+		},
+		{
+			Name: "relativenumber", ShortName: "rnu", Type: OptionBool, Default: false,
+			get: func(m *editorModel) any { return m.relativeNumbers },
+			set: func(m *editorModel, v any) { m.relativeNumbers = v.(bool) },
+		},
+		{
+			Name: "wrap", Type: OptionBool, Default: true,
+			get: func(m *editorModel) any { return m.wrap },
+			set: func(m *editorModel, v any) {
+				m.wrap = v.(bool)
+				m.ensureCursorVisibleX()
+			},
+		},
+		{
+			Name: "linebreak", ShortName: "lbr", Type: OptionBool, Default: false,
+			get: func(m *editorModel) any { return m.lineBreak },
+			set: func(m *editorModel, v any) { m.lineBreak = v.(bool) },
+		},
+		{
+			Name: "breakindent", ShortName: "bri", Type: OptionBool, Default: false,
+			get: func(m *editorModel) any { return m.breakIndent },
+			set: func(m *editorModel, v any) { m.breakIndent = v.(bool) },
+		},
+		{
+			Name: "showbreak", ShortName: "sbr", Type: OptionString, Default: "",
+			get: func(m *editorModel) any { return m.showBreak },
+			set: func(m *editorModel, v any) { m.showBreak = v.(string) },
+		},
+		{
+			Name: "sidescrolloff", ShortName: "siso", Type: OptionInt, Default: 0,
+			Validate: validateNotNegative,
+			get:      func(m *editorModel) any { return m.sideScrollOff },
+			set:      func(m *editorModel, v any) { m.sideScrollOff = v.(int) },
+		},
+		{
+			Name: "signcolumn", ShortName: "scl", Type: OptionString, Default: "auto",
+			Validate: func(v any) error { _, _, err := parseSignColumn(v.(string)); return err },
+			get:      func(m *editorModel) any { return m.signColumn },
+			set:      func(m *editorModel, v any) { m.signColumn = v.(string) },
+		},
+		{
+			Name: "updatetime", ShortName: "ut", Type: OptionInt, Default: int(defaultUpdateTime / time.Millisecond),
+			Validate: validatePositive,
+			get:      func(m *editorModel) any { return int(m.updateTime / time.Millisecond) },
+			set:      func(m *editorModel, v any) { m.updateTime = time.Duration(v.(int)) * time.Millisecond },
+		},
+		{
+			Name: "fileformat", ShortName: "ff", Type: OptionString, Scope: ScopeBuffer, Default: string(FileFormatUnix),
+			Validate: func(v any) error { _, err := parseFileFormat(v.(string)); return err },
+			get:      func(m *editorModel) any { return string(m.buffer.fileFormat) },
+			set: func(m *editorModel, v any) {
+				m.buffer.fileFormat = FileFormat(v.(string))
+				m.buffer.touch()
+			},
+		},
+		{
+			Name: "filetype", ShortName: "ft", Type: OptionString, Scope: ScopeBuffer, Default: "",
+			get: func(m *editorModel) any { return m.highlighter.fileType() },
+			set: func(m *editorModel, v any) { m.highlighter.setLanguage(v.(string)) },
+		},
+		{
+			Name: "fileencoding", ShortName: "fenc", Type: OptionString, Scope: ScopeBuffer, Default: EncodingUTF8,
+			Validate: func(v any) error { _, err := lookupEncoding(v.(string)); return err },
+			get:      func(m *editorModel) any { return m.buffer.encoding },
+			set: func(m *editorModel, v any) {
+				m.buffer.encoding = canonicalEncoding(v.(string))
+				m.buffer.touch()
+			},
+		},
+		{
+			Name: "bomb", Type: OptionBool, Scope: ScopeBuffer, Default: false,
+			get: func(m *editorModel) any { return m.buffer.bom },
+			set: func(m *editorModel, v any) {
+				m.buffer.bom = v.(bool)
+				m.buffer.touch()
+			},
+		},
+		{
+			Name: "autoindent", ShortName: "ai", Type: OptionBool, Default: true,
+			get: func(m *editorModel) any { return m.autoIndent },
+			set: func(m *editorModel, v any) { m.autoIndent = v.(bool) },
+		},
+		{
+			Name: "smartindent", ShortName: "si", Type: OptionBool, Default: false,
+			get: func(m *editorModel) any { return m.smartIndent },
+			set: func(m *editorModel, v any) { m.smartIndent = v.(bool) },
+		},
+		{
+			Name: "tabstop", ShortName: "ts", Type: OptionInt, Scope: ScopeBuffer, Default: defaultTabStop,
+			Validate: validatePositive,
+			get:      func(m *editorModel) any { return m.buffer.tabs.tabStop },
+			set:      func(m *editorModel, v any) { m.buffer.tabs.tabStop = v.(int) },
+		},
+		{
+			Name: "shiftwidth", ShortName: "sw", Type: OptionInt, Scope: ScopeBuffer, Default: 0,
+			Validate: validateNotNegative,
+			get:      func(m *editorModel) any { return m.buffer.tabs.shiftWidth },
+			set:      func(m *editorModel, v any) { m.buffer.tabs.shiftWidth = v.(int) },
+		},
+		{
+			Name: "softtabstop", ShortName: "sts", Type: OptionInt, Scope: ScopeBuffer, Default: 0,
+			Validate: validateNotNegative,
+			get:      func(m *editorModel) any { return m.buffer.tabs.softTabStop },
+			set:      func(m *editorModel, v any) { m.buffer.tabs.softTabStop = v.(int) },
+		},
+		{
+			Name: "expandtab", ShortName: "et", Type: OptionBool, Scope: ScopeBuffer, Default: false,
+			get: func(m *editorModel) any { return m.buffer.tabs.expandTab },
+			set: func(m *editorModel, v any) { m.buffer.tabs.expandTab = v.(bool) },
+		},
+	} {
+		_ = m.options.Define(def)
+	}
+}
+
+// validatePositive rejects int option values below one
+func validatePositive(v any) error {
+	if v.(int) < 1 {
+		return fmt.Errorf("E487: Argument must be positive")
+	}
+	return nil
+}
+
+// validateNotNegative rejects int option values below zero
+func validateNotNegative(v any) error {
+	if v.(int) < 0 {
+		return fmt.Errorf("E487: Argument must be positive")
+	}
+	return nil
+}
+
+// DefineOption registers a custom option that can then be changed with :set
+func (m *editorModel) DefineOption(def OptionDef) error {
+	return m.options.Define(def)
+}
+
+// GetOption returns the current value of an option. Buffer-local options
+// are read from the current buffer.
+func (m *editorModel) GetOption(name string) (any, error) {
+	def := m.options.Lookup(name)
+	if def == nil {
+		return nil, fmt.Errorf("E518: Unknown option: %s", name)
+	}
+	return m.optionValue(def), nil
+}
+
+// SetOption changes the value of an option. Buffer-local options are set on
+// the current buffer. An OptionChangedMsg is delivered with the next update
+// and OptionSet autocommands run immediately.
+func (m *editorModel) SetOption(name string, value any) error {
+	def := m.options.Lookup(name)
+	if def == nil {
+		return fmt.Errorf("E518: Unknown option: %s", name)
+	}
+	v, err := coerceOption(def, value)
+	if err != nil {
+		return err
+	}
+	if def.Validate != nil {
+		if err := def.Validate(v); err != nil {
+			return err
+		}
+	}
+
+	old := m.optionValue(def)
+	switch {
+	case def.set != nil:
+		def.set(m, v)
+	case def.Scope == ScopeBuffer:
+		if m.buffer.options == nil {
+			m.buffer.options = make(map[string]any)
+		}
+		m.buffer.options[def.Name] = v
+	default:
+		m.options.values[def.Name] = v
+	}
+
+	if optionEqual(old, v) {
+		return nil
+	}
+	msg := OptionChangedMsg{Name: def.Name, Scope: def.Scope, OldValue: old, NewValue: v}
+	m.pendingCmds = append(m.pendingCmds, func() tea.Msg { return msg })
+	m.fireAutocmd(EventOptionSet, AutocmdArgs{Option: msg})
+	return nil
+}
+
+// optionValue returns the current value of an option
+func (m *editorModel) optionValue(def *OptionDef) any {
+	if def.get != nil {
+		return def.get(m)
+	}
+	if def.Scope == ScopeBuffer {
+		if v, ok := m.buffer.options[def.Name]; ok {
+			return v
+		}
+	} else if v, ok := m.options.values[def.Name]; ok {
+		return v
+	}
+	return def.Default
+}
+
+// optionInt returns the value of an int option, or 0 if it is not defined
+func (m *editorModel) optionInt(name string) int {
+	v, _ := m.GetOption(name)
+	n, _ := v.(int)
+	return n
+}
+
+// optionBool returns the value of a bool option, or false if it is not defined
+func (m *editorModel) optionBool(name string) bool {
+	v, _ := m.GetOption(name)
+	b, _ := v.(bool)
+	return b
+}
+
+// optionString returns the value of a string option, or "" if it is not defined
+func (m *editorModel) optionString(name string) string {
+	v, _ := m.GetOption(name)
+	s, _ := v.(string)
+	return s
+}
+
+// coerceOption converts a value to the Go type of the option
+func coerceOption(def *OptionDef, value any) (any, error) {
+	invalid := fmt.Errorf("E474: Invalid argument: %s=%v", def.Name, value)
+	switch def.Type {
+	case OptionBool:
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	case OptionInt:
+		if v, ok := value.(int); ok {
+			return v, nil
+		}
+	case OptionString:
+		if v, ok := value.(string); ok {
+			return v, nil
+		}
+	case OptionList:
+		switch v := value.(type) {
+		case []string:
+			return slices.Clone(v), nil
+		case string:
+			return splitOptionList(v), nil
+		}
+	}
+	return nil, invalid
+}
+
+// optionEqual compares two option values
+func optionEqual(a, b any) bool {
+	if la, ok := a.([]string); ok {
+		lb, ok := b.([]string)
+		return ok && slices.Equal(la, lb)
+	}
+	return a == b
+}
+
+// splitOptionList parses a comma separated list option value
+func splitOptionList(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
+
+// formatOption renders an option the way :set displays it
+func formatOption(def *OptionDef, value any) string {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return "  " + def.Name
+		}
+		return "no" + def.Name
+	case []string:
+		return def.Name + "=" + strings.Join(v, ",")
+	default:
+		return fmt.Sprintf("%s=%v", def.Name, v)
+	}
+}
+
+// setCommand implements :set. Each argument has one of the forms
+// name, noname, invname, name!, name?, name&, name=value, name:value,
+// name+=value, name-=value or name^=value. Without arguments it lists
+// the options that differ from their defaults.
+func setCommand(model *editorModel) tea.Cmd {
+	_, args := model.commandLine()
+	var shown []string
+
+	if len(args) == 0 {
+		for _, name := range model.options.Names() {
+			def := model.options.Lookup(name)
+			if value := model.optionValue(def); !optionEqual(value, def.Default) {
+				shown = append(shown, formatOption(def, value))
+			}
+		}
+	}
+
+	for _, arg := range args {
+		display, err := model.applySetArgument(arg)
+		if err != nil {
+			return SetStatusMsg(err.Error())
+		}
+		if display != "" {
+			shown = append(shown, display)
+		}
+	}
+
+	if len(shown) > 0 {
+		return SetStatusMsg(strings.Join(shown, " "))
+	}
+	return nil
+}
+
+// applySetArgument applies a single :set argument and returns the text to
+// display, if the argument was a query
+func (m *editorModel) applySetArgument(arg string) (string, error) {
+	// Split off the operator and value
+	name, op, value := arg, "", ""
+	if i := strings.IndexAny(arg, "=:"); i > 0 {
+		name, op, value = arg[:i], "=", arg[i+1:]
+		if last := name[len(name)-1]; last == '+' || last == '-' || last == '^' {
+			name, op = name[:len(name)-1], string(last)+"="
+		}
+	}
+
+	suffix := ""
+	if op == "" && (strings.HasSuffix(name, "?") || strings.HasSuffix(name, "&") || strings.HasSuffix(name, "!")) {
+		name, suffix = name[:len(name)-1], name[len(name)-1:]
+	}
+
+	def := m.options.Lookup(name)
+	prefix := ""
+	if def == nil && op == "" && suffix != "?" {
+		for _, p := range []string{"no", "inv"} {
+			if d := m.options.Lookup(strings.TrimPrefix(name, p)); strings.HasPrefix(name, p) && d != nil && d.Type == OptionBool {
+				def, prefix = d, p
+				break
+			}
+		}
+	}
+	if def == nil {
+		return "", fmt.Errorf("E518: Unknown option: %s", name)
+	}
+
+	current := m.optionValue(def)
+	switch {
+	case suffix == "?" || (op == "" && suffix == "" && prefix == "" && def.Type != OptionBool):
+		return formatOption(def, current), nil
+	case suffix == "&":
+		return "", m.SetOption(def.Name, def.Default)
+	case def.Type == OptionBool:
+		if op != "" {
+			return "", fmt.Errorf("E474: Invalid argument: %s", arg)
+		}
+		switch {
+		case suffix == "!" || prefix == "inv":
+			return "", m.SetOption(def.Name, !current.(bool))
+		default:
+			return "", m.SetOption(def.Name, prefix != "no")
+		}
+	}
+
+	newValue, err := parseOptionValue(def, current, op, value)
+	if err != nil {
+		return "", err
+	}
+	return "", m.SetOption(def.Name, newValue)
+}
+
+// parseOptionValue computes the new value of a non-bool option from a :set operator
+func parseOptionValue(def *OptionDef, current any, op, value string) (any, error) {
+	invalid := fmt.Errorf("E474: Invalid argument: %s%s%s", def.Name, op, value)
+
+	switch def.Type {
+	case OptionInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("E521: Number required after =: %s%s%s", def.Name, op, value)
+		}
+		switch op {
+		case "+=":
+			return current.(int) + n, nil
+		case "-=":
+			return current.(int) - n, nil
+		case "^=":
+			return current.(int) * n, nil
+		}
+		return n, nil
+
+	case OptionString:
+		switch op {
+		case "+=":
+			return current.(string) + value, nil
+		case "-=":
+			return strings.Replace(current.(string), value, "", 1), nil
+		case "^=":
+			return value + current.(string), nil
+		}
+		return value, nil
+
+	case OptionList:
+		list := current.([]string)
+		items := splitOptionList(value)
+		switch op {
+		case "+=":
+			return append(slices.Clone(list), items...), nil
+		case "-=":
+			return slices.DeleteFunc(slices.Clone(list), func(s string) bool { return slices.Contains(items, s) }), nil
+		case "^=":
+			return append(items, list...), nil
+		}
+		return items, nil
+	}
+	return nil, invalid
+}
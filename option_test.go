@@ -0,0 +1,110 @@
+package vimtea
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetBoolOptions(t *testing.T) {
+	model := NewEditor(WithContent("one\ntwo")).(*editorModel)
+	assert.True(t, model.showNumbers, "Line numbers should be shown by default")
+
+	runCommand(t, model, "set nonumber")
+	assert.False(t, model.showNumbers, ":set nonumber should hide line numbers")
+	assert.NotContains(t, model.renderContent(), "   1", "Gutter should be hidden")
+
+	runCommand(t, model, "set nu")
+	assert.True(t, model.showNumbers, "Short names should be accepted")
+
+	runCommand(t, model, "set invrnu")
+	assert.True(t, model.relativeNumbers, "inv prefix should toggle the option")
+	runCommand(t, model, "set relativenumber!")
+	assert.False(t, model.relativeNumbers, "! suffix should toggle the option")
+
+	msgs := runCommand(t, model, "set number? rnu?")
+	assert.Contains(t, msgs, tea.Msg(statusMessageMsg("  number norelativenumber")))
+}
+
+func TestSetIntOptions(t *testing.T) {
+	model := NewEditor().(*editorModel)
+
+	runCommand(t, model, "set ut=250")
+	assert.Equal(t, 250*time.Millisecond, model.updateTime, ":set ut=250 should change the update time")
+
+	runCommand(t, model, "set updatetime+=50")
+	assert.Equal(t, 300*time.Millisecond, model.updateTime, "+= should add to the value")
+
+	msgs := runCommand(t, model, "set ut")
+	assert.Contains(t, msgs, tea.Msg(statusMessageMsg("updatetime=300")), "Naming a non-bool option should show it")
+
+	msgs = runCommand(t, model, "set ut=0")
+	assert.Contains(t, msgs, tea.Msg(statusMessageMsg("E487: Argument must be positive")))
+	msgs = runCommand(t, model, "set ut=fast")
+	assert.Contains(t, msgs, tea.Msg(statusMessageMsg("E521: Number required after =: updatetime=fast")))
+
+	runCommand(t, model, "set ut&")
+	assert.Equal(t, defaultUpdateTime, model.updateTime, "& should restore the default")
+
+	msgs = runCommand(t, model, "set nosuchoption")
+	assert.Contains(t, msgs, tea.Msg(statusMessageMsg("E518: Unknown option: nosuchoption")))
+}
+
+func TestCustomOptions(t *testing.T) {
+	model := NewEditor().(*editorModel)
+	require.NoError(t, model.DefineOption(OptionDef{
+		Name: "suffixes", ShortName: "su", Type: OptionList, Default: []string{".bak"},
+	}))
+	require.NoError(t, model.DefineOption(OptionDef{
+		Name: "textwidth", ShortName: "tw", Type: OptionInt, Scope: ScopeBuffer, Default: 0,
+	}))
+	assert.Error(t, model.DefineOption(OptionDef{Name: "bad", Type: OptionInt, Default: "x"}),
+		"Default must match the option type")
+
+	runCommand(t, model, "set su+=.o,.tmp")
+	value, err := model.GetOption("suffixes")
+	require.NoError(t, err)
+	assert.Equal(t, []string{".bak", ".o", ".tmp"}, value)
+
+	runCommand(t, model, "set su-=.bak")
+	value, _ = model.GetOption("su")
+	assert.Equal(t, []string{".o", ".tmp"}, value)
+
+	runCommand(t, model, "set tw=80")
+	assert.Equal(t, 80, model.optionInt("textwidth"))
+	assert.Equal(t, 80, model.buffer.options["textwidth"], "Buffer-local values should live on the buffer")
+
+	msgs := runCommand(t, model, "set")
+	assert.Contains(t, msgs, tea.Msg(statusMessageMsg("suffixes=.o,.tmp textwidth=80")),
+		":set without arguments should list changed options")
+}
+
+func TestSetOptionAPI(t *testing.T) {
+	model := NewEditor().(*editorModel)
+
+	var changes []OptionChangedMsg
+	model.AddAutocmd(EventOptionSet, "*", func(_ Buffer, args AutocmdArgs) tea.Cmd {
+		changes = append(changes, args.Option)
+		return nil
+	})
+
+	require.NoError(t, model.SetOption("relativenumber", true))
+	assert.True(t, model.relativeNumbers)
+	expected := OptionChangedMsg{Name: "relativenumber", Scope: ScopeGlobal, OldValue: false, NewValue: true}
+	assert.Equal(t, []OptionChangedMsg{expected}, changes, "OptionSet should fire with the change")
+	assert.Contains(t, collectMsgs(model.flushPendingCmds()), tea.Msg(expected), "OptionChangedMsg should be sent")
+
+	require.NoError(t, model.SetOption("rnu", true))
+	assert.Len(t, changes, 1, "Setting the same value should not fire OptionSet")
+
+	assert.Error(t, model.SetOption("number", 1), "Values of the wrong type should be rejected")
+	assert.Error(t, model.SetOption("missing", true), "Unknown options should be rejected")
+	_, err := model.GetOption("missing")
+	assert.Error(t, err)
+
+	require.NoError(t, model.SetOption("ff", "dos"))
+	assert.Equal(t, FileFormatDOS, model.buffer.fileFormat, "Buffer options should update the buffer")
+}
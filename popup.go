@@ -0,0 +1,28 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import "github.com/charmbracelet/lipgloss"
+
+// showPopup opens a bordered float with the given content lines until the
+// next key press, next to the cursor or at the bottom of the text area.
+// It replaces the popup that is already open.
+func (m *editorModel) showPopup(lines []string, atCursor bool) {
+	m.CloseFloat(m.popup)
+	opts := FloatOptions{
+		Anchor:     AnchorEditor,
+		Row:        -1,
+		Border:     lipgloss.RoundedBorder(),
+		ZIndex:     popupZIndex,
+		CloseOnKey: true,
+	}
+	if atCursor {
+		opts.Anchor, opts.Row = AnchorCursor, 1
+	}
+	m.popup = m.OpenFloat(lines, opts)
+}
+
+// ShowPopup shows lines of text in a box next to the cursor until the next
+// key press, for example documentation from a language server
+func (m *editorModel) ShowPopup(lines []string) {
+	m.showPopup(lines, true)
+}
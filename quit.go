@@ -0,0 +1,86 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"errors"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// QuitRequestedMsg is sent when the user asks to leave the editor with
+// :q, :qa, :wq or :x. The editor never quits the program by itself;
+// the parent model decides how to react, unless WithQuitOnRequest is set.
+type QuitRequestedMsg struct {
+	Force bool // Whether the request was forced with "!"
+	All   bool // Whether all buffers should be closed (:qa)
+}
+
+// WithQuitOnRequest makes the editor quit the program when it receives its
+// own QuitRequestedMsg. Use it when the editor is the root model.
+func WithQuitOnRequest() EditorOption {
+	return func(o *options) {
+		o.QuitOnRequest = true
+	}
+}
+
+// registerQuitCommands registers the built-in quit commands
+func registerQuitCommands(m *editorModel) {
+	m.commands.Register("q", quitCommand(false))
+	m.commands.Register("quit", quitCommand(false))
+	m.commands.Register("qa", quitCommand(true))
+	m.commands.Register("qall", quitCommand(true))
+	m.commands.Register("quitall", quitCommand(true))
+}
+
+// quitCommand implements :q[!] and :qa[!], refusing to abandon unsaved
+// changes unless "!" is given. With several windows :q closes the current
+// one.
+func quitCommand(all bool) Command {
+	return func(model *editorModel) tea.Cmd {
+		bang, _ := model.commandLine()
+		if !all && model.sharesArea() {
+			return model.quitWindow(bang)
+		}
+		if !bang {
+			if err := model.checkModified(); err != nil {
+				return SetStatusMsg(err.Error())
+			}
+		}
+		return requestQuit(bang, all)
+	}
+}
+
+// quitWindow closes the current window, or requests to quit when it is
+// the last one
+func (m *editorModel) quitWindow(force bool) tea.Cmd {
+	if !m.sharesArea() {
+		return requestQuit(force, false)
+	}
+	if err := m.CloseWindow(m.win.id, force); err != nil {
+		return SetStatusMsg(err.Error())
+	}
+	return nil
+}
+
+// checkModified returns an error when the current buffer, or else any
+// other buffer of the buffer list, has unsaved changes
+func (m *editorModel) checkModified() error {
+	if m.buffer.modified() {
+		return errors.New("E37: No write since last change (add ! to override)")
+	}
+	m.storeWindow()
+	for _, d := range m.docs {
+		if d.modified() {
+			return fmt.Errorf("E162: No write since last change for buffer %q", d.name())
+		}
+	}
+	return nil
+}
+
+// requestQuit returns a command that emits a QuitRequestedMsg
+func requestQuit(force, all bool) tea.Cmd {
+	return func() tea.Msg {
+		return QuitRequestedMsg{Force: force, All: all}
+	}
+}
@@ -0,0 +1,62 @@
+package vimtea
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModifiedTracksUndo(t *testing.T) {
+	editor := NewEditor(WithContent("abc"))
+	buf := editor.GetBuffer()
+	model := editor.(*editorModel)
+
+	assert.False(t, buf.Modified(), "New buffer should not be modified")
+
+	buf.InsertAt(0, 3, "d")
+	assert.True(t, buf.Modified(), "Inserting text should mark the buffer modified")
+
+	buf.MarkSaved()
+	assert.False(t, buf.Modified(), "MarkSaved should clear the modified flag")
+
+	buf.InsertAt(0, 4, "e")
+	assert.True(t, buf.Modified())
+
+	model.Update(buf.Undo()())
+	assert.Equal(t, "abcd", buf.Text())
+	assert.False(t, buf.Modified(), "Undoing back to the saved state should clear the flag")
+
+	model.Update(buf.Undo()())
+	assert.Equal(t, "abc", buf.Text())
+	assert.True(t, buf.Modified(), "Undoing past the saved state should set the flag")
+
+	model.Update(buf.Redo()())
+	assert.False(t, buf.Modified(), "Redoing to the saved state should clear the flag")
+}
+
+func TestQuitCommand(t *testing.T) {
+	model := NewEditor(WithContent("abc")).(*editorModel)
+
+	msgs := runCommand(t, model, "q")
+	assert.Contains(t, msgs, tea.Msg(QuitRequestedMsg{}), ":q on an unmodified buffer should request to quit")
+
+	model.buffer.insertAt(0, 0, "x")
+	msgs = runCommand(t, model, "q")
+	assert.NotContains(t, msgs, tea.Msg(QuitRequestedMsg{}), ":q should refuse to abandon changes")
+	assert.Contains(t, msgs, tea.Msg(statusMessageMsg("E37: No write since last change (add ! to override)")))
+
+	msgs = runCommand(t, model, "q!")
+	assert.Contains(t, msgs, tea.Msg(QuitRequestedMsg{Force: true}), ":q! should force the request")
+
+	msgs = runCommand(t, model, "qa!")
+	assert.Contains(t, msgs, tea.Msg(QuitRequestedMsg{Force: true, All: true}), ":qa! should request to close everything")
+}
+
+func TestQuitOnRequest(t *testing.T) {
+	_, cmd := NewEditor().Update(QuitRequestedMsg{})
+	assert.NotContains(t, collectMsgs(cmd), tea.Msg(tea.QuitMsg{}), "Embedded editors should leave quitting to the parent")
+
+	_, cmd = NewEditor(WithQuitOnRequest()).Update(QuitRequestedMsg{})
+	assert.Contains(t, collectMsgs(cmd), tea.Msg(tea.QuitMsg{}), "Root editors should quit on request")
+}
@@ -0,0 +1,107 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// WithSideScrollOff sets the minimal number of columns to keep left and
+// right of the cursor when lines do not wrap
+func WithSideScrollOff(columns int) EditorOption {
+	return func(o *options) {
+		o.SideScrollOff = columns
+	}
+}
+
+// cursorScreenCol returns the visual column of the cursor in its line
+func (m *editorModel) cursorScreenCol() int {
+	return bufferToVisualPosition(m.buffer.Line(m.cursor.Row), m.cursor.Col, m.buffer.tabs.tabStop)
+}
+
+// sideScrollMargin returns the sidescrolloff margin, limited so that the
+// cursor can still be placed in the middle of the text area
+func (m *editorModel) sideScrollMargin() int {
+	return max(0, min(m.sideScrollOff, (m.textWidth()-1)/2))
+}
+
+// ensureCursorVisibleX scrolls horizontally so the cursor column is on
+// screen. With wrap enabled there is nothing to scroll.
+func (m *editorModel) ensureCursorVisibleX() {
+	width := m.textWidth()
+	if m.wrap || width == 0 {
+		m.xOffset = 0
+		return
+	}
+
+	col := m.cursorScreenCol()
+	margin := m.sideScrollMargin()
+	if col < m.xOffset+margin {
+		m.xOffset = max(0, col-margin)
+	} else if col >= m.xOffset+width-margin {
+		m.xOffset = col - width + margin + 1
+	}
+}
+
+// clipLine cuts the horizontally scrolled part of a rendered line that is
+// visible on screen. ANSI escape sequences are preserved.
+func (m *editorModel) clipLine(rendered string) string {
+	width := m.textWidth()
+	if width == 0 {
+		return rendered
+	}
+	return ansi.Cut(rendered, m.xOffset, m.xOffset+width)
+}
+
+// scrollHorizontal scrolls the view by columns, negative to the left, and
+// moves the cursor if it would leave the screen
+func (m *editorModel) scrollHorizontal(columns int) {
+	width := m.textWidth()
+	if m.wrap || width == 0 {
+		return
+	}
+	m.xOffset = max(0, m.xOffset+columns)
+
+	line := m.buffer.Line(m.cursor.Row)
+	margin := m.sideScrollMargin()
+	col := m.cursorScreenCol()
+	switch {
+	case col < m.xOffset+margin:
+		col = m.xOffset + margin
+	case col >= m.xOffset+width-margin:
+		col = m.xOffset + width - margin - 1
+	default:
+		return
+	}
+	m.cursor.Col = visualToBufferPosition(line, col, m.buffer.tabs.tabStop)
+	m.adjustCursorPosition()
+	m.desiredCol = m.cursor.Col
+}
+
+func scrollLeft(model *editorModel) tea.Cmd {
+	model.scrollHorizontal(-model.countPrefix)
+	model.countPrefix = 1
+	return nil
+}
+
+func scrollRight(model *editorModel) tea.Cmd {
+	model.scrollHorizontal(model.countPrefix)
+	model.countPrefix = 1
+	return nil
+}
+
+// scrollCursorToStart implements zs, scrolling the cursor to the left of the screen
+func scrollCursorToStart(model *editorModel) tea.Cmd {
+	if !model.wrap {
+		model.xOffset = max(0, model.cursorScreenCol()-model.sideScrollMargin())
+	}
+	return nil
+}
+
+// scrollCursorToEnd implements ze, scrolling the cursor to the right of the screen
+func scrollCursorToEnd(model *editorModel) tea.Cmd {
+	if !model.wrap {
+		model.xOffset = max(0, model.cursorScreenCol()-model.textWidth()+model.sideScrollMargin()+1)
+	}
+	return nil
+}
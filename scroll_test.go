@@ -0,0 +1,63 @@
+package vimtea
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHorizontalScrollFollowsCursor(t *testing.T) {
+	model := newWrapEditor("0123456789abcdef", 6, 1, WithWrap(false))
+
+	sendKeys(model, "$")
+	assert.Equal(t, 10, model.xOffset, "The view should scroll right to the cursor")
+	assert.Equal(t, []string{"abcdef"}, screenRows(model))
+
+	sendKeys(model, "0")
+	assert.Equal(t, 0, model.xOffset, "The view should scroll back to the start of the line")
+
+	runCommand(t, model, "set siso=2")
+	assert.Equal(t, 2, model.sideScrollOff)
+	model.cursor.Col = 5
+	model.ensureCursorVisible()
+	assert.Equal(t, 2, model.xOffset, "sidescrolloff should keep columns right of the cursor")
+
+	model = newWrapEditor("\tx世界yz", 4, 1, WithWrap(false), WithTabStop(4))
+	model.cursor.Col = len("\tx世界")
+	model.ensureCursorVisible()
+	assert.Equal(t, 6, model.xOffset, "Tabs and wide characters should count their screen width")
+}
+
+func TestHorizontalScrollCommands(t *testing.T) {
+	model := newWrapEditor("0123456789abcdef", 6, 1, WithWrap(false))
+
+	sendKeys(model, "3zl")
+	assert.Equal(t, 3, model.xOffset)
+	assert.Equal(t, 3, model.cursor.Col, "zl should move a cursor that leaves the screen")
+	sendKeys(model, "zh")
+	assert.Equal(t, 2, model.xOffset)
+	assert.Equal(t, 3, model.cursor.Col, "zh should keep a cursor that stays on screen")
+
+	model.cursor.Col = 8
+	sendKeys(model, "zs")
+	assert.Equal(t, 8, model.xOffset, "zs should scroll the cursor to the start of the screen")
+	sendKeys(model, "ze")
+	assert.Equal(t, 3, model.xOffset, "ze should scroll the cursor to the end of the screen")
+
+	model.wrap = true
+	sendKeys(model, "zl")
+	assert.Equal(t, 3, model.xOffset, "Scrolling does nothing when lines wrap")
+	runCommand(t, model, "set wrap")
+	assert.Equal(t, 0, model.xOffset)
+}
+
+func TestHorizontalScrollHighlighted(t *testing.T) {
+	model := newWrapEditor("package main // a long comment", 10, 1, WithWrap(false), WithFileName("main.go"))
+	model.cursor.Col = 20
+	model.ensureCursorVisible()
+
+	row := model.renderDisplayLines(model.renderLine(model.buffer.Line(0), 0, false, Cursor{}, Cursor{}), 0)[0]
+	assert.Equal(t, 10, ansi.StringWidth(row), "A highlighted row should be cut to the text width")
+	assert.Equal(t, "n // a lon", ansi.Strip(row))
+}
@@ -0,0 +1,151 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// signWidth is the number of columns of one sign in the sign column
+const signWidth = 2
+
+// Sign is a marker drawn in the sign column next to a line, such as a lint
+// error, a breakpoint or a bookmark. Signs move with their lines when text
+// is inserted or deleted above them.
+type Sign struct {
+	ID       int            // Identifier, assigned by PlaceSign when zero
+	Group    string         // Group for removing related signs together, e.g. "lint"
+	Line     int            // Zero-based buffer row
+	Text     string         // Glyph of up to two columns, e.g. "E" or "●"
+	Style    lipgloss.Style // Style of the glyph, drawn over the SignColumn group
+	Priority int            // Signs with a higher priority are shown first on a shared line
+}
+
+// WithSignColumn sets when the sign column is shown: "auto" shows it while
+// there are signs, "yes" always and "no" never. "auto:N" grows the column up
+// to N signs side by side and "yes:N" keeps it N signs wide.
+func WithSignColumn(value string) EditorOption {
+	return func(o *options) {
+		o.SignColumn = value
+	}
+}
+
+// parseSignColumn splits a signcolumn value into its mode and the maximum
+// number of signs shown on a line
+func parseSignColumn(value string) (string, int, error) {
+	mode, count, found := strings.Cut(value, ":")
+	n := 1
+	if found {
+		var err error
+		n, err = strconv.Atoi(count)
+		if err != nil || n < 1 || n > 9 {
+			return "", 0, fmt.Errorf("E474: Invalid argument: signcolumn=%s", value)
+		}
+	}
+	switch mode {
+	case "auto", "yes":
+		return mode, n, nil
+	case "no":
+		if !found {
+			return mode, 0, nil
+		}
+	}
+	return "", 0, fmt.Errorf("E474: Invalid argument: signcolumn=%s", value)
+}
+
+// PlaceSign adds a sign and returns its ID. A sign with the ID of an
+// existing sign replaces it.
+func (m *editorModel) PlaceSign(sign Sign) int {
+	if sign.ID == 0 {
+		m.nextSignID++
+		sign.ID = m.nextSignID
+	} else {
+		m.nextSignID = max(m.nextSignID, sign.ID)
+	}
+	sign.Line = max(0, min(sign.Line, m.buffer.lineCount()-1))
+
+	m.signs = slices.DeleteFunc(m.signs, func(s Sign) bool { return s.ID == sign.ID })
+	m.signs = append(m.signs, sign)
+	return sign.ID
+}
+
+// UnplaceSign removes the sign with the given ID
+func (m *editorModel) UnplaceSign(id int) {
+	m.signs = slices.DeleteFunc(m.signs, func(s Sign) bool { return s.ID == id })
+}
+
+// UnplaceSigns removes all signs of a group, or all signs when group is empty
+func (m *editorModel) UnplaceSigns(group string) {
+	m.signs = slices.DeleteFunc(m.signs, func(s Sign) bool { return group == "" || s.Group == group })
+}
+
+// GetSigns returns the placed signs at their current lines
+func (m *editorModel) GetSigns() []Sign {
+	return slices.Clone(m.signs)
+}
+
+// shiftSigns moves signs with their lines after a change of the buffer
+func (m *editorModel) shiftSigns(change TextChange) {
+	for i := range m.signs {
+		m.signs[i].Line = change.shiftRow(m.signs[i].Line)
+	}
+}
+
+// lineSigns returns the signs of a row, the one with the highest priority
+// first. Of signs with the same priority the last placed comes first.
+func (m *editorModel) lineSigns(row int) []Sign {
+	var signs []Sign
+	for i := len(m.signs) - 1; i >= 0; i-- {
+		if m.signs[i].Line == row {
+			signs = append(signs, m.signs[i])
+		}
+	}
+	slices.SortStableFunc(signs, func(a, b Sign) int { return b.Priority - a.Priority })
+	return signs
+}
+
+// signColumnCells returns the number of signs shown side by side
+func (m *editorModel) signColumnCells() int {
+	mode, n, err := parseSignColumn(m.signColumn)
+	if err != nil || mode == "no" || (mode == "auto" && len(m.signs) == 0) {
+		return 0
+	}
+	if mode == "yes" {
+		return n
+	}
+
+	counts := make(map[int]int)
+	most := 0
+	for _, sign := range m.signs {
+		counts[sign.Line]++
+		most = max(most, counts[sign.Line])
+	}
+	return min(most, n)
+}
+
+// renderSignColumn renders the sign column of a row, blank for rows
+// without a buffer line
+func (m *editorModel) renderSignColumn(row int) string {
+	cells := m.signColumnCells()
+	if cells == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	signs := m.lineSigns(row)
+	for i := range cells {
+		if i >= len(signs) {
+			sb.WriteString(m.theme.SignColumn.Render(strings.Repeat(" ", signWidth)))
+			continue
+		}
+		text := ansi.Truncate(signs[i].Text, signWidth, "")
+		text += strings.Repeat(" ", signWidth-ansi.StringWidth(text))
+		sb.WriteString(signs[i].Style.Inherit(m.theme.SignColumn).Render(text))
+	}
+	return sb.String()
+}
@@ -0,0 +1,100 @@
+package vimtea
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignColumnRendering(t *testing.T) {
+	model := newWrapEditor("one\ntwo\nthree", 20, 3)
+	assert.Equal(t, []string{"one", "two", "three"}, screenRows(model), "auto should hide the column without signs")
+
+	model.PlaceSign(Sign{Line: 1, Text: "E"})
+	model.PlaceSign(Sign{Line: 2, Text: "●"})
+	assert.Equal(t, []string{"  one", "E two", "● three"}, screenRows(model))
+	assert.Equal(t, 18, model.textWidth(), "The sign column should take two columns")
+
+	model.signColumn = "no"
+	assert.Equal(t, []string{"one", "two", "three"}, screenRows(model))
+
+	model.signColumn = "yes:2"
+	assert.Equal(t, "    one", screenRows(model)[0], "yes:N should keep N cells")
+	assert.Equal(t, "E   two", screenRows(model)[1])
+
+	model.UnplaceSigns("")
+	model.signColumn = "yes"
+	assert.Equal(t, "  one", screenRows(model)[0], "yes should show the column without signs")
+}
+
+func TestSignPriority(t *testing.T) {
+	model := newWrapEditor("one", 20, 1, WithSignColumn("auto:3"))
+
+	model.PlaceSign(Sign{Line: 0, Text: "B", Group: "debug", Priority: 20})
+	model.PlaceSign(Sign{Line: 0, Text: "W", Group: "lint", Priority: 10})
+	id := model.PlaceSign(Sign{Line: 0, Text: "E", Group: "lint", Priority: 10})
+	assert.Equal(t, []string{"B E W one"}, screenRows(model), "Higher priorities and newer signs should come first")
+
+	model.UnplaceSign(id)
+	assert.Equal(t, []string{"B W one"}, screenRows(model), "auto:N should shrink with the signs")
+
+	model.PlaceSign(Sign{ID: id, Line: 0, Text: ">>", Priority: 30})
+	model.UnplaceSigns("lint")
+	assert.Equal(t, []string{">>B one"}, screenRows(model))
+
+	model.signColumn = "auto:1"
+	assert.Equal(t, []string{">>one"}, screenRows(model), "Only the signs that fit should be shown")
+}
+
+func TestSignsFollowEdits(t *testing.T) {
+	model := NewEditor(WithContent("a\nb\nc\nd")).(*editorModel)
+	b := model.PlaceSign(Sign{Line: 1, Text: "B"})
+	d := model.PlaceSign(Sign{Line: 3, Text: "D"})
+
+	line := func(id int) int {
+		for _, sign := range model.GetSigns() {
+			if sign.ID == id {
+				return sign.Line
+			}
+		}
+		require.Failf(t, "sign not found", "id %d", id)
+		return -1
+	}
+
+	// Open a line above b
+	model.cursor = newCursor(1, 0)
+	sendKeys(model, "Onew")
+	model.Update(tea.KeyMsg{Type: tea.KeyEscape})
+	require.Equal(t, "a\nnew\nb\nc\nd", model.buffer.text())
+	assert.Equal(t, 2, line(b), "Signs should move down with their line")
+	assert.Equal(t, 4, line(d))
+
+	// Delete a and new
+	model.cursor = newCursor(0, 0)
+	sendKeys(model, "dddd")
+	require.Equal(t, "b\nc\nd", model.buffer.text())
+	assert.Equal(t, 0, line(b), "Signs should move up with their line")
+	assert.Equal(t, 2, line(d))
+
+	// Edits within a line keep its signs in place
+	sendKeys(model, "x")
+	assert.Equal(t, 0, line(b))
+
+	sendKeys(model, "u")
+	assert.Equal(t, 0, line(b))
+	assert.Equal(t, 2, line(d))
+}
+
+func TestSignColumnOption(t *testing.T) {
+	model := NewEditor().(*editorModel)
+
+	require.NoError(t, model.SetOption("signcolumn", "yes:3"))
+	assert.Equal(t, 3, model.signColumnCells())
+
+	for _, value := range []string{"maybe", "yes:0", "auto:10", "no:2"} {
+		assert.Error(t, model.SetOption("scl", value), value)
+	}
+	assert.Equal(t, "yes:3", model.signColumn)
+}
@@ -0,0 +1,291 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+)
+
+// IndentRules describes how the tokens of a language change indentation
+type IndentRules struct {
+	Open  []string // Tokens that indent the following lines, e.g. "{"
+	Close []string // Tokens that dedent the line they start, e.g. "}"
+	After []string // Tokens that indent the next line when they end a line, e.g. ":" in Python
+	Files string   // Comma separated file patterns for languages without a chroma lexer
+}
+
+// bracketRules are the rules shared by languages with C-like brackets
+var bracketRules = IndentRules{
+	Open:  []string{"{", "(", "["},
+	Close: []string{"}", ")", "]"},
+}
+
+// defaultIndentRules returns the built-in rule tables by chroma language name
+func defaultIndentRules() map[string]IndentRules {
+	rules := make(map[string]IndentRules)
+	for _, lang := range []string{
+		"Go", "C", "C++", "C#", "Java", "JavaScript", "TypeScript", "TSX", "JSON", "Rust",
+		"Kotlin", "Swift", "Scala", "PHP", "CSS", "SCSS", "Dart", "Zig", "HCL", "Terraform",
+		"TOML", "Protocol Buffer", "GraphQL", "Groovy",
+	} {
+		rules[strings.ToLower(lang)] = bracketRules
+	}
+
+	python := bracketRules
+	python.After = []string{":"}
+	rules["python"] = python
+
+	yaml := bracketRules
+	yaml.After = []string{":", "-", "|", ">"}
+	rules["yaml"] = yaml
+	return rules
+}
+
+// SmartIndenter is an Indenter that uses the chroma token stream to indent
+// after opening brackets and block starters, and to dedent lines that start
+// with a closing bracket. Strings and comments are ignored. Languages without
+// rules copy the indentation of the previous line.
+type SmartIndenter struct {
+	rules map[string]IndentRules // Rule tables by lower case language name
+	names []string               // Registered names in registration order
+}
+
+// NewSmartIndenter creates a SmartIndenter with rules for common languages
+func NewSmartIndenter() *SmartIndenter {
+	return &SmartIndenter{rules: defaultIndentRules()}
+}
+
+// Register adds or replaces the rules for a language. The name is a chroma
+// language name such as "Python", or any name when rules.Files is set.
+func (s *SmartIndenter) Register(language string, rules IndentRules) {
+	key := strings.ToLower(language)
+	if _, ok := s.rules[key]; !ok {
+		s.names = append(s.names, key)
+	}
+	s.rules[key] = rules
+}
+
+// rulesFor finds the rules for a language, falling back to rules whose file
+// patterns match the file name
+func (s *SmartIndenter) rulesFor(opts IndentOptions) (IndentRules, bool) {
+	if rules, ok := s.rules[strings.ToLower(opts.Language)]; ok {
+		return rules, true
+	}
+	for _, name := range s.names {
+		if rules := s.rules[name]; rules.Files != "" && matchAutocmdPattern(rules.Files, opts.FileName) {
+			return rules, true
+		}
+	}
+	return IndentRules{}, false
+}
+
+// Indent computes the indentation of row from the previous non-blank line
+func (s *SmartIndenter) Indent(b Buffer, row int, opts IndentOptions) int {
+	rules, ok := s.rulesFor(opts)
+	if !ok {
+		return CopyIndenter.Indent(b, row, opts)
+	}
+	lexer := lexers.Get(opts.Language)
+
+	prev := row - 1
+	for prev >= 0 && strings.TrimSpace(b.Line(prev)) == "" {
+		prev--
+	}
+
+	width := 0
+	if prev >= 0 {
+		line := b.Line(prev)
+		width = IndentWidth(line, opts.TabStop)
+
+		// Leading closing tokens already dedented the previous line itself
+		tokens := indentTokens(lexer, line)
+		leading := 0
+		for leading < len(tokens) && slices.Contains(rules.Close, tokens[leading]) {
+			leading++
+		}
+
+		depth := 0
+		for _, tok := range tokens[leading:] {
+			switch {
+			case slices.Contains(rules.Open, tok):
+				depth++
+			case slices.Contains(rules.Close, tok):
+				depth--
+			}
+		}
+
+		switch {
+		case depth > 0 || (len(tokens) > 0 && slices.Contains(rules.After, tokens[len(tokens)-1])):
+			width += opts.ShiftWidth
+		case depth < 0:
+			width -= opts.ShiftWidth
+		}
+	}
+
+	if tokens := indentTokens(lexer, b.Line(row)); len(tokens) > 0 && slices.Contains(rules.Close, tokens[0]) {
+		width -= opts.ShiftWidth
+	}
+	return max(0, width)
+}
+
+// IndentKeys returns the characters that reindent the current line when
+// typed as its first non-blank character
+func (s *SmartIndenter) IndentKeys(opts IndentOptions) string {
+	rules, _ := s.rulesFor(opts)
+	var keys strings.Builder
+	for _, tok := range rules.Close {
+		if len([]rune(tok)) == 1 {
+			keys.WriteString(tok)
+		}
+	}
+	return keys.String()
+}
+
+// IndentKeyer is implemented by Indenters that reindent the current line in
+// insert mode when one of their keys is typed at the start of the line
+type IndentKeyer interface {
+	IndentKeys(opts IndentOptions) string
+}
+
+// indentTokens splits a line into the tokens that matter for indentation.
+// Comments and strings are skipped, words are kept whole and every other
+// character is a token of its own. Without a lexer the raw text is split.
+func indentTokens(lexer chroma.Lexer, line string) []string {
+	var tokens []string
+	split := func(text string) {
+		word := -1
+		for i, r := range text {
+			isWord := unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+			if word >= 0 && !isWord {
+				tokens = append(tokens, text[word:i])
+				word = -1
+			}
+			switch {
+			case isWord && word < 0:
+				word = i
+			case !isWord && !unicode.IsSpace(r):
+				tokens = append(tokens, string(r))
+			}
+		}
+		if word >= 0 {
+			tokens = append(tokens, text[word:])
+		}
+	}
+
+	if lexer == nil {
+		split(line)
+		return tokens
+	}
+
+	iter, err := lexer.Tokenise(nil, line)
+	if err != nil {
+		split(line)
+		return tokens
+	}
+	for _, tok := range iter.Tokens() {
+		if tok.Type.InCategory(chroma.Comment) || tok.Type.InSubCategory(chroma.String) {
+			continue
+		}
+		split(tok.Value)
+	}
+	return tokens
+}
+
+// WithSmartIndent makes new lines and typed closing brackets use the
+// Indenter. Unless WithIndenter is given, a SmartIndenter is used.
+func WithSmartIndent(enable bool) EditorOption {
+	return func(o *options) {
+		o.SmartIndent = enable
+	}
+}
+
+// indentOptions returns the settings passed to the Indenter
+func (m *editorModel) indentOptions() IndentOptions {
+	m.highlighter.detect(m.buffer)
+	return IndentOptions{
+		TabStop:    m.buffer.tabs.tabStop,
+		ShiftWidth: m.buffer.tabs.shift(),
+		ExpandTab:  m.buffer.tabs.expandTab,
+		Language:   m.highlighter.languageName(),
+		FileName:   m.fileName(),
+	}
+}
+
+// currentIndenter returns the Indenter set with WithIndenter, or else the
+// smart indenter while smartindent is on and CopyIndenter otherwise
+func (m *editorModel) currentIndenter() Indenter {
+	switch {
+	case m.indenter != nil:
+		return m.indenter
+	case m.smartIndent:
+		return m.smartIndenter
+	default:
+		return CopyIndenter
+	}
+}
+
+// addedIndent is the indentation that o, O or Enter put on an empty line.
+// Like in Vim it is removed again when nothing is typed on the line.
+type addedIndent struct {
+	buffer  *buffer // Buffer of the line, nil when there is none
+	row     int     // Row of the line
+	version int     // Buffer version right after the indentation was added
+}
+
+// indentNewLine sets the indentation of a line that was just opened. With
+// smartindent the Indenter decides, otherwise autoindent copies from.
+func (m *editorModel) indentNewLine(row, from int) {
+	if m.smartIndent {
+		if width := m.currentIndenter().Indent(m.GetBuffer(), row, m.indentOptions()); width >= 0 {
+			m.setIndent(row, width)
+		}
+	} else if indent := m.newLineIndent(from); indent != "" {
+		m.buffer.setLine(row, indent+strings.TrimLeft(m.buffer.Line(row), " \t"))
+	}
+
+	m.addedIndent = addedIndent{}
+	if line := m.buffer.Line(row); line != "" && strings.TrimLeft(line, " \t") == "" {
+		m.addedIndent = addedIndent{buffer: m.buffer, row: row, version: m.buffer.version}
+	}
+}
+
+// onAddedIndent reports whether the cursor is on the last opened line and
+// nothing was typed since its indentation was added
+func (m *editorModel) onAddedIndent() bool {
+	added := m.addedIndent
+	return added.buffer == m.buffer && added.row == m.cursor.Row && added.version == m.buffer.version
+}
+
+// removeAddedIndent removes the indentation of the last opened line if
+// nothing was typed on it
+func (m *editorModel) removeAddedIndent() {
+	if m.onAddedIndent() {
+		m.buffer.setLine(m.cursor.Row, "")
+		m.cursor.Col = 0
+	}
+	m.addedIndent = addedIndent{}
+}
+
+// indentOnKey reindents the cursor line after char was typed if char is one
+// of the Indenter's indent keys and the first non-blank of the line
+func (m *editorModel) indentOnKey(char string) {
+	indenter := m.currentIndenter()
+	keyer, ok := indenter.(IndentKeyer)
+	if !m.smartIndent || !ok {
+		return
+	}
+	opts := m.indentOptions()
+	line := m.buffer.Line(m.cursor.Row)
+	if strings.TrimLeft(line[:m.cursor.Col], " \t") != char || !strings.Contains(keyer.IndentKeys(opts), char) {
+		return
+	}
+
+	if width := indenter.Indent(m.GetBuffer(), m.cursor.Row, opts); width >= 0 {
+		m.setIndent(m.cursor.Row, width)
+		m.cursor.Col += len(m.buffer.Line(m.cursor.Row)) - len(line)
+	}
+}
@@ -0,0 +1,99 @@
+package vimtea
+
+import (
+	"testing"
+
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndentTokens(t *testing.T) {
+	model := NewEditor(WithFileName("main.go")).(*editorModel)
+	opts := model.indentOptions()
+	assert.Equal(t, "Go", opts.Language, "Language should be detected from the file name")
+
+	assert.Equal(t, []string{"if", "x", "{", "s", ":", "="},
+		indentTokens(lexers.Get(opts.Language), `if x { s := "{" // {`), "Strings and comments should be skipped")
+	assert.Equal(t, []string{"a", "(", "b"}, indentTokens(nil, "a( b"), "Without a lexer the raw text is split")
+}
+
+func TestSmartIndenter(t *testing.T) {
+	indenter := NewSmartIndenter()
+	opts := IndentOptions{TabStop: 4, ShiftWidth: 4, Language: "Go"}
+	buf := NewEditor(WithContent("func f() {\n\tcall(a,\n\t\tb)\n\tx\n}\n\t}) {\ny")).(*editorModel).GetBuffer()
+
+	assert.Equal(t, 4, indenter.Indent(buf, 1, opts), "An open brace should indent the next line")
+	assert.Equal(t, 8, indenter.Indent(buf, 2, opts), "An open parenthesis should indent the next line")
+	assert.Equal(t, 4, indenter.Indent(buf, 3, opts), "Closing a parenthesis should dedent the next line")
+	assert.Equal(t, 0, indenter.Indent(buf, 4, opts), "A closing brace should dedent its own line")
+	assert.Equal(t, 8, indenter.Indent(buf, 6, opts), "Leading closers should not dedent the next line again")
+
+	python := IndentOptions{TabStop: 4, ShiftWidth: 4, Language: "Python"}
+	buf = NewEditor(WithContent("def f(x):  # comment\nreturn x")).(*editorModel).GetBuffer()
+	assert.Equal(t, 4, indenter.Indent(buf, 1, python), "A trailing colon should indent in Python")
+
+	yaml := IndentOptions{TabStop: 2, ShiftWidth: 2, Language: "YAML"}
+	buf = NewEditor(WithContent("  key:\nvalue")).(*editorModel).GetBuffer()
+	assert.Equal(t, 4, indenter.Indent(buf, 1, yaml), "A mapping key should indent in YAML")
+
+	plain := IndentOptions{TabStop: 4, ShiftWidth: 4, Language: "plaintext"}
+	buf = NewEditor(WithContent("  a {\nb")).(*editorModel).GetBuffer()
+	assert.Equal(t, 2, indenter.Indent(buf, 1, plain), "Languages without rules should copy the indentation")
+}
+
+func TestSmartIndenterCustomRules(t *testing.T) {
+	indenter := NewSmartIndenter()
+	indenter.Register("Flow", IndentRules{
+		Open:  []string{"begin"},
+		Close: []string{"end"},
+		Files: "*.flow",
+	})
+
+	opts := IndentOptions{TabStop: 2, ShiftWidth: 2, FileName: "job.flow"}
+	buf := NewEditor(WithContent("step begin\nrun\nend")).(*editorModel).GetBuffer()
+	assert.Equal(t, 2, indenter.Indent(buf, 1, opts), "Custom open tokens should indent")
+	assert.Equal(t, 0, indenter.Indent(buf, 2, IndentOptions{TabStop: 2, ShiftWidth: 2, Language: "flow"}),
+		"Rules should also be found by language name")
+	assert.Equal(t, "", indenter.IndentKeys(opts), "Only single characters are indent keys")
+}
+
+func TestSmartIndentInsert(t *testing.T) {
+	model := NewEditor(WithContent("func f() {"), WithFileName("main.go"), WithSmartIndent(true), WithTabStop(8)).(*editorModel)
+	model.mode = ModeInsert
+	model.cursor.Col = 10
+
+	handleInsertEnterKey(model)
+	assert.Equal(t, "func f() {\n\t", model.buffer.text(), "Enter after a brace should indent")
+	assert.Equal(t, Cursor{1, 1}, model.cursor)
+
+	insertCharacter(model, "x")
+	handleInsertEnterKey(model)
+	insertCharacter(model, "}")
+	assert.Equal(t, "func f() {\n\tx\n}", model.buffer.text(), "Typing a closing brace should dedent the line")
+	assert.Equal(t, Cursor{2, 1}, model.cursor)
+
+	switchMode(model, ModeNormal)
+	model.cursor = Cursor{2, 0}
+	openLineAbove(model)
+	assert.Equal(t, "func f() {\n\tx\n\t\n}", model.buffer.text(), "O should use the indenter")
+
+	runCommand(t, model, "set nosi")
+	assert.False(t, model.smartIndent)
+}
+
+func TestSmartIndentOption(t *testing.T) {
+	model := NewEditor(WithContent("func f() {"), WithFileName("main.go"), WithTabStop(8)).(*editorModel)
+	runCommand(t, model, "set si")
+	model.mode = ModeInsert
+	model.cursor.Col = 10
+
+	handleInsertEnterKey(model)
+	assert.Equal(t, "func f() {\n\t", model.buffer.text(), ":set si should indent with the smart indenter")
+
+	runCommand(t, model, "set nosi")
+	model.mode = ModeInsert
+	model.cursor = Cursor{1, 1}
+	insertCharacter(model, "x")
+	handleInsertEnterKey(model)
+	assert.Equal(t, "func f() {\n\tx\n\t", model.buffer.text(), ":set nosi should go back to copying the indent")
+}
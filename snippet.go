@@ -0,0 +1,659 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Snippets use the LSP and TextMate syntax:
+//
+//	$1, ${1}           tabstop, visited in increasing order
+//	${1:default}       placeholder, may contain other tabstops
+//	${1|one,two|}      choice of texts
+//	$0                 final cursor position, the end of the snippet if absent
+//	$NAME, ${NAME:x}   variable such as TM_FILENAME, with a default
+//	\$, \}, \\         literal characters
+//
+// A tabstop that appears more than once is mirrored: typing in its first
+// occurrence updates the others.
+
+// WithSnippets registers snippets expanded with <Tab> after their trigger
+// word in insert mode
+func WithSnippets(snippets map[string]string) EditorOption {
+	return func(o *options) {
+		if o.Snippets == nil {
+			o.Snippets = map[string]string{}
+		}
+		for trigger, body := range snippets {
+			o.Snippets[trigger] = body
+		}
+	}
+}
+
+// AddSnippet registers a snippet expanded with <Tab> after its trigger word
+// in insert mode. Triggers are made of keyword characters.
+func (m *editorModel) AddSnippet(trigger, body string) {
+	if m.snippets == nil {
+		m.snippets = map[string]string{}
+	}
+	m.snippets[trigger] = body
+}
+
+// InsertSnippet inserts a snippet at the cursor as a single undo step and
+// enters insert mode on its first tabstop
+func (m *editorModel) InsertSnippet(snippet string) tea.Cmd {
+	m.cursor = m.clampPosition(m.cursor)
+	return m.insertSnippet(m.cursor, snippet)
+}
+
+// snippetNode is a parsed piece of a snippet
+type snippetNode struct {
+	text     string        // Literal text, for text nodes
+	number   int           // Tabstop number, -1 for text and variables
+	name     string        // Variable name, for variables
+	children []snippetNode // Placeholder text or variable default
+	choices  []string      // Texts of a choice
+}
+
+// parseSnippet parses snippet syntax. Malformed elements are kept as text.
+func parseSnippet(s string) []snippetNode {
+	nodes, _ := parseSnippetNodes(s, 0, false)
+	return nodes
+}
+
+// parseSnippetNodes parses from i until the end of s, or until the brace
+// closing a placeholder when nested, and returns the index it stopped at
+func parseSnippetNodes(s string, i int, nested bool) ([]snippetNode, int) {
+	var nodes []snippetNode
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, snippetNode{text: text.String(), number: -1})
+			text.Reset()
+		}
+	}
+
+	for i < len(s) {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`$}\`, s[i+1]) >= 0:
+			text.WriteByte(s[i+1])
+			i += 2
+		case s[i] == '}' && nested:
+			flush()
+			return nodes, i
+		case s[i] == '$':
+			node, next, ok := parseSnippetElement(s, i)
+			if !ok {
+				text.WriteByte('$')
+				i++
+				continue
+			}
+			flush()
+			nodes = append(nodes, node)
+			i = next
+		default:
+			text.WriteByte(s[i])
+			i++
+		}
+	}
+	flush()
+	return nodes, i
+}
+
+// parseSnippetElement parses the tabstop or variable starting with the $ at
+// i and returns the index after it
+func parseSnippetElement(s string, i int) (snippetNode, int, bool) {
+	node := snippetNode{number: -1}
+	braced := i+1 < len(s) && s[i+1] == '{'
+	j := i + 1
+	if braced {
+		j++
+	}
+
+	// Tabstop number or variable name
+	k := j
+	for k < len(s) && s[k] >= '0' && s[k] <= '9' {
+		k++
+	}
+	if k > j {
+		node.number, _ = strconv.Atoi(s[j:k])
+	} else {
+		for k < len(s) && (s[k] == '_' || s[k] >= 'A' && s[k] <= 'Z' || s[k] >= 'a' && s[k] <= 'z' || k > j && s[k] >= '0' && s[k] <= '9') {
+			k++
+		}
+		if k == j {
+			return node, 0, false
+		}
+		node.name = s[j:k]
+	}
+	if !braced {
+		return node, k, true
+	}
+
+	switch {
+	case k < len(s) && s[k] == '}':
+		return node, k + 1, true
+	case k < len(s) && s[k] == ':':
+		children, end := parseSnippetNodes(s, k+1, true)
+		if end >= len(s) {
+			return node, 0, false
+		}
+		node.children = children
+		return node, end + 1, true
+	case k < len(s) && s[k] == '|' && node.name == "":
+		var choice strings.Builder
+		for k++; k < len(s); k++ {
+			switch {
+			case s[k] == '\\' && k+1 < len(s) && strings.IndexByte(`,|$}\`, s[k+1]) >= 0:
+				k++
+				choice.WriteByte(s[k])
+			case s[k] == ',':
+				node.choices = append(node.choices, choice.String())
+				choice.Reset()
+			case s[k] == '|' && k+1 < len(s) && s[k+1] == '}':
+				node.choices = append(node.choices, choice.String())
+				return node, k + 2, true
+			default:
+				choice.WriteByte(s[k])
+			}
+		}
+	}
+	return node, 0, false
+}
+
+// renderedRange is a tabstop occurrence in rendered snippet text
+type renderedRange struct {
+	number     int
+	start, end int // Byte offsets in the text
+	parent     int // Index of the enclosing range, -1 at the top level
+}
+
+// snippetRenderer turns parsed snippets into text
+type snippetRenderer struct {
+	b        strings.Builder
+	ranges   []renderedRange
+	parents  []int                 // Indexes of the recorded ranges being rendered
+	active   []int                 // Numbers of the tabstops being rendered
+	defaults map[int][]snippetNode // Placeholder text of each tabstop
+	choices  map[int][]string      // Choices of each tabstop
+	lineSep  string                // Inserted for line breaks, with the indentation
+	tab      string                // Inserted for tabs
+	variable func(name string) (string, bool)
+}
+
+// collect records the first placeholder text and choices of each tabstop so
+// that mirrors can repeat them
+func (r *snippetRenderer) collect(nodes []snippetNode) {
+	for _, node := range nodes {
+		if node.number < 0 {
+			r.collect(node.children)
+			continue
+		}
+		if _, ok := r.defaults[node.number]; !ok && len(node.children) > 0 {
+			r.defaults[node.number] = node.children
+		}
+		if _, ok := r.choices[node.number]; !ok && len(node.choices) > 0 {
+			r.choices[node.number] = node.choices
+		}
+		r.collect(node.children)
+	}
+}
+
+// render writes nodes, recording their tabstops unless they are part of a
+// mirror
+func (r *snippetRenderer) render(nodes []snippetNode, record bool) {
+	for _, node := range nodes {
+		switch {
+		case node.name != "":
+			value, known := r.variable(node.name)
+			switch {
+			case value != "":
+				r.write(value)
+			case len(node.children) > 0:
+				r.render(node.children, record)
+			case !known:
+				r.write(node.name)
+			}
+		case node.number >= 0:
+			r.renderTabstop(node, record)
+		default:
+			r.write(node.text)
+		}
+	}
+}
+
+func (r *snippetRenderer) renderTabstop(node snippetNode, record bool) {
+	if slices.Contains(r.active, node.number) {
+		// A tabstop inside its own placeholder
+		return
+	}
+	mirror := slices.ContainsFunc(r.ranges, func(rr renderedRange) bool { return rr.number == node.number })
+	r.active = append(r.active, node.number)
+	defer func() { r.active = r.active[:len(r.active)-1] }()
+
+	index := -1
+	if record {
+		parent := -1
+		if len(r.parents) > 0 {
+			parent = r.parents[len(r.parents)-1]
+		}
+		index = len(r.ranges)
+		r.ranges = append(r.ranges, renderedRange{number: node.number, start: r.b.Len(), parent: parent})
+		r.parents = append(r.parents, index)
+	}
+
+	// Only the first occurrence has tabstops of its own
+	children, nested := r.defaults[node.number], record && !mirror
+	if !mirror && len(node.children) > 0 {
+		children = node.children
+	}
+	if choices := r.choices[node.number]; len(children) == 0 && len(choices) > 0 {
+		r.write(choices[0])
+	} else {
+		r.render(children, nested)
+	}
+
+	if record {
+		r.parents = r.parents[:len(r.parents)-1]
+		r.ranges[index].end = r.b.Len()
+	}
+}
+
+func (r *snippetRenderer) write(text string) {
+	text = strings.ReplaceAll(text, "\n", r.lineSep)
+	r.b.WriteString(strings.ReplaceAll(text, "\t", r.tab))
+}
+
+// renderSnippet turns a snippet into text inserted on a line with the given
+// indentation and returns the text with its tabstop occurrences and the
+// choices of each tabstop
+func (m *editorModel) renderSnippet(snippet, indent string) (string, []renderedRange, map[int][]string) {
+	nodes := parseSnippet(snippet)
+	r := &snippetRenderer{
+		defaults: map[int][]snippetNode{},
+		choices:  map[int][]string{},
+		lineSep:  "\n" + indent,
+		tab:      "\t",
+		variable: m.snippetVariable,
+	}
+	if m.buffer.tabs.expandTab {
+		r.tab = strings.Repeat(" ", m.buffer.tabs.shift())
+	}
+	r.collect(nodes)
+	r.render(nodes, true)
+	return r.b.String(), r.ranges, r.choices
+}
+
+// snippetVariable returns the value of a snippet variable and whether the
+// variable is known
+func (m *editorModel) snippetVariable(name string) (string, bool) {
+	path := m.fileName()
+	switch name {
+	case "TM_FILENAME":
+		if path == "" {
+			return "", true
+		}
+		return filepath.Base(path), true
+	case "TM_FILENAME_BASE":
+		if path == "" {
+			return "", true
+		}
+		base := filepath.Base(path)
+		return strings.TrimSuffix(base, filepath.Ext(base)), true
+	case "TM_FILEPATH":
+		if path == "" {
+			return "", true
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return path, true
+		}
+		return abs, true
+	case "TM_DIRECTORY":
+		if path == "" {
+			return "", true
+		}
+		abs, err := filepath.Abs(filepath.Dir(path))
+		if err != nil {
+			return filepath.Dir(path), true
+		}
+		return abs, true
+	case "TM_LINE_INDEX":
+		return strconv.Itoa(m.cursor.Row), true
+	case "TM_LINE_NUMBER":
+		return strconv.Itoa(m.cursor.Row + 1), true
+	case "TM_SELECTED_TEXT":
+		return "", true
+	}
+	return "", false
+}
+
+// snippetRange is a tabstop occurrence in the buffer
+type snippetRange struct {
+	start, end Cursor // End is exclusive
+	order      int    // Position in the snippet, outer placeholders first
+	parent     *snippetRange
+}
+
+// encloses reports whether r is a placeholder that other is nested in
+func (r *snippetRange) encloses(other *snippetRange) bool {
+	for p := other.parent; p != nil; p = p.parent {
+		if p == r {
+			return true
+		}
+	}
+	return false
+}
+
+// snippetStop is a tabstop of an expanded snippet
+type snippetStop struct {
+	number  int
+	ranges  []*snippetRange // The first occurrence is edited, the others mirror it
+	choices []string
+}
+
+// snippetSession is an expanded snippet whose tabstops are being visited
+type snippetSession struct {
+	stops      []snippetStop   // Stops in visiting order, $0 last
+	ranges     []*snippetRange // Every occurrence in snippet order
+	current    int             // Index of the current stop
+	selecting  bool            // Whether the placeholder is selected, typing replaces it
+	start, end Cursor          // Extent of the snippet
+
+	text   string // Buffer text after the last key, to find what a key changed
+	cursor Cursor // Cursor after the last key
+}
+
+// shift moves the ranges over a change made in owner. Ranges that contain
+// the change grow; a range that touches the change keeps its text when it
+// comes before owner in the snippet.
+func (s *snippetSession) shift(c TextChange, owner *snippetRange) {
+	contains := func(start, end Cursor) bool {
+		return comparePositions(start, c.Start) <= 0 && comparePositions(c.End, end) <= 0
+	}
+	for _, r := range s.ranges {
+		if (r == owner || r.encloses(owner)) && contains(r.start, r.end) {
+			r.end = c.shiftPos(r.end)
+			continue
+		}
+		before := r.order < owner.order
+		r.start, r.end = shiftSnippetPos(c, r.start, before), shiftSnippetPos(c, r.end, before)
+	}
+	if contains(s.start, s.end) {
+		s.end = c.shiftPos(s.end)
+	} else {
+		s.start, s.end = c.shiftPos(s.start), c.shiftPos(s.end)
+	}
+}
+
+// shiftSnippetPos moves a position over a change. A position at the start
+// of the change stays there when it belongs before it.
+func shiftSnippetPos(c TextChange, pos Cursor, before bool) Cursor {
+	if before && pos == c.Start {
+		return pos
+	}
+	return c.shiftPos(pos)
+}
+
+// insertSnippet replaces the text from start to the cursor with a snippet
+// as a single undo step and selects its first tabstop
+func (m *editorModel) insertSnippet(start Cursor, snippet string) tea.Cmd {
+	line := m.buffer.Line(start.Row)
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	indent = indent[:min(len(indent), start.Col)]
+	text, rendered, choices := m.renderSnippet(snippet, indent)
+
+	m.completion = nil
+	m.buffer.saveUndoState(m.cursor)
+	end := m.buffer.replaceRange(start, m.cursor, text)
+
+	s := &snippetSession{start: start, end: end}
+	stops := map[int]*snippetStop{}
+	for i, rr := range rendered {
+		r := &snippetRange{
+			start: advanceCursor(start, text[:rr.start]),
+			end:   advanceCursor(start, text[:rr.end]),
+			order: i,
+		}
+		if rr.parent >= 0 {
+			r.parent = s.ranges[rr.parent]
+		}
+		s.ranges = append(s.ranges, r)
+		stop, ok := stops[rr.number]
+		if !ok {
+			stop = &snippetStop{number: rr.number}
+			stops[rr.number] = stop
+		}
+		stop.ranges = append(stop.ranges, r)
+	}
+	if _, ok := stops[0]; !ok {
+		r := &snippetRange{start: end, end: end, order: len(s.ranges)}
+		s.ranges = append(s.ranges, r)
+		stops[0] = &snippetStop{ranges: []*snippetRange{r}}
+	}
+	for _, n := range slices.Sorted(maps.Keys(stops)) {
+		if n > 0 {
+			s.stops = append(s.stops, *stops[n])
+		}
+	}
+	s.stops = append(s.stops, *stops[0])
+	for i := range s.stops {
+		s.stops[i].choices = choices[s.stops[i].number]
+	}
+
+	var cmd tea.Cmd
+	if m.mode != ModeInsert {
+		cmd = switchMode(m, ModeInsert)
+	}
+	m.snippet = s
+	m.jumpSnippet(0)
+	return cmd
+}
+
+// snippetSelectMessage is shown while a placeholder is selected
+const snippetSelectMessage = "-- SELECT --"
+
+// jumpSnippet moves to the stop at index i of the active snippet. Placeholder
+// text is selected, so that typing replaces it, and choices open the
+// completion menu. Reaching $0 ends the snippet.
+func (m *editorModel) jumpSnippet(i int) {
+	s := m.snippet
+	s.current = max(0, min(i, len(s.stops)-1))
+	m.completion = nil
+	stop := s.stops[s.current]
+	r := stop.ranges[0]
+	m.cursor = r.end
+	m.desiredCol = m.cursor.Col
+	m.ensureCursorVisible()
+
+	if s.current == len(s.stops)-1 {
+		m.endSnippet()
+		return
+	}
+	s.selecting = r.start != r.end
+	if s.selecting {
+		m.statusMessage = snippetSelectMessage
+	} else if m.statusMessage == snippetSelectMessage {
+		m.statusMessage = ""
+	}
+	if len(stop.choices) > 0 && r.start.Row == r.end.Row {
+		m.openChoices(r.start, stop.choices)
+	}
+	s.text, s.cursor = m.buffer.text(), m.cursor
+}
+
+// endSnippet stops visiting the tabstops of the active snippet
+func (m *editorModel) endSnippet() {
+	if m.snippet == nil {
+		return
+	}
+	m.snippet = nil
+	if m.statusMessage == snippetSelectMessage {
+		m.statusMessage = ""
+	}
+}
+
+// openChoices opens the completion menu with the choices of a tabstop
+// starting at start
+func (m *editorModel) openChoices(start Cursor, choices []string) {
+	m.nextCompletionID++
+	c := &completion{
+		request: CompletionRequest{
+			ID:     m.nextCompletionID,
+			Buffer: m.GetBuffer(),
+			Cursor: m.cursor,
+			Start:  start,
+			Prefix: m.buffer.Line(start.Row)[start.Col:m.cursor.Col],
+		},
+		choice:   true,
+		selected: -1,
+	}
+	for _, choice := range choices {
+		c.items = append(c.items, CompletionItem{Label: choice})
+	}
+	c.filter("")
+	c.typed = c.request.Prefix
+	c.selectMatch(0)
+	m.completion = c
+}
+
+// refreshSnippet follows the changes of a key press in the active snippet:
+// the current tabstop grows with the typed text, the other tabstops move
+// and mirrors are updated. Leaving insert mode or the snippet ends it.
+func (m *editorModel) refreshSnippet() {
+	s := m.snippet
+	if s == nil {
+		return
+	}
+	if m.mode != ModeInsert {
+		m.endSnippet()
+		return
+	}
+
+	text := m.buffer.text()
+	if text != s.text {
+		// Attribute ambiguous changes, such as typing a letter next to the
+		// same letter, to the cursor
+		limit := min(cursorToOffset(s.text, s.cursor), cursorToOffset(text, m.cursor))
+		if change, ok := diffTextAt(s.text, text, limit); ok {
+			s.shift(change, s.stops[s.current].ranges[0])
+			m.syncSnippetMirrors()
+		}
+	}
+
+	if comparePositions(m.cursor, s.start) < 0 || comparePositions(m.cursor, s.end) > 0 {
+		m.endSnippet()
+		return
+	}
+	s.text, s.cursor = m.buffer.text(), m.cursor
+}
+
+// syncSnippetMirrors copies the text of the current tabstop to its mirrors
+func (m *editorModel) syncSnippetMirrors() {
+	s := m.snippet
+	stop := s.stops[s.current]
+	primary := stop.ranges[0]
+	text := m.buffer.textBetween(primary.start, primary.end)
+	for _, r := range stop.ranges[1:] {
+		if m.buffer.textBetween(r.start, r.end) == text {
+			continue
+		}
+		start, end := r.start, r.end
+		change := TextChange{Start: start, End: end, NewEnd: m.buffer.replaceRange(start, end, text)}
+		s.shift(change, r)
+		m.cursor = shiftSnippetPos(change, m.cursor, primary.order < r.order)
+	}
+}
+
+// snippetSelectKey handles a key while a placeholder is selected and
+// reports whether the key was used. Typed text and <BS> replace the
+// placeholder; any other key keeps it.
+func (m *editorModel) snippetSelectKey(msg tea.KeyMsg) bool {
+	s := m.snippet
+	s.selecting = false
+	if m.statusMessage == snippetSelectMessage {
+		m.statusMessage = ""
+	}
+
+	key := msg.String()
+	deletes := key == "backspace" || key == "delete" || key == "ctrl+h"
+	if !deletes && (msg.Type != tea.KeyRunes && msg.Type != tea.KeySpace || msg.Paste) {
+		return false
+	}
+	r := s.stops[s.current].ranges[0]
+	m.buffer.saveUndoState(m.cursor)
+	m.buffer.replaceRange(r.start, r.end, "")
+	m.cursor = r.start
+	m.desiredCol = m.cursor.Col
+	return deletes
+}
+
+// registerSnippetBindings registers <S-Tab> in insert mode. <Tab> is
+// registered with the other insert mode keys.
+func registerSnippetBindings(m *editorModel) {
+	m.registry.Add("shift+tab", snippetPrev, ModeInsert, "Jump to the previous tabstop of a snippet")
+}
+
+// snippetTab jumps to the next tabstop of the active snippet, expands the
+// snippet whose trigger is before the cursor, or inserts a tab
+func snippetTab(m *editorModel) tea.Cmd {
+	if m.snippet != nil {
+		m.jumpSnippet(m.snippet.current + 1)
+		return nil
+	}
+	start := m.wordStart()
+	if body, ok := m.snippets[m.buffer.Line(start.Row)[start.Col:m.cursor.Col]]; ok && start != m.cursor {
+		return m.insertSnippet(start, body)
+	}
+	return handleInsertTab(m)
+}
+
+func snippetPrev(m *editorModel) tea.Cmd {
+	if m.snippet != nil {
+		m.jumpSnippet(m.snippet.current - 1)
+	}
+	return nil
+}
+
+// snippetSpans highlights the selected placeholder on a row
+func (m *editorModel) snippetSpans(row int) []Span {
+	s := m.snippet
+	if s == nil || !s.selecting {
+		return nil
+	}
+	r := s.stops[s.current].ranges[0]
+	if row < r.start.Row || row > r.end.Row {
+		return nil
+	}
+	start, end := 0, m.buffer.lineLength(row)
+	if row == r.start.Row {
+		start = r.start.Col
+	}
+	if row == r.end.Row {
+		end = r.end.Col
+	}
+	return []Span{{Start: start, End: end, Style: m.theme.Visual}}
+}
+
+// snippetItems returns the registered snippets as completion items
+func (m *editorModel) snippetItems() []CompletionItem {
+	var items []CompletionItem
+	for _, trigger := range slices.Sorted(maps.Keys(m.snippets)) {
+		body := m.snippets[trigger]
+		text, _, _ := m.renderSnippet(body, "")
+		items = append(items, CompletionItem{
+			Label:         trigger,
+			Kind:          "snippet",
+			Documentation: text,
+			InsertText:    body,
+			Snippet:       true,
+		})
+	}
+	return items
+}
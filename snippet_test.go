@@ -0,0 +1,122 @@
+package vimtea
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderSnippet(t *testing.T) {
+	model := NewEditor(WithFileName("main.go")).(*editorModel)
+	for snippet, want := range map[string]struct {
+		text  string
+		stops []string // Text of each occurrence as number:text
+	}{
+		"plain":                          {"plain", nil},
+		"f($1)$0":                        {"f()", []string{"1:", "0:"}},
+		"f(${1:x}, ${2:y})":              {"f(x, y)", []string{"1:x", "2:y"}},
+		"${1:a} = $1":                    {"a = a", []string{"1:a", "1:a"}},
+		"$1 = ${1:a}":                    {"a = a", []string{"1:a", "1:a"}},
+		"${1|GET,POST|} /":               {"GET /", []string{"1:GET"}},
+		`${1|a\,b,c\|d|}`:                {"a,b", []string{"1:a,b"}},
+		`cost \$5 ${1:a\}b}`:             {"cost $5 a}b", []string{"1:a}b"}},
+		"${1:outer ${2:inner}}!":         {"outer inner!", []string{"1:outer inner", "2:inner"}},
+		"${1:a $1}":                      {"a ", []string{"1:a "}},
+		"$TM_FILENAME ${TM_LINE_NUMBER}": {"main.go 1", nil},
+		"${UNKNOWN:fallback} $UNKNOWN":   {"fallback UNKNOWN", nil},
+		"unclosed ${1:x and $":           {"unclosed ${1:x and $", nil},
+	} {
+		text, ranges, _ := model.renderSnippet(snippet, "")
+		assert.Equal(t, want.text, text, snippet)
+		var stops []string
+		for _, r := range ranges {
+			stops = append(stops, string(rune('0'+r.number))+":"+text[r.start:r.end])
+		}
+		assert.Equal(t, want.stops, stops, snippet)
+	}
+}
+
+func TestInsertSnippet(t *testing.T) {
+	model := newWrapEditor("\tif ok {\n\t\t\n\t}", 40, 8)
+	model.cursor = Cursor{1, 2}
+
+	model.InsertSnippet("for ${1:i} := 0; $1 < ${2:n}; $1++ {\n\t$0\n}")
+	assert.Equal(t, "\tif ok {\n\t\tfor i := 0; i < n; i++ {\n\t\t\t\n\t\t}\n\t}", model.buffer.text(), "Continuation lines should keep the indentation")
+	assert.Equal(t, ModeInsert, model.mode)
+	assert.Equal(t, Cursor{1, 7}, model.cursor, "The cursor should be at the end of the first placeholder")
+	assert.Equal(t, "-- SELECT --", model.statusMessage)
+	assert.Equal(t, []Span{{Start: 6, End: 7, Style: model.theme.Visual}}, model.snippetSpans(1), "The placeholder should be highlighted")
+
+	sendKeys(model, "idx")
+	assert.Equal(t, "\t\tfor idx := 0; idx < n; idx++ {", model.buffer.Line(1), "Typing should replace the placeholder and update its mirrors")
+	assert.Equal(t, Cursor{1, 9}, model.cursor)
+	assert.Empty(t, model.statusMessage)
+
+	pressKey(model, tea.KeyTab)
+	assert.Equal(t, Cursor{1, 23}, model.cursor, "<Tab> should select the next placeholder")
+	pressKey(model, tea.KeyBackspace)
+	sendKeys(model, "len(s)")
+	pressKey(model, tea.KeyShiftTab)
+	assert.Equal(t, Cursor{1, 9}, model.cursor, "<S-Tab> should go back to the previous placeholder")
+	assert.True(t, model.snippet.selecting)
+	sendKeys(model, "j")
+	assert.Equal(t, "\t\tfor j := 0; j < len(s); j++ {", model.buffer.Line(1))
+
+	pressKey(model, tea.KeyTab)
+	pressKey(model, tea.KeyTab)
+	assert.Equal(t, Cursor{2, 3}, model.cursor, "The last <Tab> should move to $0")
+	assert.Nil(t, model.snippet, "Reaching $0 should end the snippet")
+	pressKey(model, tea.KeyTab)
+	assert.Equal(t, "\t\t\t\t", model.buffer.Line(2), "<Tab> should insert a tab again")
+}
+
+func TestSnippetUndo(t *testing.T) {
+	model := newWrapEditor("x", 40, 8)
+	sendKeys(model, "A")
+	sendKeys(model, " ")
+
+	model.InsertSnippet("(${1:a}, ${2:b})")
+	assert.Equal(t, "x (a, b)", model.buffer.text())
+	pressKey(model, tea.KeyEsc)
+	assert.Nil(t, model.snippet, "Leaving insert mode should end the snippet")
+
+	model.buffer.undo(model.cursor)()
+	assert.Equal(t, "x ", model.buffer.text(), "The expansion should be a single undo step")
+}
+
+func TestSnippetTrigger(t *testing.T) {
+	model := newWrapEditor("", 40, 8, WithSnippets(map[string]string{"fn": "func ${1:name}($2) {\n\t$0\n}"}))
+	model.AddSnippet("req", "${1|GET,POST,text/plain|} ${2:/}")
+
+	sendKeys(model, "ifn")
+	pressKey(model, tea.KeyTab)
+	assert.Equal(t, "func name() {\n\t\n}", model.buffer.text(), "<Tab> after a trigger should expand its snippet")
+	sendKeys(model, "main")
+	pressKey(model, tea.KeyTab)
+	assert.Equal(t, Cursor{0, 10}, model.cursor)
+	pressKey(model, tea.KeyTab)
+	assert.Equal(t, Cursor{1, 1}, model.cursor)
+
+	sendKeys(model, "req")
+	pressKey(model, tea.KeyTab)
+	assert.Equal(t, "\tGET /", model.buffer.Line(1))
+	require.NotNil(t, model.completion, "Choices should open the completion menu")
+	assert.Equal(t, []string{"    GET /", "}   GET", "    POST", "    text/plain"}, completionRows(model)[1:5])
+
+	pressKey(model, tea.KeyCtrlN)
+	pressKey(model, tea.KeyCtrlN)
+	pressKey(model, tea.KeyEnter)
+	assert.Equal(t, "\ttext/plain /", model.buffer.Line(1), "Accepting a choice should replace the tabstop")
+	require.NotNil(t, model.snippet)
+	pressKey(model, tea.KeyTab)
+	sendKeys(model, "/users")
+	assert.Equal(t, "\ttext/plain /users", model.buffer.Line(1))
+
+	sendKeys(model, " fn")
+	pressKey(model, tea.KeyCtrlN)
+	require.NotNil(t, model.completion)
+	item := model.completion.items[model.completion.matches[model.completion.selected]]
+	assert.Equal(t, CompletionItem{Label: "fn", Kind: "snippet", Documentation: "func name() {\n\t\n}", InsertText: "func ${1:name}($2) {\n\t$0\n}", Snippet: true}, item, "Snippets should be offered as completion items")
+}
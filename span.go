@@ -0,0 +1,176 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Span styles a range of a buffer line. Spans are drawn in layers: the
+// Normal and CursorLine groups of the theme first, then syntax highlighting,
+// then the spans of each SpanProvider in the order they were added, then
+// diagnostic underlines, yank highlights, the visual selection and the cursor.
+// A span only overrides the style properties it sets, so a selection
+// background keeps the syntax colors of the text below it.
+type Span struct {
+	Start int            // Byte offset of the first styled character
+	End   int            // Byte offset after the last styled character
+	Style lipgloss.Style // Style of the range
+}
+
+// SpanProvider supplies spans for buffer lines, for example semantic
+// highlights from an external analyzer
+type SpanProvider interface {
+	// Spans returns the spans of a line. It is called whenever the line is drawn.
+	Spans(b Buffer, row int) []Span
+}
+
+// SpanProviderFunc is a function that implements SpanProvider
+type SpanProviderFunc func(b Buffer, row int) []Span
+
+// Spans calls the function
+func (f SpanProviderFunc) Spans(b Buffer, row int) []Span {
+	return f(b, row)
+}
+
+// WithSpanProvider adds a provider of spans drawn over the syntax highlighting
+func WithSpanProvider(provider SpanProvider) EditorOption {
+	return func(o *options) {
+		o.SpanProviders = append(o.SpanProviders, provider)
+	}
+}
+
+// AddSpanProvider adds a provider of spans drawn over the syntax highlighting
+func (m *editorModel) AddSpanProvider(provider SpanProvider) {
+	m.spanProviders = append(m.spanProviders, provider)
+}
+
+// lineSpans collects the spans of a line from every layer, bottom first
+func (m *editorModel) lineSpans(line string, rowIdx int, inVisualSelection bool, selStart, selEnd Cursor) []Span {
+	var spans []Span
+	if styled(m.theme.Normal) {
+		spans = append(spans, Span{Start: 0, End: len(line), Style: m.theme.Normal})
+	}
+	if rowIdx == m.cursor.Row && styled(m.theme.CursorLine) {
+		spans = append(spans, Span{Start: 0, End: len(line), Style: m.theme.CursorLine})
+	}
+	if m.highlighter != nil {
+		spans = append(spans, m.highlighter.spans(m.buffer, rowIdx)...)
+	}
+	for _, provider := range m.spanProviders {
+		spans = append(spans, provider.Spans(m.GetBuffer(), rowIdx)...)
+	}
+	spans = append(spans, m.diagnosticSpans(rowIdx)...)
+	spans = append(spans, m.snippetSpans(rowIdx)...)
+
+	if m.mode != ModeVisual {
+		if start, end := m.getYankHighlightBounds(rowIdx); start >= 0 {
+			spans = append(spans, Span{Start: start, End: end, Style: m.theme.Yank})
+		}
+	}
+
+	if m.mode == ModeVisual && inVisualSelection {
+		start, end := 0, len(line)
+		if !m.isVisualLine {
+			if rowIdx == selStart.Row {
+				start = selStart.Col
+			}
+			if rowIdx == selEnd.Row {
+				end = selEnd.Col + 1
+			}
+		}
+		spans = append(spans, Span{Start: start, End: end, Style: m.theme.Visual})
+	}
+	return spans
+}
+
+// renderSpans renders a line with tabs expanded and the spans applied. The
+// cursor is drawn at the byte offset cursorCol unless it is negative.
+func (m *editorModel) renderSpans(line string, spans []Span, cursorCol int) string {
+	tabStop := m.buffer.tabs.tabStop
+
+	// Split the line where any span or the cursor starts or ends, so each
+	// piece has a single style
+	clamp := func(offset int) int {
+		offset = max(0, min(offset, len(line)))
+		for offset < len(line) && !utf8.RuneStart(line[offset]) {
+			offset++
+		}
+		return offset
+	}
+	spans = slices.Clone(spans)
+	cuts := []int{0, len(line)}
+	for i := range spans {
+		spans[i].Start, spans[i].End = clamp(spans[i].Start), clamp(spans[i].End)
+		cuts = append(cuts, spans[i].Start, spans[i].End)
+	}
+	if cursorCol >= 0 && cursorCol < len(line) {
+		cursorCol = clamp(cursorCol)
+		cuts = append(cuts, cursorCol, clamp(cursorCol+1))
+	}
+	slices.Sort(cuts)
+	cuts = slices.Compact(cuts)
+
+	var sb strings.Builder
+	visualCol := 0
+	for i := 0; i+1 < len(cuts); i++ {
+		start, end := cuts[i], cuts[i+1]
+		style, styled := composeSpans(spans, start, end)
+		text := renderTabsFrom(line[start:end], visualCol, tabStop)
+		visualCol += visualLength(line[start:end], visualCol, tabStop)
+
+		if start == cursorCol {
+			// A tab shows the cursor on its first cell
+			first, size := utf8.DecodeRuneInString(text)
+			sb.WriteString(m.cursorStyleOver(style).Render(string(first)))
+			text = text[size:]
+		}
+		if styled && text != "" {
+			text = style.Render(text)
+		}
+		sb.WriteString(text)
+	}
+
+	if cursorCol >= len(line) {
+		sb.WriteString(m.renderCursor(" "))
+	}
+	return sb.String()
+}
+
+// styled reports whether a style changes the text it renders
+func styled(style lipgloss.Style) bool {
+	return style.Render("x") != "x"
+}
+
+// composeSpans returns the style of the text from start to end, with every
+// span covering it applied over the ones before it
+func composeSpans(spans []Span, start, end int) (lipgloss.Style, bool) {
+	style := lipgloss.NewStyle()
+	styled := false
+	for _, span := range spans {
+		if span.Start <= start && span.End >= end {
+			style = span.Style.Inherit(style)
+			styled = true
+		}
+	}
+	return style, styled
+}
+
+// cursorStyleOver returns the style of the cursor drawn over text in style
+func (m *editorModel) cursorStyleOver(style lipgloss.Style) lipgloss.Style {
+	if !m.cursorBlink {
+		return style
+	}
+
+	switch m.mode {
+	case ModeInsert:
+		return lipgloss.NewStyle().Underline(true).Inherit(style)
+	case ModeCommand:
+		return style
+	default:
+		return m.theme.Cursor.Inherit(style)
+	}
+}
@@ -0,0 +1,79 @@
+package vimtea
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComposeSpans(t *testing.T) {
+	red := lipgloss.Color("#ff0000")
+	blue := lipgloss.Color("#0000ff")
+	spans := []Span{
+		{Start: 0, End: 4, Style: lipgloss.NewStyle().Foreground(red)},
+		{Start: 2, End: 6, Style: lipgloss.NewStyle().Background(blue)},
+	}
+
+	style, styled := composeSpans(spans, 2, 4)
+	assert.True(t, styled)
+	assert.Equal(t, red, style.GetForeground(), "Lower spans should keep the properties upper spans do not set")
+	assert.Equal(t, blue, style.GetBackground())
+
+	style, _ = composeSpans(spans, 4, 6)
+	assert.Equal(t, lipgloss.NoColor{}, style.GetForeground())
+
+	_, styled = composeSpans(spans, 6, 8)
+	assert.False(t, styled, "Text outside every span should not be styled")
+}
+
+func TestRenderSpans(t *testing.T) {
+	model := NewEditor(WithContent("a\tb世c")).(*editorModel)
+	line := model.buffer.Line(0)
+	spans := []Span{{Start: 0, End: 3, Style: lipgloss.NewStyle().Bold(true)}}
+
+	assert.Equal(t, "a   b世c", ansi.Strip(model.renderSpans(line, spans, -1)), "Tabs should be expanded across spans")
+	assert.Equal(t, "a   b世c", ansi.Strip(model.renderSpans(line, spans, 4)), "The cursor should not change the text")
+	assert.Equal(t, "a   b世c ", ansi.Strip(model.renderSpans(line, spans, len(line))), "A cursor after the line should add a cell")
+	assert.Equal(t, "a   b世c", ansi.Strip(model.renderSpans(line, []Span{{Start: 4, End: 5}}, -1)),
+		"Spans ending inside a character should not split it")
+}
+
+func TestSpanProviders(t *testing.T) {
+	semantic := lipgloss.NewStyle().Italic(true)
+	var rows []int
+	model := NewEditor(WithContent("package main\nvar x int"), WithFileName("main.go"),
+		WithSpanProvider(SpanProviderFunc(func(b Buffer, row int) []Span {
+			rows = append(rows, row)
+			return []Span{{Start: 4, End: 5, Style: semantic}}
+		})),
+	).(*editorModel)
+
+	spans := model.lineSpans(model.buffer.Line(1), 1, false, Cursor{}, Cursor{})
+	assert.Equal(t, []int{1}, rows, "Providers should be asked for the drawn row")
+	assert.Equal(t, Span{Start: 4, End: 5, Style: semantic}, spans[len(spans)-1], "Provider spans should be drawn over syntax spans")
+
+	style, _ := composeSpans(spans, 4, 5)
+	assert.True(t, style.GetItalic())
+	assert.NotEqual(t, lipgloss.NoColor{}, style.GetForeground(), "Syntax colors should stay below provider spans")
+
+	model.AddSpanProvider(SpanProviderFunc(func(b Buffer, row int) []Span { return nil }))
+	assert.Len(t, model.spanProviders, 2)
+}
+
+func TestSelectionSpans(t *testing.T) {
+	model := NewEditor(WithContent("package main"), WithFileName("main.go")).(*editorModel)
+	model.mode = ModeVisual
+	model.visualStart = Cursor{0, 0}
+	model.cursor = Cursor{0, 2}
+	selStart, selEnd := model.GetSelectionBoundary()
+
+	spans := model.lineSpans(model.buffer.Line(0), 0, true, selStart, selEnd)
+	assert.Equal(t, 0, spans[len(spans)-1].Start)
+	assert.Equal(t, 3, spans[len(spans)-1].End, "The selection should include the cursor character")
+
+	style, _ := composeSpans(spans, 0, 3)
+	assert.Equal(t, model.theme.Visual.GetBackground(), style.GetBackground())
+	assert.NotEqual(t, lipgloss.NoColor{}, style.GetForeground(), "Selected text should keep its syntax colors")
+}
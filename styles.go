@@ -19,6 +19,38 @@ var (
 				Background(lipgloss.AdaptiveColor{Light: "252", Dark: "236"}).
 				PaddingRight(1)
 
+	// signColumnStyle defines the appearance of the sign column
+	signColumnStyle = lipgloss.NewStyle()
+
+	// floatStyle defines the appearance of popups drawn over the text
+	floatStyle = lipgloss.NewStyle().
+			Background(lipgloss.AdaptiveColor{Light: "254", Dark: "235"})
+
+	// floatBorderStyle defines the appearance of the border of popups
+	floatBorderStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.AdaptiveColor{Light: "245", Dark: "242"})
+
+	// completionMenuStyle defines the appearance of the completion menu
+	completionMenuStyle = lipgloss.NewStyle().
+				Background(lipgloss.AdaptiveColor{Light: "254", Dark: "236"})
+
+	// completionSelectedStyle defines the appearance of the selected
+	// item of the completion menu
+	completionSelectedStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.AdaptiveColor{Light: "0", Dark: "15"}).
+				Background(lipgloss.AdaptiveColor{Light: "251", Dark: "240"}).
+				Bold(true)
+
+	// diagnosticStyles define the appearance of diagnostic signs, virtual
+	// text and messages by severity
+	diagnosticErrorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	diagnosticWarnStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	diagnosticInfoStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("4"))
+	diagnosticHintStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("6"))
+
+	// diagnosticUnderlineStyle defines the appearance of text with a diagnostic
+	diagnosticUnderlineStyle = lipgloss.NewStyle().Underline(true)
+
 	// textStyle defines the appearance of regular text in the editor
 	textStyle = lipgloss.NewStyle()
 
@@ -27,6 +59,28 @@ var (
 			Foreground(lipgloss.AdaptiveColor{Light: "7", Dark: "8"}).
 			Background(lipgloss.AdaptiveColor{Light: "8", Dark: "7"})
 
+	// statusInactiveStyle defines the appearance of the status lines of
+	// the windows that are not current
+	statusInactiveStyle = lipgloss.NewStyle().
+				Background(lipgloss.AdaptiveColor{Light: "252", Dark: "236"})
+
+	// windowSeparatorStyle defines the appearance of the separator between
+	// windows side by side
+	windowSeparatorStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.AdaptiveColor{Light: "245", Dark: "242"})
+
+	// tabLineStyle defines the appearance of the labels of the tab pages
+	// that are not current
+	tabLineStyle = lipgloss.NewStyle().
+			Background(lipgloss.AdaptiveColor{Light: "252", Dark: "236"})
+
+	// tabLineSelectedStyle defines the appearance of the label of the
+	// current tab page
+	tabLineSelectedStyle = lipgloss.NewStyle().Bold(true)
+
+	// tabLineFillStyle defines the appearance of the tabline after the labels
+	tabLineFillStyle = lipgloss.NewStyle().Reverse(true)
+
 	// cursorStyle defines the appearance of the cursor
 	cursorStyle = lipgloss.NewStyle().
 			Background(lipgloss.AdaptiveColor{Light: "252", Dark: "248"}).
@@ -41,4 +95,12 @@ var (
 	selectedStyle = lipgloss.NewStyle().Background(
 		lipgloss.AdaptiveColor{Light: "7", Dark: "8"},
 	)
+
+	// yankStyle defines the appearance of text that was just yanked
+	yankStyle = lipgloss.NewStyle().Background(lipgloss.Color("7"))
+
+	// nonTextStyle defines the appearance of markers that are not part of
+	// the text, such as showbreak
+	nonTextStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.AdaptiveColor{Light: "245", Dark: "242"})
 )
@@ -0,0 +1,475 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// TabInfo describes a tab page of the editor
+type TabInfo struct {
+	Number   int    // Position of the tab page, from 1
+	Windows  int    // Number of windows in the tab page
+	Buffer   int    // Number of the buffer shown in its current window
+	File     string // File of the buffer shown in its current window
+	Modified bool   // Whether a buffer shown in the tab page has unsaved changes
+}
+
+// tabPage is a layout of windows shown instead of those of the other tab
+// pages. The layout and window of the current tab page are kept in the
+// fields of the editor and stored back with storeTab.
+type tabPage struct {
+	layout *layoutNode // Windows of the tab page
+	win    *window     // Current window of the tab page
+}
+
+// storeTab saves the layout and window of the current tab page from the
+// fields of the editor
+func (m *editorModel) storeTab() {
+	m.tab.layout, m.tab.win = m.layout, m.win
+}
+
+// allWindows returns the windows of all tab pages, those of the current
+// tab page included
+func (m *editorModel) allWindows() []*window {
+	m.storeTab()
+	var wins []*window
+	for _, t := range m.tabPages {
+		wins = append(wins, t.layout.windows()...)
+	}
+	return wins
+}
+
+// tablineHeight returns the rows taken by the tabline, which is shown
+// while there are several tab pages
+func (m *editorModel) tablineHeight() int {
+	if len(m.tabPages) > 1 {
+		return 1
+	}
+	return 0
+}
+
+// enterTab makes a tab page current, restoring its windows
+func (m *editorModel) enterTab(t *tabPage) {
+	if t == m.tab {
+		return
+	}
+	if m.mode == ModeVisual {
+		m.mode = ModeNormal
+	}
+	m.endSnippet()
+	m.completion = nil
+	m.fireAutocmd(EventWinLeave, AutocmdArgs{})
+	m.fireAutocmd(EventTabLeave, AutocmdArgs{})
+	m.storeWindow()
+	m.storeTab()
+	m.tab = t
+	m.layout, m.win = t.layout, t.win
+	m.layoutWindows()
+	m.loadWindow(t.win)
+	m.ensureCursorVisible()
+	m.fireAutocmd(EventWinEnter, AutocmdArgs{})
+	m.fireAutocmd(EventTabEnter, AutocmdArgs{})
+}
+
+// newTab opens a tab page after the current one with a window on the
+// current buffer and makes it current
+func (m *editorModel) newTab() {
+	if !m.sharesArea() {
+		m.areaWidth, m.areaHeight = m.width, m.height
+	}
+	m.storeWindow()
+	m.nextWindowID++
+	w := &window{}
+	*w = *m.win
+	w.id = m.nextWindowID
+	w.node = &layoutNode{win: w}
+
+	t := &tabPage{layout: w.node, win: w}
+	i := slices.Index(m.tabPages, m.tab)
+	m.tabPages = slices.Insert(m.tabPages, i+1, t)
+	m.enterTab(t)
+}
+
+// tabAbandons reports whether closing a tab page would hide unsaved
+// changes that no window of another tab page shows
+func (m *editorModel) tabAbandons(t *tabPage) bool {
+	m.storeWindow()
+	shown := make(map[*document]bool)
+	for _, other := range m.allWindows() {
+		if other.node.root() != t.layout {
+			shown[other.doc] = true
+		}
+	}
+	for _, w := range t.layout.windows() {
+		if w.doc.modified() && !shown[w.doc] {
+			return true
+		}
+	}
+	return false
+}
+
+// root returns the top node of the layout holding a node
+func (n *layoutNode) root() *layoutNode {
+	for n.parent != nil {
+		n = n.parent
+	}
+	return n
+}
+
+// closeTab closes a tab page with its windows, keeping their buffers in
+// the buffer list. The last tab page cannot be closed.
+func (m *editorModel) closeTab(t *tabPage) error {
+	if len(m.tabPages) == 1 {
+		return errors.New("E784: Cannot close last tab page")
+	}
+	m.storeTab()
+	i := slices.Index(m.tabPages, t)
+	m.tabPages = slices.Delete(m.tabPages, i, i+1)
+	if t == m.tab {
+		m.enterTab(m.tabPages[min(i, len(m.tabPages)-1)])
+		return nil
+	}
+	// The tabline may have gone
+	m.storeWindow()
+	m.layoutWindows()
+	m.loadWindow(m.win)
+	return nil
+}
+
+// onlyTab closes the tab pages other than the current one
+func (m *editorModel) onlyTab(force bool) error {
+	if !force {
+		for _, t := range m.tabPages {
+			if t != m.tab && m.tabAbandons(t) {
+				return errors.New("E445: Other window contains changes")
+			}
+		}
+	}
+	m.storeWindow()
+	m.tabPages = []*tabPage{m.tab}
+	m.layoutWindows()
+	m.loadWindow(m.win)
+	return nil
+}
+
+// cycleTab enters the tab page count places after the current one,
+// before it when count is negative
+func (m *editorModel) cycleTab(count int) {
+	i := slices.Index(m.tabPages, m.tab)
+	n := len(m.tabPages)
+	m.enterTab(m.tabPages[((i+count)%n+n)%n])
+}
+
+// tabNumber returns the tab page at a position counted from 1
+func (m *editorModel) tabNumber(n int) (*tabPage, error) {
+	if n < 1 || n > len(m.tabPages) {
+		return nil, fmt.Errorf("E475: Invalid argument: %d", n)
+	}
+	return m.tabPages[n-1], nil
+}
+
+// tabArg returns the tab page named by a command argument, the current
+// one without argument
+func (m *editorModel) tabArg(args []string) (*tabPage, error) {
+	if len(args) == 0 {
+		return m.tab, nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, errors.New("E475: Invalid argument: " + args[0])
+	}
+	return m.tabNumber(n)
+}
+
+// label returns the label of a tab page in the tabline: the number of
+// its windows when there are several, "+" when one of its buffers has
+// unsaved changes and the name of the file of its current window
+func (t *tabPage) label() string {
+	wins := t.layout.windows()
+	label := ""
+	if len(wins) > 1 {
+		label = strconv.Itoa(len(wins))
+	}
+	for _, w := range wins {
+		if w.doc.modified() {
+			label += "+"
+			break
+		}
+	}
+	if label != "" {
+		label += " "
+	}
+	name := "[No Name]"
+	if t.win.doc.filePath != "" {
+		name = filepath.Base(t.win.doc.filePath)
+	}
+	return " " + label + name + " "
+}
+
+// renderTabline renders the labels of the tab pages, highlighting the
+// current one
+func (m *editorModel) renderTabline() string {
+	m.storeWindow()
+	m.storeTab()
+	var sb strings.Builder
+	for _, t := range m.tabPages {
+		style := m.theme.TabLine
+		if t == m.tab {
+			style = m.theme.TabLineSel
+		}
+		sb.WriteString(style.Render(t.label()))
+	}
+	line := ansi.Truncate(sb.String(), m.areaWidth, "")
+	fill := max(0, m.areaWidth-ansi.StringWidth(line))
+	return line + m.theme.TabLineFill.Render(strings.Repeat(" ", fill))
+}
+
+// tabAt returns the tab page whose label is at a column of the tabline,
+// or nil
+func (m *editorModel) tabAt(col int) *tabPage {
+	m.storeWindow()
+	m.storeTab()
+	x := 0
+	for _, t := range m.tabPages {
+		x += ansi.StringWidth(t.label())
+		if col < x {
+			return t
+		}
+	}
+	return nil
+}
+
+// clickTabline enters the tab page whose label is clicked at a column of
+// the tabline
+func (m *editorModel) clickTabline(col int) {
+	if col >= m.areaWidth {
+		return
+	}
+	if t := m.tabAt(col); t != nil {
+		m.enterTab(t)
+	}
+}
+
+// NewTab opens a tab page after the current one and makes it current. It
+// shows the buffer of a file, read into a new buffer when no buffer has it
+// yet, or a new empty buffer when path is empty.
+func (m *editorModel) NewTab(path string) error {
+	m.newTab()
+	if path == "" {
+		m.showDocument(m.newDocument(""))
+		return nil
+	}
+	return m.openFile(path)
+}
+
+// Tabs returns the tab pages in the order of the tabline
+func (m *editorModel) Tabs() []TabInfo {
+	m.storeWindow()
+	m.storeTab()
+	infos := make([]TabInfo, 0, len(m.tabPages))
+	for i, t := range m.tabPages {
+		info := TabInfo{
+			Number: i + 1,
+			Buffer: t.win.doc.number,
+			File:   t.win.doc.filePath,
+		}
+		for _, w := range t.layout.windows() {
+			info.Windows++
+			info.Modified = info.Modified || w.doc.modified()
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// CurrentTab returns the position of the current tab page, from 1
+func (m *editorModel) CurrentTab() int {
+	return slices.Index(m.tabPages, m.tab) + 1
+}
+
+// SetCurrentTab makes the tab page at a position, from 1, current
+func (m *editorModel) SetCurrentTab(n int) error {
+	t, err := m.tabNumber(n)
+	if err != nil {
+		return err
+	}
+	m.enterTab(t)
+	return nil
+}
+
+// CloseTab closes the tab page at a position, from 1. Unsaved changes
+// that no other tab page shows are only hidden when force is set; the
+// buffers stay in the buffer list.
+func (m *editorModel) CloseTab(n int, force bool) error {
+	t, err := m.tabNumber(n)
+	if err != nil {
+		return err
+	}
+	if !force && len(m.tabPages) > 1 && m.tabAbandons(t) {
+		return errors.New("E37: No write since last change (add ! to override)")
+	}
+	return m.closeTab(t)
+}
+
+// registerTabCommands registers the commands and key bindings of tab pages
+func registerTabCommands(m *editorModel) {
+	for _, name := range []string{"tabnew", "tabe", "tabedit"} {
+		m.commands.Register(name, tabNewCommand)
+	}
+	for _, name := range []string{"tabc", "tabclose"} {
+		m.commands.Register(name, tabCloseCommand)
+	}
+	for _, name := range []string{"tabo", "tabonly"} {
+		m.commands.Register(name, tabOnlyCommand)
+	}
+	for _, name := range []string{"tabn", "tabnext"} {
+		m.commands.Register(name, tabNextCommand)
+	}
+	for _, name := range []string{"tabp", "tabprevious", "tabN", "tabNext"} {
+		m.commands.Register(name, tabPreviousCommand)
+	}
+	for _, name := range []string{"tabfir", "tabfirst", "tabr", "tabrewind"} {
+		m.commands.Register(name, tabGoCommand(1))
+	}
+	for _, name := range []string{"tabl", "tablast"} {
+		m.commands.Register(name, tabGoCommand(-1))
+	}
+	m.commands.Register("tabs", listTabs)
+
+	m.registry.Add("gt", tabNext, ModeNormal, "Go to the next tab page, or tab page N")
+	m.registry.Add("ctrl+pgdown", tabNext, ModeNormal, "Go to the next tab page, or tab page N")
+	m.registry.Add("gT", tabPrevious, ModeNormal, "Go to the previous tab page, or N tab pages back")
+	m.registry.Add("ctrl+pgup", tabPrevious, ModeNormal, "Go to the previous tab page, or N tab pages back")
+}
+
+// tabNewCommand implements :tabnew [file] and :tabe[dit] [file]
+func tabNewCommand(model *editorModel) tea.Cmd {
+	_, args := model.commandLine()
+	path := ""
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if err := model.NewTab(path); err != nil {
+		return SetStatusMsg(err.Error())
+	}
+	return nil
+}
+
+// tabCloseCommand implements :tabc[lose][!] [N]
+func tabCloseCommand(model *editorModel) tea.Cmd {
+	bang, args := model.commandLine()
+	t, err := model.tabArg(args)
+	if err == nil {
+		err = model.CloseTab(slices.Index(model.tabPages, t)+1, bang)
+	}
+	if err != nil {
+		return SetStatusMsg(err.Error())
+	}
+	return nil
+}
+
+// tabOnlyCommand implements :tabo[nly][!]
+func tabOnlyCommand(model *editorModel) tea.Cmd {
+	bang, _ := model.commandLine()
+	if err := model.onlyTab(bang); err != nil {
+		return SetStatusMsg(err.Error())
+	}
+	return nil
+}
+
+// tabNextCommand implements :tabn[ext] [N], going to tab page N with an
+// argument
+func tabNextCommand(model *editorModel) tea.Cmd {
+	_, args := model.commandLine()
+	if len(args) == 0 {
+		model.cycleTab(1)
+		return nil
+	}
+	t, err := model.tabArg(args)
+	if err != nil {
+		return SetStatusMsg(err.Error())
+	}
+	model.enterTab(t)
+	return nil
+}
+
+// tabPreviousCommand implements :tabp[revious] [N], going N tab pages back
+func tabPreviousCommand(model *editorModel) tea.Cmd {
+	_, args := model.commandLine()
+	count := 1
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return SetStatusMsg("E475: Invalid argument: " + args[0])
+		}
+		count = n
+	}
+	model.cycleTab(-count)
+	return nil
+}
+
+// tabGoCommand returns a command that goes to the first tab page, or to
+// the last one when n is negative
+func tabGoCommand(n int) Command {
+	return func(model *editorModel) tea.Cmd {
+		i := n - 1
+		if n < 0 {
+			i = len(model.tabPages) - 1
+		}
+		model.enterTab(model.tabPages[i])
+		return nil
+	}
+}
+
+// listTabs implements :tabs, listing the windows of each tab page with >
+// for the current window and + for unsaved changes
+func listTabs(model *editorModel) tea.Cmd {
+	model.storeWindow()
+	model.storeTab()
+	var lines []string
+	for i, t := range model.tabPages {
+		lines = append(lines, fmt.Sprintf("Tab page %d", i+1))
+		for _, w := range t.layout.windows() {
+			flags := []byte("   ")
+			if w == model.win {
+				flags[0] = '>'
+			}
+			if w.doc.modified() {
+				flags[1] = '+'
+			}
+			lines = append(lines, string(flags)+" "+w.doc.name())
+		}
+	}
+	model.showPopup(lines, false)
+	return nil
+}
+
+// tabNext implements gt, going to tab page N with a count
+func tabNext(model *editorModel) tea.Cmd {
+	count := model.countPrefix
+	model.countPrefix = 1
+	if count > 1 {
+		if t, err := model.tabNumber(count); err == nil {
+			model.enterTab(t)
+		}
+		return nil
+	}
+	model.cycleTab(1)
+	return nil
+}
+
+// tabPrevious implements gT, going N tab pages back with a count
+func tabPrevious(model *editorModel) tea.Cmd {
+	count := model.countPrefix
+	model.countPrefix = 1
+	model.cycleTab(-count)
+	return nil
+}
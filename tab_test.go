@@ -0,0 +1,109 @@
+package vimtea
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTabPages(t *testing.T) {
+	fsys := NewMemFileSystem(map[string]string{"a.txt": "alpha", "dir/b.txt": "beta"})
+	model := NewEditor(WithFile("a.txt"), WithFileSystem(fsys)).(*editorModel)
+	model.showNumbers = false
+	model.SetSize(30, 6)
+	require.Len(t, strings.Split(model.View(), "\n"), 6)
+
+	runCommand(t, model, "tabnew dir/b.txt")
+	assert.Equal(t, 2, model.CurrentTab(), ":tabnew should open a tab page after the current one")
+	assert.Equal(t, []string{
+		" a.txt  b.txt",
+		"beta",
+		"",
+		"",
+	}, windowRows(model), "The tabline should take the top row of the editor")
+	assert.Equal(t, 3, model.height)
+	assert.Len(t, strings.Split(model.View(), "\n"), 6, "The tabline should not change the height of the editor")
+
+	runCommand(t, model, "vsplit")
+	sendKeys(model, "x")
+	assert.Equal(t, " a.txt  2+ b.txt", windowRows(model)[0],
+		"Labels should show the number of windows and unsaved changes")
+
+	sendKeys(model, "gt")
+	assert.Equal(t, 1, model.CurrentTab(), "gt should wrap around to the first tab page")
+	assert.Equal(t, "alpha", model.buffer.text())
+	sendKeys(model, "2gt")
+	assert.Equal(t, 2, model.CurrentTab(), "A count should go to that tab page")
+	assert.Len(t, model.Windows(), 2, "The tab page should keep its windows")
+
+	runCommand(t, model, "tabnew")
+	assert.Equal(t, []string{" a.txt  2+ b.txt  [No Name]"}, windowRows(model)[:1])
+	sendKeys(model, "2gT")
+	assert.Equal(t, 1, model.CurrentTab(), "A count should go back that many tab pages")
+	runCommand(t, model, "tablast")
+	assert.Equal(t, 3, model.CurrentTab())
+
+	msgs := runCommand(t, model, "tabclose 2")
+	assert.Contains(t, msgs, statusMessageMsg("E37: No write since last change (add ! to override)"))
+	runCommand(t, model, "tabclose! 2")
+	assert.Equal(t, []TabInfo{
+		{Number: 1, Windows: 1, Buffer: 1, File: "a.txt"},
+		{Number: 2, Windows: 1, Buffer: 3},
+	}, model.Tabs())
+	assert.True(t, model.Buffers()[1].Modified, "Closing a tab page should keep its buffers")
+
+	runCommand(t, model, "q")
+	assert.Equal(t, 1, model.CurrentTab(), ":q in the last window of a tab page should close it")
+	assert.Len(t, model.Tabs(), 1)
+	assert.Equal(t, []string{"alpha"}, windowRows(model)[:1], "The tabline should go with the last but one tab page")
+	assert.Equal(t, 4, model.height)
+
+	msgs = runCommand(t, model, "tabclose")
+	assert.Contains(t, msgs, statusMessageMsg("E784: Cannot close last tab page"))
+}
+
+func TestTabLineClick(t *testing.T) {
+	model := newWrapEditor("one", 30, 6)
+	require.NoError(t, model.NewTab(""))
+	require.NoError(t, model.NewTab(""))
+	assert.Equal(t, 3, model.CurrentTab())
+
+	click := func(x, y int) {
+		model.Update(tea.MouseMsg{X: x, Y: y, Action: tea.MouseActionPress, Button: tea.MouseButtonLeft})
+	}
+	click(12, 0)
+	assert.Equal(t, 2, model.CurrentTab(), "Clicking a label should go to its tab page")
+	click(1, 0)
+	assert.Equal(t, 1, model.CurrentTab())
+	click(12, 2)
+	assert.Equal(t, 1, model.CurrentTab(), "Clicks below the tabline should not go to a tab page")
+}
+
+func TestTabAPI(t *testing.T) {
+	model := newWrapEditor("one\ntwo", 20, 6)
+	var events []string
+	model.AddAutocmd(EventTabEnter, "", func(Buffer, AutocmdArgs) tea.Cmd {
+		events = append(events, "enter")
+		return nil
+	})
+	model.AddAutocmd(EventTabLeave, "", func(Buffer, AutocmdArgs) tea.Cmd {
+		events = append(events, "leave")
+		return nil
+	})
+
+	model.SetCursor(Cursor{1, 1})
+	require.NoError(t, model.NewTab(""))
+	assert.Equal(t, []string{"leave", "enter"}, events)
+	assert.Equal(t, "", model.buffer.text(), "NewTab without a path should show an empty buffer")
+
+	require.NoError(t, model.SetCurrentTab(1))
+	assert.Equal(t, Cursor{1, 1}, model.cursor, "The tab page should keep its cursor")
+	assert.Error(t, model.SetCurrentTab(3), "Unknown tab pages should be reported")
+
+	require.NoError(t, model.CloseTab(2, false))
+	assert.Len(t, model.Tabs(), 1)
+	assert.Error(t, model.CloseTab(1, true), "The last tab page cannot be closed")
+}
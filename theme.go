@@ -0,0 +1,377 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/styles"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// defaultSyntaxStyle is the chroma style of the default theme
+const defaultSyntaxStyle = "catppuccin-macchiato"
+
+// Theme holds all colors of the editor: the styles of the UI highlight
+// groups, named after their Vim counterparts, and the syntax palette
+type Theme struct {
+	Name         string         // Name used by :colorscheme
+	Normal       lipgloss.Style // Text
+	CursorLine   lipgloss.Style // Text of the cursor line
+	Cursor       lipgloss.Style // Cursor in normal and visual mode
+	Visual       lipgloss.Style // Visual mode selection
+	Yank         lipgloss.Style // Text that was just yanked
+	LineNr       lipgloss.Style // Line numbers
+	CursorLineNr lipgloss.Style // Line number of the cursor line
+	SignColumn   lipgloss.Style // Sign column, under the styles of the signs
+	NonText      lipgloss.Style // Markers that are not part of the text, such as showbreak
+	NormalFloat  lipgloss.Style // Text of popups
+	FloatBorder  lipgloss.Style // Border of popups
+	Pmenu        lipgloss.Style // Items of the completion menu
+	PmenuSel     lipgloss.Style // Selected item of the completion menu
+	StatusLine   lipgloss.Style // Status bar, and status line of the current window
+	StatusLineNC lipgloss.Style // Status lines of the other windows
+	WinSeparator lipgloss.Style // Separator between windows side by side
+	TabLine      lipgloss.Style // Labels of the tab pages that are not current
+	TabLineSel   lipgloss.Style // Label of the current tab page
+	TabLineFill  lipgloss.Style // Tabline after the labels
+	CommandLine  lipgloss.Style // Command line input
+	Syntax       *chroma.Style  // Syntax colors
+
+	DiagnosticError          lipgloss.Style // Signs, virtual text and messages of errors
+	DiagnosticWarn           lipgloss.Style // Signs, virtual text and messages of warnings
+	DiagnosticInfo           lipgloss.Style // Signs, virtual text and messages of information
+	DiagnosticHint           lipgloss.Style // Signs, virtual text and messages of hints
+	DiagnosticUnderlineError lipgloss.Style // Text with an error
+	DiagnosticUnderlineWarn  lipgloss.Style // Text with a warning
+	DiagnosticUnderlineInfo  lipgloss.Style // Text with information
+	DiagnosticUnderlineHint  lipgloss.Style // Text with a hint
+}
+
+// themes holds the themes registered with RegisterTheme by name
+var (
+	themesMu sync.RWMutex
+	themes   = map[string]Theme{}
+)
+
+// DefaultTheme returns the theme used when no other theme is set
+func DefaultTheme() Theme {
+	return Theme{
+		Name:         "default",
+		Normal:       textStyle,
+		Cursor:       cursorStyle,
+		Visual:       selectedStyle,
+		Yank:         yankStyle,
+		LineNr:       lineNumberStyle,
+		CursorLineNr: currentLineNumberStyle,
+		SignColumn:   signColumnStyle,
+		NonText:      nonTextStyle,
+		NormalFloat:  floatStyle,
+		FloatBorder:  floatBorderStyle,
+		Pmenu:        completionMenuStyle,
+		PmenuSel:     completionSelectedStyle,
+		StatusLine:   statusStyle,
+		StatusLineNC: statusInactiveStyle,
+		WinSeparator: windowSeparatorStyle,
+		TabLine:      tabLineStyle,
+		TabLineSel:   tabLineSelectedStyle,
+		TabLineFill:  tabLineFillStyle,
+		CommandLine:  commandStyle,
+		Syntax:       styles.Get(defaultSyntaxStyle),
+
+		DiagnosticError:          diagnosticErrorStyle,
+		DiagnosticWarn:           diagnosticWarnStyle,
+		DiagnosticInfo:           diagnosticInfoStyle,
+		DiagnosticHint:           diagnosticHintStyle,
+		DiagnosticUnderlineError: diagnosticUnderlineStyle,
+		DiagnosticUnderlineWarn:  diagnosticUnderlineStyle,
+		DiagnosticUnderlineInfo:  diagnosticUnderlineStyle,
+		DiagnosticUnderlineHint:  diagnosticUnderlineStyle,
+	}
+}
+
+// ThemeFromStyle derives a theme from a chroma style, taking the UI colors
+// from its background, line number and line highlight entries. A nil style
+// uses the chroma fallback style.
+func ThemeFromStyle(style *chroma.Style) Theme {
+	if style == nil {
+		style = styles.Fallback
+	}
+	theme := DefaultTheme()
+	theme.Name = style.Name
+	theme.Syntax = style
+
+	text := style.Get(chroma.Text)
+	background := style.Get(chroma.Background).Background
+	lineNumbers := style.Get(chroma.LineNumbers).Colour
+	lineHighlight := style.Get(chroma.LineHighlight).Background
+	keyword := style.Get(chroma.Keyword).Colour
+
+	theme.Normal = themeColors(lipgloss.NewStyle(), text.Colour, background)
+	theme.Cursor = themeColors(lipgloss.NewStyle(), background, text.Colour)
+	theme.Visual = themeColors(lipgloss.NewStyle(), 0, lineHighlight)
+	theme.Yank = themeColors(lipgloss.NewStyle(), background, keyword)
+	theme.LineNr = themeColors(lineNumberStyle, lineNumbers, 0)
+	theme.CursorLineNr = themeColors(currentLineNumberStyle, text.Colour, lineHighlight)
+	theme.SignColumn = themeColors(signColumnStyle, 0, style.Get(chroma.LineNumbers).Background)
+	theme.NonText = themeColors(nonTextStyle, lineNumbers, 0)
+	theme.NormalFloat = themeColors(floatStyle, text.Colour, lineHighlight)
+	theme.FloatBorder = themeColors(floatBorderStyle, lineNumbers, 0)
+	theme.Pmenu = themeColors(completionMenuStyle, text.Colour, lineHighlight)
+	theme.PmenuSel = themeColors(completionSelectedStyle, background, keyword)
+	theme.StatusLine = themeColors(statusStyle, background, text.Colour)
+	theme.StatusLineNC = themeColors(statusInactiveStyle, text.Colour, lineHighlight)
+	theme.WinSeparator = themeColors(windowSeparatorStyle, lineNumbers, 0)
+	theme.TabLine = themeColors(tabLineStyle, text.Colour, lineHighlight)
+	theme.TabLineSel = themeColors(tabLineSelectedStyle, text.Colour, background)
+	theme.TabLineFill = themeColors(tabLineFillStyle, lineNumbers, 0)
+	theme.DiagnosticError = themeColors(diagnosticErrorStyle, style.Get(chroma.Error).Colour, 0)
+	theme.CommandLine = themeColors(commandStyle, keyword, 0)
+	return theme
+}
+
+// themeColors sets the colors of style that are set in the chroma style
+func themeColors(style lipgloss.Style, fg, bg chroma.Colour) lipgloss.Style {
+	if fg.IsSet() {
+		style = style.Foreground(lipgloss.Color(fg.String()))
+	}
+	if bg.IsSet() {
+		style = style.Background(lipgloss.Color(bg.String()))
+	}
+	return style
+}
+
+// groups returns the UI highlight groups by name
+func (t *Theme) groups() map[string]*lipgloss.Style {
+	return map[string]*lipgloss.Style{
+		"Normal":       &t.Normal,
+		"CursorLine":   &t.CursorLine,
+		"Cursor":       &t.Cursor,
+		"Visual":       &t.Visual,
+		"Yank":         &t.Yank,
+		"LineNr":       &t.LineNr,
+		"CursorLineNr": &t.CursorLineNr,
+		"SignColumn":   &t.SignColumn,
+		"NonText":      &t.NonText,
+		"NormalFloat":  &t.NormalFloat,
+		"FloatBorder":  &t.FloatBorder,
+		"Pmenu":        &t.Pmenu,
+		"PmenuSel":     &t.PmenuSel,
+		"StatusLine":   &t.StatusLine,
+		"StatusLineNC": &t.StatusLineNC,
+		"WinSeparator": &t.WinSeparator,
+		"TabLine":      &t.TabLine,
+		"TabLineSel":   &t.TabLineSel,
+		"TabLineFill":  &t.TabLineFill,
+		"CommandLine":  &t.CommandLine,
+
+		"DiagnosticError":          &t.DiagnosticError,
+		"DiagnosticWarn":           &t.DiagnosticWarn,
+		"DiagnosticInfo":           &t.DiagnosticInfo,
+		"DiagnosticHint":           &t.DiagnosticHint,
+		"DiagnosticUnderlineError": &t.DiagnosticUnderlineError,
+		"DiagnosticUnderlineWarn":  &t.DiagnosticUnderlineWarn,
+		"DiagnosticUnderlineInfo":  &t.DiagnosticUnderlineInfo,
+		"DiagnosticUnderlineHint":  &t.DiagnosticUnderlineHint,
+	}
+}
+
+// themeFile is the JSON format of a theme
+type themeFile struct {
+	Name    string                `json:"name"`
+	Syntax  string                `json:"syntax"`  // Chroma style the theme starts from
+	Groups  map[string]themeGroup `json:"groups"`  // UI highlight groups by name
+	Palette map[string]string     `json:"palette"` // Chroma style entries by token type, e.g. "bold #ff0000"
+}
+
+// themeGroup is a UI highlight group in a theme file
+type themeGroup struct {
+	Fg        string `json:"fg"`
+	Bg        string `json:"bg"`
+	Bold      bool   `json:"bold"`
+	Italic    bool   `json:"italic"`
+	Underline bool   `json:"underline"`
+	Reverse   bool   `json:"reverse"`
+}
+
+// ParseTheme reads a theme from JSON or from a chroma style in XML.
+//
+// A JSON theme starts from the default theme, or from the theme derived from
+// the chroma style named by "syntax". "groups" overrides the properties of UI
+// highlight groups and "palette" the syntax colors of token types:
+//
+//	{
+//	  "name": "midnight",
+//	  "syntax": "dracula",
+//	  "groups": {"Visual": {"bg": "#44475a"}, "LineNr": {"fg": "#6272a4"}},
+//	  "palette": {"Comment": "italic #6272a4"}
+//	}
+func ParseTheme(data []byte) (Theme, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		style, err := chroma.NewXMLStyle(bytes.NewReader(data))
+		if err != nil {
+			return Theme{}, fmt.Errorf("invalid style: %w", err)
+		}
+		return ThemeFromStyle(style), nil
+	}
+
+	var file themeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return Theme{}, fmt.Errorf("invalid theme: %w", err)
+	}
+
+	theme := DefaultTheme()
+	if file.Syntax != "" {
+		style, ok := styles.Registry[file.Syntax]
+		if !ok {
+			return Theme{}, fmt.Errorf("unknown syntax style: %s", file.Syntax)
+		}
+		theme = ThemeFromStyle(style)
+	}
+	if file.Name != "" {
+		theme.Name = file.Name
+	}
+
+	groups := theme.groups()
+	for name, group := range file.Groups {
+		style, ok := groups[name]
+		if !ok {
+			return Theme{}, fmt.Errorf("unknown highlight group: %s", name)
+		}
+		*style = group.apply(*style)
+	}
+
+	if len(file.Palette) > 0 {
+		builder := theme.Syntax.Builder()
+		for name, entry := range file.Palette {
+			tokenType, err := chroma.TokenTypeString(name)
+			if err != nil {
+				return Theme{}, fmt.Errorf("unknown token type: %s", name)
+			}
+			builder.Add(tokenType, entry)
+		}
+		style, err := builder.Build()
+		if err != nil {
+			return Theme{}, fmt.Errorf("invalid palette: %w", err)
+		}
+		theme.Syntax = style
+	}
+	return theme, nil
+}
+
+// apply sets the properties of the group on style
+func (g themeGroup) apply(style lipgloss.Style) lipgloss.Style {
+	if g.Fg != "" {
+		style = style.Foreground(lipgloss.Color(g.Fg))
+	}
+	if g.Bg != "" {
+		style = style.Background(lipgloss.Color(g.Bg))
+	}
+	if g.Bold {
+		style = style.Bold(true)
+	}
+	if g.Italic {
+		style = style.Italic(true)
+	}
+	if g.Underline {
+		style = style.Underline(true)
+	}
+	if g.Reverse {
+		style = style.Reverse(true)
+	}
+	return style
+}
+
+// LoadTheme reads a theme file in JSON or chroma XML format. Themes without
+// a name are named after the file.
+func LoadTheme(path string) (Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Theme{}, err
+	}
+	theme, err := ParseTheme(data)
+	if err != nil {
+		return Theme{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if theme.Name == "" || theme.Name == "default" {
+		theme.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return theme, nil
+}
+
+// RegisterTheme makes a theme available to :colorscheme in all editors.
+// It is safe to call from several goroutines.
+func RegisterTheme(theme Theme) {
+	themesMu.Lock()
+	defer themesMu.Unlock()
+	themes[theme.Name] = theme
+}
+
+// lookupTheme finds a theme by name, deriving one from a chroma style if no
+// theme of that name is registered
+func lookupTheme(name string) (Theme, bool) {
+	themesMu.RLock()
+	theme, ok := themes[name]
+	themesMu.RUnlock()
+	if ok {
+		return theme, true
+	}
+	if name == "default" {
+		return DefaultTheme(), true
+	}
+	if style, ok := styles.Registry[name]; ok {
+		return ThemeFromStyle(style), true
+	}
+	return Theme{}, false
+}
+
+// WithTheme sets the colors of the editor. Style options such as
+// WithCursorStyle change a single group of the theme set before them.
+func WithTheme(theme Theme) EditorOption {
+	return func(o *options) {
+		o.Theme = theme
+	}
+}
+
+// SetTheme switches the colors of the editor. A theme without a syntax
+// style keeps the current syntax colors.
+func (m *editorModel) SetTheme(theme Theme) {
+	if theme.Syntax == nil {
+		theme.Syntax = m.theme.Syntax
+	}
+	if theme.Syntax == nil {
+		theme.Syntax = styles.Fallback
+	}
+	m.theme = theme
+	m.storeWindow()
+	for _, d := range m.docs {
+		d.highlighter.setStyle(theme.Syntax)
+	}
+}
+
+// GetTheme returns the current theme
+func (m *editorModel) GetTheme() Theme {
+	return m.theme
+}
+
+// colorschemeCommand switches the theme, or shows the current one without
+// an argument
+func colorschemeCommand(m *editorModel) tea.Cmd {
+	_, args := m.commandLine()
+	if len(args) == 0 {
+		return SetStatusMsg(m.theme.Name)
+	}
+	theme, ok := lookupTheme(args[0])
+	if !ok {
+		return SetStatusMsg(fmt.Sprintf("E185: Cannot find color scheme '%s'", args[0]))
+	}
+	m.SetTheme(theme)
+	return nil
+}
@@ -0,0 +1,164 @@
+package vimtea
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultTheme(t *testing.T) {
+	model := NewEditor().(*editorModel)
+
+	theme := model.GetTheme()
+	assert.Equal(t, "default", theme.Name)
+	assert.Equal(t, defaultSyntaxStyle, theme.Syntax.Name)
+	assert.Equal(t, yankStyle.GetBackground(), theme.Yank.GetBackground(), "Yank highlights should use the theme")
+	assert.Same(t, theme.Syntax, model.highlighter.style)
+}
+
+func TestThemeFromStyle(t *testing.T) {
+	style := chroma.MustNewStyle("vimtea-derived", chroma.StyleEntries{
+		chroma.Background:    "#cccccc bg:#101010",
+		chroma.LineNumbers:   "#555555",
+		chroma.LineHighlight: "bg:#202020",
+		chroma.Keyword:       "#ff8800",
+	})
+
+	theme := ThemeFromStyle(style)
+	assert.Equal(t, "vimtea-derived", theme.Name)
+	assert.Same(t, style, theme.Syntax)
+	assert.Equal(t, lipgloss.Color("#101010"), theme.Normal.GetBackground())
+	assert.Equal(t, lipgloss.Color("#555555"), theme.LineNr.GetForeground())
+	assert.Equal(t, 1, theme.LineNr.GetPaddingRight(), "Line numbers should keep their padding")
+	assert.Equal(t, lipgloss.Color("#202020"), theme.Visual.GetBackground())
+	assert.Equal(t, lipgloss.Color("#ff8800"), theme.CommandLine.GetForeground())
+}
+
+func TestParseTheme(t *testing.T) {
+	theme, err := ParseTheme([]byte(`{
+		"name": "midnight",
+		"syntax": "dracula",
+		"groups": {
+			"Visual": {"bg": "#44475a"},
+			"LineNr": {"fg": "#6272a4", "italic": true}
+		},
+		"palette": {"Comment": "bold #00ff00"}
+	}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "midnight", theme.Name)
+	assert.Equal(t, lipgloss.Color("#44475a"), theme.Visual.GetBackground())
+	assert.Equal(t, lipgloss.Color("#6272a4"), theme.LineNr.GetForeground())
+	assert.True(t, theme.LineNr.GetItalic())
+	assert.Equal(t, 1, theme.LineNr.GetPaddingRight(), "Groups should change only the given properties")
+	assert.Equal(t, "#00ff00", theme.Syntax.Get(chroma.Comment).Colour.String())
+	assert.Equal(t, styles.Get("dracula").Get(chroma.Keyword), theme.Syntax.Get(chroma.Keyword),
+		"Unchanged token types should keep the colors of the base style")
+
+	_, err = ParseTheme([]byte(`{"groups": {"Nope": {"fg": "1"}}}`))
+	assert.ErrorContains(t, err, "unknown highlight group: Nope")
+	_, err = ParseTheme([]byte(`{"palette": {"Nope": "#fff"}}`))
+	assert.ErrorContains(t, err, "unknown token type: Nope")
+	_, err = ParseTheme([]byte(`{"syntax": "nope"}`))
+	assert.ErrorContains(t, err, "unknown syntax style: nope")
+}
+
+func TestLoadTheme(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "xmlstyle.xml")
+	require.NoError(t, os.WriteFile(path, []byte(`<style name="xmlstyle">
+  <entry type="Background" style="bg:#000000"/>
+  <entry type="Keyword" style="#ff0000"/>
+</style>`), 0o644))
+
+	theme, err := LoadTheme(path)
+	require.NoError(t, err)
+	assert.Equal(t, "xmlstyle", theme.Name)
+	assert.Equal(t, "#ff0000", theme.Syntax.Get(chroma.Keyword).Colour.String())
+
+	path = filepath.Join(dir, "plain.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"groups": {"Cursor": {"reverse": true}}}`), 0o644))
+	theme, err = LoadTheme(path)
+	require.NoError(t, err)
+	assert.Equal(t, "plain", theme.Name, "Themes without a name should be named after the file")
+	assert.True(t, theme.Cursor.GetReverse())
+
+	_, err = LoadTheme(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestColorschemeSwitchesTheme(t *testing.T) {
+	theme := DefaultTheme()
+	theme.Name = "vimtea-registered"
+	theme.Visual = lipgloss.NewStyle().Background(lipgloss.Color("#123456"))
+	RegisterTheme(theme)
+
+	model := NewEditor().(*editorModel)
+	runCommand(t, model, "colorscheme vimtea-registered")
+	assert.Equal(t, lipgloss.Color("#123456"), model.theme.Visual.GetBackground())
+
+	runCommand(t, model, "colorscheme monokai")
+	assert.Equal(t, "monokai", model.theme.Name, "Chroma styles should be usable as themes")
+	assert.Equal(t, "monokai", model.highlighter.style.Name)
+
+	msgs := runCommand(t, model, "colorscheme")
+	assert.Contains(t, msgs, statusMessageMsg("monokai"))
+
+	runCommand(t, model, "colo default")
+	assert.Equal(t, "default", model.theme.Name)
+}
+
+func TestRegisterThemeConcurrently(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			theme := DefaultTheme()
+			theme.Name = fmt.Sprintf("vimtea-concurrent-%d", i)
+			RegisterTheme(theme)
+			lookupTheme(theme.Name)
+		}()
+	}
+	wg.Wait()
+
+	for i := range 8 {
+		_, ok := lookupTheme(fmt.Sprintf("vimtea-concurrent-%d", i))
+		assert.True(t, ok)
+	}
+}
+
+func TestThemeWithoutSyntax(t *testing.T) {
+	model := NewEditor(WithContent("package main"), WithFileName("main.go")).(*editorModel)
+	model.SetSize(40, 5)
+	current := model.theme.Syntax
+
+	model.SetTheme(Theme{Name: "plain", Normal: lipgloss.NewStyle()})
+	assert.NotPanics(t, func() { model.View() })
+	assert.Equal(t, current, model.GetTheme().Syntax, "A theme without a syntax style should keep the current one")
+
+	model = NewEditor(WithContent("package main"), WithFileName("main.go"), WithTheme(Theme{Name: "plain"})).(*editorModel)
+	model.SetTheme(Theme{Name: "plain"})
+	assert.NotPanics(t, func() { model.View() })
+	assert.Equal(t, styles.Fallback, model.GetTheme().Syntax)
+
+	assert.Equal(t, styles.Fallback, ThemeFromStyle(nil).Syntax)
+}
+
+func TestStyleOptionsChangeThemeGroups(t *testing.T) {
+	cursor := lipgloss.NewStyle().Background(lipgloss.Color("#abcdef"))
+	theme := DefaultTheme()
+	theme.Name = "custom"
+
+	model := NewEditor(WithTheme(theme), WithCursorStyle(cursor)).(*editorModel)
+	assert.Equal(t, "custom", model.theme.Name)
+	assert.Equal(t, lipgloss.Color("#abcdef"), model.theme.Cursor.GetBackground())
+}
@@ -4,7 +4,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -106,6 +108,11 @@ func TestViewStatusMessages(t *testing.T) {
 }
 
 func TestViewSyntaxHighlighting(t *testing.T) {
+	// Styles are only rendered for terminals with colors
+	profile := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.TrueColor)
+	defer lipgloss.SetColorProfile(profile)
+
 	// Create Go code
 	goCode := "package main\n\nfunc main() {\n\t// Comment\n\tfmt.Println(\"Hello\")\n}"
 
@@ -70,5 +70,13 @@ Customize the appearance with style options:
 		vimtea.WithLineNumberStyle(numberStyle),
 		vimtea.WithCursorStyle(cursorStyle),
 	)
+
+or switch all colors at once with a Theme, loaded from a file or derived from
+a chroma style:
+
+	theme, err := vimtea.LoadTheme("midnight.json")
+	if err == nil {
+		editor.SetTheme(theme)
+	}
 */
 package vimtea
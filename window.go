@@ -0,0 +1,930 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// WindowID identifies a window of the editor
+type WindowID int
+
+// WindowInfo describes a window of the editor
+type WindowInfo struct {
+	ID       WindowID
+	Buffer   int    // Number of the buffer shown in the window
+	File     string // File of the buffer shown in the window
+	Cursor   Cursor // Cursor of the window
+	Row, Col int    // Top left cell of the window in the editor
+	Width    int    // Columns of the window
+	Height   int    // Text rows of the window, without its status line
+}
+
+// window is a view of a document with its own cursor, scroll position and
+// visual selection. The state of the current window is kept in the fields
+// of the editor model and stored back when another window becomes current.
+type window struct {
+	id           WindowID
+	doc          *document
+	alt          *document   // Alternate buffer, shown before the current one
+	node         *layoutNode // Leaf of the layout holding the window
+	cursor       Cursor
+	desiredCol   int
+	yOffset      int
+	xOffset      int
+	visualStart  Cursor
+	isVisualLine bool
+
+	row, col      int // Top left cell in the editor
+	width, height int // Size of the text area
+}
+
+// document is a buffer of the buffer list with the state that belongs to
+// it rather than to the windows showing it. Windows on the same document
+// share its text and undo history.
+type document struct {
+	number      int     // Buffer number shown by :ls
+	buffer      *buffer // Text, nil while the buffer is unloaded
+	filePath    string
+	highlighter *syntaxHighlighter
+	signs       []Sign
+	diagnostics []Diagnostic
+	marks       map[string]Cursor
+	cursor      Cursor // Last cursor position, restored when the buffer is shown again
+	version     int    // Buffer version of the last change notification
+}
+
+// layoutNode is a node of the window layout. Leaves hold a window, other
+// nodes hold their children stacked or side by side.
+type layoutNode struct {
+	win      *window
+	vertical bool // Children side by side, as created by :vsplit
+	children []*layoutNode
+	parent   *layoutNode
+	size     int // Rows or columns taken in the parent
+
+	row, col      int // Top left cell in the editor
+	width, height int
+}
+
+// Minimum size of a window: one text row and its status line, one column
+const (
+	minWindowHeight = 2
+	minWindowWidth  = 1
+)
+
+// initWindows creates the first tab page with a window showing the
+// editor's buffer as the first buffer of the buffer list
+func (m *editorModel) initWindows() {
+	m.nextBufferNumber++
+	doc := &document{number: m.nextBufferNumber}
+	m.docs = []*document{doc}
+	m.nextWindowID++
+	m.win = &window{id: m.nextWindowID, doc: doc}
+	m.layout = &layoutNode{win: m.win}
+	m.win.node = m.layout
+	m.tab = &tabPage{layout: m.layout, win: m.win}
+	m.tabPages = []*tabPage{m.tab}
+}
+
+// hasSplits reports whether more than one window is open
+func (m *editorModel) hasSplits() bool {
+	return m.layout.win == nil
+}
+
+// storeWindow saves the state of the current window and its document from
+// the fields of the editor
+func (m *editorModel) storeWindow() {
+	w := m.win
+	w.cursor, w.desiredCol = m.cursor, m.desiredCol
+	w.yOffset, w.xOffset = m.viewport.YOffset, m.xOffset
+	w.visualStart, w.isVisualLine = m.visualStart, m.isVisualLine
+
+	d := w.doc
+	d.buffer, d.filePath, d.highlighter = m.buffer, m.filePath, m.highlighter
+	d.signs, d.diagnostics, d.marks = m.signs, m.diagnostics, m.marks
+	d.cursor = m.cursor
+	d.version = m.lastState.version
+}
+
+// loadWindow moves the state of a window and its document into the fields
+// of the editor, making it the current window
+func (m *editorModel) loadWindow(w *window) {
+	m.win = w
+	m.cursor, m.desiredCol = w.cursor, w.desiredCol
+	m.viewport.YOffset, m.xOffset = w.yOffset, w.xOffset
+	m.visualStart, m.isVisualLine = w.visualStart, w.isVisualLine
+	m.width, m.height = w.width, w.height
+	m.viewport.Width, m.viewport.Height = w.width, w.height
+
+	d := w.doc
+	m.buffer, m.filePath, m.highlighter = d.buffer, d.filePath, d.highlighter
+	m.signs, m.diagnostics, m.marks = d.signs, d.diagnostics, d.marks
+	m.lastState.version = d.version
+}
+
+// windows returns the windows of the current tab page in layout order,
+// left to right and top to bottom
+func (m *editorModel) windows() []*window {
+	return m.layout.windows()
+}
+
+// windows returns the windows of a layout in order
+func (n *layoutNode) windows() []*window {
+	if n.win != nil {
+		return []*window{n.win}
+	}
+	var wins []*window
+	for _, c := range n.children {
+		wins = append(wins, c.windows()...)
+	}
+	return wins
+}
+
+// window returns the window of the current tab page with an ID, or nil
+func (m *editorModel) window(id WindowID) *window {
+	for _, w := range m.windows() {
+		if w.id == id {
+			return w
+		}
+	}
+	return nil
+}
+
+// enterWindow makes w the current window, leaving visual mode and ending
+// the snippet and completion of the previous one
+func (m *editorModel) enterWindow(w *window) {
+	if w == m.win {
+		return
+	}
+	if m.mode == ModeVisual {
+		m.mode = ModeNormal
+	}
+	m.endSnippet()
+	m.completion = nil
+	m.fireAutocmd(EventWinLeave, AutocmdArgs{})
+	m.storeWindow()
+	m.loadWindow(w)
+	m.ensureCursorVisible()
+	m.fireAutocmd(EventWinEnter, AutocmdArgs{})
+}
+
+// shiftWindows moves the cursors and scroll positions of the other windows
+// on the current document along with a change of its text
+func (m *editorModel) shiftWindows(change TextChange) {
+	for _, w := range m.allWindows() {
+		if w == m.win || w.doc != m.win.doc {
+			continue
+		}
+		w.cursor = change.shiftPos(w.cursor)
+		w.visualStart = change.shiftPos(w.visualStart)
+		w.yOffset = change.shiftRow(w.yOffset)
+	}
+}
+
+// splitWindow splits the current window in two, side by side when
+// vertical, and makes the new window current. The new window shows the
+// same document at the same position and is placed above or to the left.
+func (m *editorModel) splitWindow(vertical bool) *window {
+	if !m.sharesArea() {
+		m.areaWidth, m.areaHeight = m.width, m.height
+	}
+	m.storeWindow()
+	old := m.win
+	m.nextWindowID++
+	w := &window{}
+	*w = *old
+	w.id = m.nextWindowID
+	leaf := &layoutNode{win: w}
+	w.node = leaf
+
+	node := old.node
+	if parent := node.parent; parent != nil && parent.vertical == vertical {
+		// Share the space of the split window
+		sep := 0
+		if vertical {
+			sep = 1
+		}
+		leaf.size = (node.size - sep) / 2
+		node.size -= leaf.size + sep
+		leaf.parent = parent
+		i := slices.Index(parent.children, node)
+		parent.children = slices.Insert(parent.children, i, leaf)
+	} else {
+		// Turn the leaf into a node holding both windows at equal sizes
+		container := &layoutNode{vertical: vertical, parent: node.parent, size: node.size}
+		if node.parent == nil {
+			m.layout = container
+		} else {
+			i := slices.Index(node.parent.children, node)
+			node.parent.children[i] = container
+		}
+		leaf.parent, node.parent = container, container
+		leaf.size, node.size = 0, 0
+		container.children = []*layoutNode{leaf, node}
+	}
+
+	m.layoutWindows()
+	m.enterWindow(w)
+	return w
+}
+
+// abandons reports whether closing a window would lose unsaved changes of
+// a document no other window shows
+func (m *editorModel) abandons(w *window) bool {
+	m.storeWindow()
+	if !w.doc.modified() {
+		return false
+	}
+	for _, other := range m.allWindows() {
+		if other != w && other.doc == w.doc {
+			return false
+		}
+	}
+	return true
+}
+
+// closeWindow closes a window, giving its space to a neighbour. Closing
+// the last window of a tab page closes the tab page, but the last window
+// of the editor cannot be closed.
+func (m *editorModel) closeWindow(w *window) error {
+	if !m.hasSplits() {
+		if len(m.tabPages) > 1 {
+			return m.closeTab(m.tab)
+		}
+		return errors.New("E444: Cannot close last window")
+	}
+	m.storeWindow()
+
+	node := w.node
+	parent := node.parent
+	i := slices.Index(parent.children, node)
+	parent.children = slices.Delete(parent.children, i, i+1)
+	neighbour := parent.children[max(0, i-1)]
+	neighbour.size += node.size
+	if parent.vertical {
+		neighbour.size++
+	}
+
+	// A node left with a single child is replaced by it, and its children
+	// join the grandparent when they are split the same way
+	if len(parent.children) == 1 {
+		child := parent.children[0]
+		child.parent, child.size = parent.parent, parent.size
+		switch {
+		case parent.parent == nil:
+			m.layout = child
+		case child.win == nil && child.vertical == parent.parent.vertical:
+			j := slices.Index(parent.parent.children, parent)
+			for _, c := range child.children {
+				c.parent = parent.parent
+			}
+			parent.parent.children = slices.Replace(parent.parent.children, j, j+1, child.children...)
+		default:
+			j := slices.Index(parent.parent.children, parent)
+			parent.parent.children[j] = child
+		}
+	}
+
+	m.layoutWindows()
+	if w != m.win {
+		m.loadWindow(m.win)
+		return nil
+	}
+
+	// Focus the window nearest to the closed one
+	next := neighbour
+	for next.win == nil {
+		if i > 0 {
+			next = next.children[len(next.children)-1]
+		} else {
+			next = next.children[0]
+		}
+	}
+	m.loadWindow(next.win)
+	m.ensureCursorVisible()
+	m.fireAutocmd(EventWinEnter, AutocmdArgs{})
+	return nil
+}
+
+// onlyWindow closes every window but the current one. Unless force is
+// set it fails when changes would be lost.
+func (m *editorModel) onlyWindow(force bool) error {
+	if !m.hasSplits() {
+		return nil
+	}
+	if !force {
+		for _, w := range m.windows() {
+			if w != m.win && w.doc != m.win.doc && m.abandons(w) {
+				return errors.New("E445: Other window contains changes")
+			}
+		}
+	}
+	m.storeWindow()
+	m.layout = m.win.node
+	m.layout.parent = nil
+	m.layout.size = 0
+	m.layoutWindows()
+	m.loadWindow(m.win)
+	return nil
+}
+
+// layoutWindows computes the position and size of the windows from the
+// sizes in the layout and the size of the editor below the tabline
+func (m *editorModel) layoutWindows() {
+	top := m.tablineHeight()
+	if !m.hasSplits() {
+		w := m.layout.win
+		w.row, w.col = top, 0
+		w.width, w.height = m.areaWidth, max(0, m.areaHeight-top)
+	} else {
+		m.layout.place(top, 0, m.areaWidth, max(0, m.areaHeight-top))
+	}
+	m.width, m.height = m.win.width, m.win.height
+	m.viewport.Width, m.viewport.Height = m.win.width, m.win.height
+}
+
+// place positions a node and its children in a rectangle of the editor.
+// Every window gets a status line below its text.
+func (n *layoutNode) place(row, col, width, height int) {
+	n.row, n.col, n.width, n.height = row, col, width, height
+	if n.win != nil {
+		n.win.row, n.win.col = row, col
+		n.win.width, n.win.height = max(0, width), max(0, height-1)
+		return
+	}
+
+	total := height
+	if n.vertical {
+		total = width - (len(n.children) - 1)
+	}
+	sizes := fitSizes(n.children, max(0, total))
+	for i, c := range n.children {
+		c.size = sizes[i]
+		if n.vertical {
+			c.place(row, col, c.size, height)
+			col += c.size + 1
+		} else {
+			c.place(row, col, width, c.size)
+			row += c.size
+		}
+	}
+}
+
+// fitSizes scales the sizes of nodes to add up to total, sharing it
+// equally when no sizes are set
+func fitSizes(nodes []*layoutNode, total int) []int {
+	sizes := make([]int, len(nodes))
+	sum := 0
+	for _, n := range nodes {
+		sum += max(0, n.size)
+	}
+	if sum == 0 {
+		for i := range sizes {
+			sizes[i] = total / len(nodes)
+			if i < total%len(nodes) {
+				sizes[i]++
+			}
+		}
+		return sizes
+	}
+
+	used := 0
+	for i, n := range nodes {
+		sizes[i] = max(0, n.size) * total / sum
+		used += sizes[i]
+	}
+	sizes[len(sizes)-1] += total - used
+	return sizes
+}
+
+// minSize returns the smallest number of rows, or columns when vertical,
+// a node can be given
+func (n *layoutNode) minSize(vertical bool) int {
+	if n.win != nil {
+		if vertical {
+			return minWindowWidth
+		}
+		return minWindowHeight
+	}
+	size := 0
+	for _, c := range n.children {
+		if n.vertical == vertical {
+			size += c.minSize(vertical)
+		} else {
+			size = max(size, c.minSize(vertical))
+		}
+	}
+	if n.vertical && vertical {
+		size += len(n.children) - 1
+	}
+	return size
+}
+
+// resizeWindow grows a window by delta rows, or columns when vertical,
+// taking the space from the windows after it and then from the ones
+// before it. A negative delta gives space to the next window.
+func (m *editorModel) resizeWindow(w *window, vertical bool, delta int) {
+	node := w.node
+	for node.parent != nil && node.parent.vertical != vertical {
+		node = node.parent
+	}
+	parent := node.parent
+	if parent == nil || delta == 0 {
+		return
+	}
+	siblings := parent.children
+	i := slices.Index(siblings, node)
+
+	if delta > 0 {
+		need := delta
+		order := append(slices.Clone(siblings[i+1:]), reversed(siblings[:i])...)
+		for _, s := range order {
+			take := min(need, s.size-s.minSize(vertical))
+			if take > 0 {
+				s.size -= take
+				need -= take
+			}
+		}
+		node.size += delta - need
+	} else {
+		give := min(-delta, node.size-node.minSize(vertical))
+		if give <= 0 {
+			return
+		}
+		node.size -= give
+		if i+1 < len(siblings) {
+			siblings[i+1].size += give
+		} else {
+			siblings[i-1].size += give
+		}
+	}
+	m.layoutWindows()
+	m.ensureCursorVisible()
+}
+
+// reversed returns a reversed copy of nodes
+func reversed(nodes []*layoutNode) []*layoutNode {
+	nodes = slices.Clone(nodes)
+	slices.Reverse(nodes)
+	return nodes
+}
+
+// equalizeWindows gives all windows the same size
+func (m *editorModel) equalizeWindows() {
+	var walk func(n *layoutNode)
+	walk = func(n *layoutNode) {
+		for _, c := range n.children {
+			c.size = 0
+			walk(c)
+		}
+	}
+	walk(m.layout)
+	m.layoutWindows()
+	m.ensureCursorVisible()
+}
+
+// neighbourWindow returns the window next to the current one in the
+// direction of dr rows and dc columns, the one beside the cursor when
+// there are several, or nil
+func (m *editorModel) neighbourWindow(dr, dc int) *window {
+	cur := m.win
+	row, col, ok := m.screenPosition(m.cursor)
+	if !ok {
+		row, col = 0, 0
+	}
+	row = cur.row + min(row, max(0, cur.height-1))
+	col = cur.col + min(col, max(0, cur.width-1))
+
+	var best *window
+	bestEdge := 0
+	for _, w := range m.windows() {
+		top, bottom := w.row, w.row+w.height+1
+		left, right := w.col, w.col+w.width
+		var edge int
+		switch {
+		case dc < 0 && right <= cur.col && row >= top && row < bottom:
+			edge = right
+		case dc > 0 && left >= cur.col+cur.width && row >= top && row < bottom:
+			edge = -left
+		case dr < 0 && bottom <= cur.row && col >= left && col < right+1:
+			edge = bottom
+		case dr > 0 && top >= cur.row+cur.height+1 && col >= left && col < right+1:
+			edge = -top
+		default:
+			continue
+		}
+		if best == nil || edge > bestEdge {
+			best, bestEdge = w, edge
+		}
+	}
+	return best
+}
+
+// renderWindows renders the tabline and the windows of the current tab
+// page with their status lines and the separators between windows side by
+// side
+func (m *editorModel) renderWindows() string {
+	if !m.sharesArea() {
+		return m.renderContent()
+	}
+
+	rows := make([]string, m.areaHeight)
+	for i := range rows {
+		rows[i] = strings.Repeat(" ", m.areaWidth)
+	}
+	content := strings.Join(rows, "\n")
+	if m.tablineHeight() > 0 {
+		content = overlay(content, m.renderTabline(), 0, 0, m.areaWidth)
+	}
+	// The area ends with the empty row that follows the content of a single
+	// window, so the height of the editor does not change
+	if !m.hasSplits() {
+		return overlay(content, m.renderWindow(true, false), m.win.col, m.win.row, m.areaWidth) + "\n"
+	}
+	content = m.renderSeparators(content, m.layout)
+
+	current := m.win
+	m.storeWindow()
+	for _, w := range m.windows() {
+		m.loadWindow(w)
+		content = overlay(content, m.renderWindow(w == current, true), w.col, w.row, m.areaWidth)
+	}
+	m.loadWindow(current)
+	return content + "\n"
+}
+
+// renderWindow renders the loaded window, with its status line when
+// status is set. Windows that are not current are drawn in normal mode
+// without a cursor.
+func (m *editorModel) renderWindow(current, status bool) string {
+	if !current {
+		mode, blink, snippet := m.mode, m.cursorBlink, m.snippet
+		m.mode, m.cursorBlink, m.snippet = ModeNormal, false, nil
+		defer func() { m.mode, m.cursorBlink, m.snippet = mode, blink, snippet }()
+	}
+
+	lines := strings.Split(strings.TrimSuffix(m.renderContent(), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = fitWidth(line, m.width)
+	}
+	lines = lines[:min(len(lines), m.height)]
+	if status {
+		lines = append(lines, m.renderWindowStatus(current))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderWindowStatus renders the status line of the loaded window with
+// its file name, modified marker and cursor position
+func (m *editorModel) renderWindowStatus(current bool) string {
+	name := m.filePath
+	if name == "" {
+		name = "[No Name]"
+	}
+	if m.buffer.modified() {
+		name += " [+]"
+	}
+	pos := fmt.Sprintf(" %d:%d ", m.cursor.Row+1, m.cursor.Col+1)
+
+	style := m.theme.StatusLineNC
+	if current {
+		style = m.theme.StatusLine
+	}
+	name = ansi.Truncate(" "+name, max(0, m.width-ansi.StringWidth(pos)), "…")
+	return style.Render(fitWidth(name+strings.Repeat(" ", max(0, m.width-ansi.StringWidth(name+pos)))+pos, m.width))
+}
+
+// renderSeparators draws the separators between the children of the
+// nodes side by side
+func (m *editorModel) renderSeparators(content string, n *layoutNode) string {
+	for i, c := range n.children {
+		if n.vertical && i < len(n.children)-1 {
+			sep := strings.Repeat(m.theme.WinSeparator.Render("│")+"\n", n.height)
+			content = overlay(content, strings.TrimSuffix(sep, "\n"), c.col+c.width, n.row, m.areaWidth)
+		}
+		content = m.renderSeparators(content, c)
+	}
+	return content
+}
+
+// fitWidth truncates or pads a rendered line to width columns
+func fitWidth(line string, width int) string {
+	line = ansi.Truncate(line, width, "")
+	return line + strings.Repeat(" ", max(0, width-ansi.StringWidth(line)))
+}
+
+// sharesArea reports whether the area of the editor is divided between
+// windows or holds a tabline
+func (m *editorModel) sharesArea() bool {
+	return m.hasSplits() || m.tablineHeight() > 0
+}
+
+// editorSize returns the size of the area shared by the tabline and the
+// windows
+func (m *editorModel) editorSize() (int, int) {
+	if m.sharesArea() {
+		return m.areaWidth, m.areaHeight
+	}
+	return m.width, m.height
+}
+
+// windowOrigin returns the top left cell of the current window
+func (m *editorModel) windowOrigin() (int, int) {
+	if m.sharesArea() {
+		return m.win.row, m.win.col
+	}
+	return 0, 0
+}
+
+// SplitWindow splits the current window and makes the new window, which
+// shows the same buffer, current. It is placed to the left when vertical
+// and above otherwise.
+func (m *editorModel) SplitWindow(vertical bool) WindowID {
+	return m.splitWindow(vertical).id
+}
+
+// Windows returns the open windows, left to right and top to bottom
+func (m *editorModel) Windows() []WindowInfo {
+	m.storeWindow()
+	var infos []WindowInfo
+	for _, w := range m.windows() {
+		infos = append(infos, WindowInfo{
+			ID:     w.id,
+			Buffer: w.doc.number,
+			File:   w.doc.filePath,
+			Cursor: w.cursor,
+			Row:    w.row,
+			Col:    w.col,
+			Width:  w.width,
+			Height: w.height,
+		})
+	}
+	return infos
+}
+
+// CurrentWindow returns the ID of the window that has the focus
+func (m *editorModel) CurrentWindow() WindowID {
+	return m.win.id
+}
+
+// SetCurrentWindow moves the focus to a window
+func (m *editorModel) SetCurrentWindow(id WindowID) error {
+	w := m.window(id)
+	if w == nil {
+		return fmt.Errorf("E957: Invalid window number: %d", id)
+	}
+	m.enterWindow(w)
+	return nil
+}
+
+// CloseWindow closes a window. Unsaved changes of a buffer no other
+// window shows are only discarded when force is set.
+func (m *editorModel) CloseWindow(id WindowID, force bool) error {
+	w := m.window(id)
+	if w == nil {
+		return fmt.Errorf("E957: Invalid window number: %d", id)
+	}
+	if !force && m.sharesArea() && m.abandons(w) {
+		return errors.New("E37: No write since last change (add ! to override)")
+	}
+	return m.closeWindow(w)
+}
+
+// ResizeWindow changes the number of text rows and columns of a window.
+// A size of zero or less leaves that dimension alone.
+func (m *editorModel) ResizeWindow(id WindowID, width, height int) error {
+	w := m.window(id)
+	if w == nil {
+		return fmt.Errorf("E957: Invalid window number: %d", id)
+	}
+	if height > 0 {
+		m.resizeWindow(w, false, height-w.height)
+	}
+	if width > 0 {
+		m.resizeWindow(w, true, width-w.width)
+	}
+	return nil
+}
+
+// registerWindowCommands registers the ex commands that split, close and
+// resize windows
+func registerWindowCommands(m *editorModel) {
+	for _, name := range []string{"sp", "split"} {
+		m.commands.Register(name, splitCommand(false))
+	}
+	for _, name := range []string{"vs", "vsplit"} {
+		m.commands.Register(name, splitCommand(true))
+	}
+	for _, name := range []string{"clo", "close"} {
+		m.commands.Register(name, closeCommand)
+	}
+	for _, name := range []string{"on", "only"} {
+		m.commands.Register(name, onlyCommand)
+	}
+	for _, name := range []string{"res", "resize"} {
+		m.commands.Register(name, resizeCommand(false))
+	}
+	for _, name := range []string{"vert", "vertical"} {
+		m.commands.Register(name, verticalCommand)
+	}
+}
+
+// splitCommand implements :sp[lit] [file] and :vs[plit] [file]
+func splitCommand(vertical bool) Command {
+	return func(model *editorModel) tea.Cmd {
+		_, args := model.commandLine()
+		model.splitWindow(vertical)
+		if len(args) > 0 {
+			if err := model.openFile(args[0]); err != nil {
+				return SetStatusMsg(err.Error())
+			}
+		}
+		return nil
+	}
+}
+
+// closeCommand implements :clo[se][!]
+func closeCommand(model *editorModel) tea.Cmd {
+	bang, _ := model.commandLine()
+	if err := model.CloseWindow(model.win.id, bang); err != nil {
+		return SetStatusMsg(err.Error())
+	}
+	return nil
+}
+
+// onlyCommand implements :on[ly][!]
+func onlyCommand(model *editorModel) tea.Cmd {
+	bang, _ := model.commandLine()
+	if err := model.onlyWindow(bang); err != nil {
+		return SetStatusMsg(err.Error())
+	}
+	return nil
+}
+
+// resizeCommand implements :res[ize] [+-]N, setting or changing the
+// height of the current window, or its width when vertical
+func resizeCommand(vertical bool) Command {
+	return func(model *editorModel) tea.Cmd {
+		_, args := model.commandLine()
+		return model.resizeTo(vertical, args)
+	}
+}
+
+// verticalCommand implements :vert[ical] res[ize] [+-]N
+func verticalCommand(model *editorModel) tea.Cmd {
+	_, args := model.commandLine()
+	if len(args) == 0 || (args[0] != "res" && args[0] != "resize") {
+		return SetStatusMsg("E492: Not an editor command: vertical " + strings.Join(args, " "))
+	}
+	return model.resizeTo(true, args[1:])
+}
+
+// resizeTo resizes the current window to the size in args, relative when
+// it starts with + or -, or as large as possible without one
+func (m *editorModel) resizeTo(vertical bool, args []string) tea.Cmd {
+	current := m.win.height
+	if vertical {
+		current = m.win.width
+	}
+	if len(args) == 0 {
+		m.resizeWindow(m.win, vertical, m.areaHeight+m.areaWidth)
+		return nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return SetStatusMsg("E475: Invalid argument: " + args[0])
+	}
+	if args[0][0] != '+' && args[0][0] != '-' {
+		n -= current
+	}
+	m.resizeWindow(m.win, vertical, n)
+	return nil
+}
+
+// registerWindowBindings registers the <C-w> commands of normal mode
+func registerWindowBindings(m *editorModel) {
+	for _, key := range []string{"s", "S", "ctrl+s"} {
+		m.registry.Add("ctrl+w"+key, windowSplit(false), ModeNormal, "Split window")
+	}
+	for _, key := range []string{"v", "ctrl+v"} {
+		m.registry.Add("ctrl+w"+key, windowSplit(true), ModeNormal, "Split window vertically")
+	}
+	for _, key := range []string{"w", "ctrl+w"} {
+		m.registry.Add("ctrl+w"+key, windowCycle(1), ModeNormal, "Go to the next window, or window N")
+	}
+	m.registry.Add("ctrl+wW", windowCycle(-1), ModeNormal, "Go to the previous window, or window N")
+	moves := []struct {
+		keys   []string
+		dr, dc int
+		help   string
+	}{
+		{[]string{"h", "left", "ctrl+h", "backspace"}, 0, -1, "Go to the window on the left"},
+		{[]string{"j", "down", "ctrl+j"}, 1, 0, "Go to the window below"},
+		{[]string{"k", "up", "ctrl+k"}, -1, 0, "Go to the window above"},
+		{[]string{"l", "right", "ctrl+l"}, 0, 1, "Go to the window on the right"},
+	}
+	for _, mv := range moves {
+		for _, key := range mv.keys {
+			m.registry.Add("ctrl+w"+key, windowMove(mv.dr, mv.dc), ModeNormal, mv.help)
+		}
+	}
+	for _, key := range []string{"c", "q", "ctrl+q"} {
+		m.registry.Add("ctrl+w"+key, windowClose, ModeNormal, "Close the window")
+	}
+	for _, key := range []string{"o", "ctrl+o"} {
+		m.registry.Add("ctrl+w"+key, windowOnly, ModeNormal, "Close all other windows")
+	}
+	m.registry.Add("ctrl+w+", windowResize(false, 1), ModeNormal, "Increase window height")
+	m.registry.Add("ctrl+w-", windowResize(false, -1), ModeNormal, "Decrease window height")
+	m.registry.Add("ctrl+w>", windowResize(true, 1), ModeNormal, "Increase window width")
+	m.registry.Add("ctrl+w<", windowResize(true, -1), ModeNormal, "Decrease window width")
+	m.registry.Add("ctrl+w_", windowMaximize(false), ModeNormal, "Set window height to N, or maximize it")
+	m.registry.Add("ctrl+w|", windowMaximize(true), ModeNormal, "Set window width to N, or maximize it")
+	m.registry.Add("ctrl+w=", windowEqualize, ModeNormal, "Make all windows the same size")
+}
+
+// windowSplit implements <C-w>s and <C-w>v
+func windowSplit(vertical bool) Command {
+	return func(model *editorModel) tea.Cmd {
+		model.splitWindow(vertical)
+		return nil
+	}
+}
+
+// windowCycle implements <C-w>w and <C-w>W, going to window N with a count
+func windowCycle(dir int) Command {
+	return func(model *editorModel) tea.Cmd {
+		wins := model.windows()
+		count := model.countPrefix
+		model.countPrefix = 1
+		if count > 1 {
+			model.enterWindow(wins[min(count, len(wins))-1])
+			return nil
+		}
+		i := slices.Index(wins, model.win)
+		model.enterWindow(wins[(i+dir+len(wins))%len(wins)])
+		return nil
+	}
+}
+
+// windowMove implements <C-w>h, j, k and l, repeated by the count
+func windowMove(dr, dc int) Command {
+	return func(model *editorModel) tea.Cmd {
+		withCountPrefix(model, func() {
+			if w := model.neighbourWindow(dr, dc); w != nil {
+				model.enterWindow(w)
+			}
+		})
+		return nil
+	}
+}
+
+// windowClose implements <C-w>c
+func windowClose(model *editorModel) tea.Cmd {
+	if err := model.CloseWindow(model.win.id, false); err != nil {
+		return SetStatusMsg(err.Error())
+	}
+	return nil
+}
+
+// windowOnly implements <C-w>o
+func windowOnly(model *editorModel) tea.Cmd {
+	if err := model.onlyWindow(false); err != nil {
+		return SetStatusMsg(err.Error())
+	}
+	return nil
+}
+
+// windowResize implements <C-w>+, -, > and <, changing the size by the count
+func windowResize(vertical bool, sign int) Command {
+	return func(model *editorModel) tea.Cmd {
+		model.resizeWindow(model.win, vertical, sign*model.countPrefix)
+		model.countPrefix = 1
+		return nil
+	}
+}
+
+// windowMaximize implements <C-w>_ and <C-w>|
+func windowMaximize(vertical bool) Command {
+	return func(model *editorModel) tea.Cmd {
+		var args []string
+		if model.countPrefix > 1 {
+			args = []string{strconv.Itoa(model.countPrefix)}
+		}
+		model.countPrefix = 1
+		return model.resizeTo(vertical, args)
+	}
+}
+
+// windowEqualize implements <C-w>=
+func windowEqualize(model *editorModel) tea.Cmd {
+	model.equalizeWindows()
+	return nil
+}
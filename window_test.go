@@ -0,0 +1,230 @@
+package vimtea
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// windowRows returns the rendered windows as plain text rows
+func windowRows(model *editorModel) []string {
+	rows := strings.Split(strings.TrimSuffix(ansi.Strip(model.renderWindows()), "\n"), "\n")
+	for i := range rows {
+		rows[i] = strings.TrimRight(rows[i], " ")
+	}
+	return rows
+}
+
+// windowKey sends <C-w> followed by keys
+func windowKey(model *editorModel, keys string) {
+	pressKey(model, tea.KeyCtrlW)
+	sendKeys(model, keys)
+}
+
+func TestSplitWindow(t *testing.T) {
+	model := newWrapEditor("one\ntwo\nthree", 20, 7)
+
+	windowKey(model, "s")
+	require.Len(t, model.Windows(), 2, "<C-w>s should split the window")
+	assert.Equal(t, []string{
+		"one",
+		"two",
+		"three",
+		" [No Name]      1:1",
+		"one",
+		"two",
+		" [No Name]      1:1",
+	}, windowRows(model), "Both windows should show the buffer above their status line")
+	assert.Equal(t, model.Windows()[0].ID, model.CurrentWindow(), "The new window should be above and current")
+
+	sendKeys(model, "jj")
+	windowKey(model, "j")
+	assert.Equal(t, Cursor{0, 0}, model.cursor, "The lower window should keep its own cursor")
+
+	sendKeys(model, "x")
+	assert.Equal(t, "ne\ntwo\nthree", model.buffer.text())
+	assert.Equal(t, "ne", windowRows(model)[0], "Windows on the same buffer should share the text")
+
+	windowKey(model, "k")
+	assert.Equal(t, Cursor{2, 0}, model.cursor, "The upper window should keep its cursor")
+	model.buffer.undo(model.cursor)()
+	assert.Equal(t, "one\ntwo\nthree", model.buffer.text(), "Windows on the same buffer should share the undo history")
+}
+
+func TestVerticalSplitNavigation(t *testing.T) {
+	model := newWrapEditor("one\ntwo", 41, 4)
+
+	runCommand(t, model, "vsplit")
+	runCommand(t, model, "split")
+	assert.Equal(t, []string{
+		"one                 │one",
+		" [No Name]      1:1 │two",
+		"one                 │",
+		" [No Name]      1:1 │ [No Name]      1:1",
+	}, windowRows(model), "Windows side by side should have a separator")
+
+	wins := model.Windows()
+	require.Len(t, wins, 3)
+	assert.Equal(t, wins[0].ID, model.CurrentWindow())
+
+	windowKey(model, "l")
+	assert.Equal(t, wins[2].ID, model.CurrentWindow(), "<C-w>l should go to the window on the right")
+	windowKey(model, "h")
+	assert.Equal(t, wins[0].ID, model.CurrentWindow(), "<C-w>h should go to the window on the left")
+	windowKey(model, "w")
+	assert.Equal(t, wins[1].ID, model.CurrentWindow(), "<C-w>w should go to the next window")
+	windowKey(model, "W")
+	assert.Equal(t, wins[0].ID, model.CurrentWindow(), "<C-w>W should go to the previous window")
+	sendKeys(model, "3")
+	windowKey(model, "w")
+	assert.Equal(t, wins[2].ID, model.CurrentWindow(), "A count should go to that window")
+}
+
+func TestRenderCurrentLowerWindow(t *testing.T) {
+	model := newWrapEditor("one\ntwo\nthree\nfour\nfive\nsix", 41, 7)
+
+	runCommand(t, model, "split")
+	sendKeys(model, "G")
+	windowKey(model, "j")
+	assert.Equal(t, []string{
+		"four",
+		"five",
+		"six",
+		" [No Name]                           6:1",
+		"one",
+		"two",
+		" [No Name]                           1:1",
+	}, windowRows(model), "The current window below should be drawn with its own cursor and scroll")
+
+	runCommand(t, model, "vsplit")
+	windowKey(model, "l")
+	sendKeys(model, "j")
+	assert.Equal(t, []string{
+		"four",
+		"five",
+		"six",
+		" [No Name]                           6:1",
+		"one                 │one",
+		"two                 │two",
+		" [No Name]      1:1 │ [No Name]      2:1",
+	}, windowRows(model), "The current window on the right should be drawn with its own cursor")
+
+	model.View()
+	assert.Equal(t, Cursor{1, 0}, model.cursor, "Rendering should leave the current window loaded")
+	assert.Equal(t, model.Windows()[2].ID, model.CurrentWindow())
+}
+
+func TestCloseWindows(t *testing.T) {
+	fsys := NewMemFileSystem(map[string]string{"a.txt": "alpha", "b.txt": "beta"})
+	model := NewEditor(WithFile("a.txt"), WithFileSystem(fsys)).(*editorModel)
+	model.SetSize(40, 12)
+	require.Len(t, strings.Split(model.View(), "\n"), 12)
+
+	runCommand(t, model, "split b.txt")
+	require.Len(t, model.Windows(), 2)
+	assert.Len(t, strings.Split(model.View(), "\n"), 12, "Splitting should not change the height of the editor")
+	assert.Equal(t, "beta", model.buffer.text(), ":split with a file should load it in the new window")
+	assert.Equal(t, "a.txt", model.Windows()[1].File, "The other window should keep its file")
+
+	sendKeys(model, "x")
+	msgs := runCommand(t, model, "q")
+	assert.Len(t, model.Windows(), 2, ":q should not abandon changes")
+	assert.Contains(t, msgs, statusMessageMsg("E37: No write since last change (add ! to override)"))
+
+	windowKey(model, "j")
+	msgs = runCommand(t, model, "only")
+	assert.Contains(t, msgs, statusMessageMsg("E445: Other window contains changes"))
+	runCommand(t, model, "only!")
+	require.Len(t, model.Windows(), 1, ":only! should close the other window")
+	assert.Len(t, strings.Split(model.View(), "\n"), 12)
+	assert.Equal(t, "alpha", model.buffer.text())
+	assert.Equal(t, "a.txt", model.filePath)
+	assert.Equal(t, 10, model.height, "The last window should take the whole area")
+
+	msgs = runCommand(t, model, "close")
+	assert.Contains(t, msgs, statusMessageMsg("E444: Cannot close last window"))
+
+	windowKey(model, "v")
+	windowKey(model, "v")
+	require.Len(t, model.Windows(), 3)
+	windowKey(model, "o")
+	assert.Len(t, model.Windows(), 1, "<C-w>o should close the other windows")
+
+	windowKey(model, "s")
+	msgs = runCommand(t, model, "q")
+	assert.Len(t, model.Windows(), 1, ":q should close a window while there are several")
+	assert.NotContains(t, msgs, QuitRequestedMsg{})
+	msgs = runCommand(t, model, "q")
+	assert.Contains(t, msgs, statusMessageMsg(`E162: No write since last change for buffer "b.txt"`),
+		"The changes of the hidden buffer should not be abandoned")
+	msgs = runCommand(t, model, "q!")
+	assert.Contains(t, msgs, QuitRequestedMsg{Force: true}, ":q! in the last window should quit")
+}
+
+func TestResizeWindows(t *testing.T) {
+	model := newWrapEditor("one\ntwo\nthree", 20, 10)
+	first := model.CurrentWindow()
+	second := model.SplitWindow(false)
+	assert.Equal(t, 4, model.height)
+
+	sendKeys(model, "2")
+	windowKey(model, "+")
+	assert.Equal(t, 6, model.height, "<C-w>+ should grow the window by the count")
+	windowKey(model, "-")
+	assert.Equal(t, 5, model.height)
+
+	runCommand(t, model, "resize 2")
+	assert.Equal(t, 2, model.height, ":resize should set the height")
+	runCommand(t, model, "resize +1")
+	assert.Equal(t, 3, model.height, ":resize + should grow the window")
+	windowKey(model, "_")
+	assert.Equal(t, 7, model.height, "<C-w>_ should leave the other window its minimum size")
+	windowKey(model, "=")
+	assert.Equal(t, 4, model.height, "<C-w>= should make the windows equal")
+
+	require.NoError(t, model.ResizeWindow(first, 0, 6))
+	assert.Equal(t, 2, model.height, "Growing a window should shrink its neighbour")
+	assert.Equal(t, second, model.CurrentWindow(), "ResizeWindow should not move the focus")
+
+	require.NoError(t, model.SetCurrentWindow(first))
+	model.SplitWindow(true)
+	runCommand(t, model, "vertical resize 5")
+	assert.Equal(t, 5, model.width, ":vertical resize should set the width")
+	assert.Equal(t, 14, model.Windows()[2].Width, "The window beside should take the rest")
+}
+
+func TestWindowAPI(t *testing.T) {
+	model := newWrapEditor("one\ntwo\nthree", 20, 6)
+	var events []string
+	model.AddAutocmd(EventWinEnter, "", func(Buffer, AutocmdArgs) tea.Cmd {
+		events = append(events, "enter")
+		return nil
+	})
+	model.AddAutocmd(EventWinLeave, "", func(Buffer, AutocmdArgs) tea.Cmd {
+		events = append(events, "leave")
+		return nil
+	})
+
+	first := model.CurrentWindow()
+	model.SetCursor(Cursor{2, 1})
+	second := model.SplitWindow(true)
+	assert.Equal(t, Cursor{2, 1}, model.cursor, "The new window should start at the same position")
+	assert.Equal(t, []string{"leave", "enter"}, events)
+
+	model.SetCursor(Cursor{0, 0})
+	require.NoError(t, model.SetCurrentWindow(first))
+	assert.Equal(t, Cursor{2, 1}, model.cursor)
+	assert.Equal(t, []WindowInfo{
+		{ID: second, Buffer: 1, Cursor: Cursor{0, 0}, Width: 10, Height: 5},
+		{ID: first, Buffer: 1, Cursor: Cursor{2, 1}, Col: 11, Width: 9, Height: 5},
+	}, model.Windows())
+
+	assert.Error(t, model.SetCurrentWindow(42), "Unknown windows should be reported")
+	require.NoError(t, model.CloseWindow(second, false))
+	assert.Equal(t, first, model.CurrentWindow())
+	assert.Error(t, model.CloseWindow(first, true), "The last window cannot be closed")
+}
@@ -0,0 +1,273 @@
+// Package vimtea provides a Vim-like text editor component for terminal applications
+package vimtea
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// breakAt lists the characters after which linebreak may wrap a line
+const breakAt = " \t!@*-+;:,./?"
+
+// displayLine is one screen row of a buffer line, as a range of visual columns
+type displayLine struct {
+	start int // First visual column (inclusive)
+	end   int // Last visual column (exclusive)
+}
+
+// WithWrap enables or disables soft wrapping of lines longer than the editor width
+func WithWrap(enable bool) EditorOption {
+	return func(o *options) {
+		o.Wrap = enable
+	}
+}
+
+// WithLineBreak makes soft wrapping break lines at word boundaries
+// instead of at the last column that fits
+func WithLineBreak(enable bool) EditorOption {
+	return func(o *options) {
+		o.LineBreak = enable
+	}
+}
+
+// WithBreakIndent indents wrapped rows like the start of their line
+func WithBreakIndent(enable bool) EditorOption {
+	return func(o *options) {
+		o.BreakIndent = enable
+	}
+}
+
+// WithShowBreak sets a marker shown at the start of wrapped rows, e.g. "↪ "
+func WithShowBreak(marker string) EditorOption {
+	return func(o *options) {
+		o.ShowBreak = marker
+	}
+}
+
+// blankGutter returns the sign and line number columns of rows without a
+// line number
+func (m *editorModel) blankGutter() string {
+	return m.renderSignColumn(-1) + m.renderLineNumber(0, m.buffer.lineCount())
+}
+
+// gutterWidth returns the width of the sign and line number columns
+func (m *editorModel) gutterWidth() int {
+	return lipgloss.Width(m.blankGutter())
+}
+
+// textWidth returns the number of columns available for text, or 0 when
+// the width is not known yet
+func (m *editorModel) textWidth() int {
+	return max(m.width-m.gutterWidth(), 0)
+}
+
+// breakPrefix returns the indentation and marker drawn before the wrapped
+// rows of a line. It is dropped when it would leave no room for text.
+func (m *editorModel) breakPrefix(line string) string {
+	prefix := ""
+	if m.breakIndent {
+		prefix = strings.Repeat(" ", IndentWidth(line, m.buffer.tabs.tabStop))
+	}
+	prefix += m.showBreak
+	if lipgloss.Width(prefix) >= m.textWidth() {
+		return ""
+	}
+	return prefix
+}
+
+// displayLines splits a buffer line into screen rows. Without wrap the
+// whole line is a single row.
+func (m *editorModel) displayLines(row int) []displayLine {
+	line := m.buffer.Line(row)
+	tabStop := m.buffer.tabs.tabStop
+	total := visualLength(line, 0, tabStop)
+	width := m.textWidth()
+	if !m.wrap || width == 0 {
+		return []displayLine{{0, total}}
+	}
+
+	// Visual column where each rune ends, for breaking at word boundaries
+	var ends []int
+	var breaks []bool
+	if m.lineBreak {
+		for _, r := range line {
+			prev := 0
+			if len(ends) > 0 {
+				prev = ends[len(ends)-1]
+			}
+			ends = append(ends, prev+visualLength(string(r), prev, tabStop))
+			breaks = append(breaks, strings.ContainsRune(breakAt, r))
+		}
+	}
+
+	prefixWidth := lipgloss.Width(m.breakPrefix(line))
+	var rows []displayLine
+	for start := 0; ; {
+		avail := width
+		if len(rows) > 0 {
+			avail -= prefixWidth
+		}
+		if total-start <= avail {
+			rows = append(rows, displayLine{start, total})
+			// A cursor after the last character needs a row of its own
+			// when the line exactly fills the last row
+			if total-start == avail && row == m.cursor.Row && m.cursor.Col >= len(line) && m.mode == ModeInsert {
+				rows = append(rows, displayLine{total, total})
+			}
+			return rows
+		}
+
+		end := start + avail
+		for i := len(ends) - 1; i >= 0; i-- {
+			if ends[i] <= end && ends[i] > start && breaks[i] {
+				end = ends[i]
+				break
+			}
+		}
+		rows = append(rows, displayLine{start, end})
+		start = end
+	}
+}
+
+// cursorDisplayLine returns the index of the screen row of the cursor line
+// that contains the cursor
+func (m *editorModel) cursorDisplayLine() int {
+	rows := m.displayLines(m.cursor.Row)
+	col := bufferToVisualPosition(m.buffer.Line(m.cursor.Row), m.cursor.Col, m.buffer.tabs.tabStop)
+	for i, r := range rows {
+		if col < r.end {
+			return i
+		}
+	}
+	return len(rows) - 1
+}
+
+// renderDisplayLines renders a buffer line and cuts it into its screen rows,
+// with virtual text after the end of the line. Without wrap the single row
+// is clipped to the horizontally scrolled view.
+func (m *editorModel) renderDisplayLines(rendered string, row int) []string {
+	virtualText := m.renderVirtualText(row)
+	if !m.wrap {
+		return []string{m.clipLine(rendered + virtualText)}
+	}
+	rows := m.displayLines(row)
+	if len(rows) == 1 {
+		return []string{m.appendVirtualText(rendered, virtualText)}
+	}
+
+	prefix := m.breakPrefix(m.buffer.Line(row))
+	if prefix != "" && m.showBreak != "" {
+		indent := strings.TrimSuffix(prefix, m.showBreak)
+		prefix = indent + m.theme.NonText.Render(m.showBreak)
+	}
+
+	parts := make([]string, len(rows))
+	for i, r := range rows {
+		end := r.end
+		if i == len(rows)-1 {
+			// The last row also holds a cursor drawn after the line
+			end = r.end + 1
+		}
+		parts[i] = ansi.Cut(rendered, r.start, end)
+		if i > 0 {
+			parts[i] = prefix + parts[i]
+		}
+	}
+	parts[len(parts)-1] = m.appendVirtualText(parts[len(parts)-1], virtualText)
+	return parts
+}
+
+// appendVirtualText adds virtual text after the last screen row of a line,
+// cut off at the edge of the screen
+func (m *editorModel) appendVirtualText(part, virtualText string) string {
+	width := m.textWidth()
+	if virtualText == "" || width == 0 {
+		return part + virtualText
+	}
+	return part + ansi.Truncate(virtualText, max(width-lipgloss.Width(part), 0), "")
+}
+
+// visualToBufferPosition converts a visual column to the byte offset of the
+// character drawn at that column
+func visualToBufferPosition(line string, visualCol, tabStop int) int {
+	col := 0
+	for i, r := range line {
+		col += visualLength(string(r), col, tabStop)
+		if col > visualCol {
+			return i
+		}
+	}
+	return len(line)
+}
+
+// moveDisplayLine moves the cursor count screen rows down, or up when
+// count is negative, keeping the column within the row
+func (m *editorModel) moveDisplayLine(count int) {
+	tabStop := m.buffer.tabs.tabStop
+	rows := m.displayLines(m.cursor.Row)
+	index := m.cursorDisplayLine()
+	offset := bufferToVisualPosition(m.buffer.Line(m.cursor.Row), m.cursor.Col, tabStop) - rows[index].start
+
+	for ; count > 0; count-- {
+		if index+1 < len(rows) {
+			index++
+		} else if m.cursor.Row < m.buffer.lineCount()-1 {
+			m.cursor.Row++
+			rows = m.displayLines(m.cursor.Row)
+			index = 0
+		}
+	}
+	for ; count < 0; count++ {
+		if index > 0 {
+			index--
+		} else if m.cursor.Row > 0 {
+			m.cursor.Row--
+			rows = m.displayLines(m.cursor.Row)
+			index = len(rows) - 1
+		}
+	}
+
+	r := rows[index]
+	col := min(r.start+offset, max(r.end-1, r.start))
+	m.cursor.Col = visualToBufferPosition(m.buffer.Line(m.cursor.Row), col, tabStop)
+	m.ensureCursorVisible()
+}
+
+func moveDisplayLineDown(model *editorModel) tea.Cmd {
+	model.moveDisplayLine(model.countPrefix)
+	model.countPrefix = 1
+	return nil
+}
+
+func moveDisplayLineUp(model *editorModel) tea.Cmd {
+	model.moveDisplayLine(-model.countPrefix)
+	model.countPrefix = 1
+	return nil
+}
+
+func moveToDisplayLineStart(model *editorModel) tea.Cmd {
+	r := model.displayLines(model.cursor.Row)[model.cursorDisplayLine()]
+	model.cursor.Col = visualToBufferPosition(model.buffer.Line(model.cursor.Row), r.start, model.buffer.tabs.tabStop)
+	model.desiredCol = model.cursor.Col
+	return nil
+}
+
+func moveToDisplayLineEnd(model *editorModel) tea.Cmd {
+	r := model.displayLines(model.cursor.Row)[model.cursorDisplayLine()]
+	model.cursor.Col = visualToBufferPosition(model.buffer.Line(model.cursor.Row), max(r.end-1, r.start), model.buffer.tabs.tabStop)
+	model.desiredCol = model.cursor.Col
+	return nil
+}
+
+// screenRowsBetween counts the screen rows of the buffer lines from start up
+// to, but not including, end
+func (m *editorModel) screenRowsBetween(start, end int) int {
+	rows := 0
+	for row := start; row < end; row++ {
+		rows += len(m.displayLines(row))
+	}
+	return rows
+}
@@ -0,0 +1,121 @@
+package vimtea
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+)
+
+// newWrapEditor creates an editor without line numbers and with the given size
+func newWrapEditor(content string, width, height int, opts ...EditorOption) *editorModel {
+	model := NewEditor(append([]EditorOption{WithContent(content)}, opts...)...).(*editorModel)
+	model.showNumbers = false
+	model.width = width
+	model.height = height
+	return model
+}
+
+// screenRows returns the rendered content as plain text rows
+func screenRows(model *editorModel) []string {
+	rows := strings.Split(strings.TrimSuffix(ansi.Strip(model.renderContent()), "\n"), "\n")
+	for i := range rows {
+		rows[i] = strings.TrimRight(rows[i], " ")
+	}
+	return rows
+}
+
+func TestDisplayLines(t *testing.T) {
+	model := newWrapEditor("abcdefghij\nxy", 4, 10)
+	assert.Equal(t, []displayLine{{0, 4}, {4, 8}, {8, 10}}, model.displayLines(0))
+	assert.Equal(t, []displayLine{{0, 2}}, model.displayLines(1))
+
+	model.wrap = false
+	assert.Equal(t, []displayLine{{0, 10}}, model.displayLines(0), "Without wrap a line is a single row")
+
+	model = newWrapEditor("one two three", 8, 10, WithLineBreak(true))
+	assert.Equal(t, []displayLine{{0, 8}, {8, 13}}, model.displayLines(0), "linebreak should wrap after a space")
+
+	model = newWrapEditor("  abcdefgh", 6, 10, WithBreakIndent(true), WithShowBreak(">"))
+	assert.Equal(t, []displayLine{{0, 6}, {6, 9}, {9, 10}}, model.displayLines(0), "Wrapped rows should leave room for the prefix")
+}
+
+func TestWrapRendering(t *testing.T) {
+	model := newWrapEditor("abcdefghij\nxy", 4, 5)
+	model.cursor = Cursor{1, 0}
+	assert.Equal(t, []string{"abcd", "efgh", "ij", "xy", ""}, screenRows(model))
+
+	model = newWrapEditor("  abcdefgh", 6, 3, WithBreakIndent(true), WithShowBreak(">"))
+	assert.Equal(t, []string{"  abcd", "  >efg", "  >h"}, screenRows(model), "Wrapped rows should show the break prefix")
+
+	model = newWrapEditor("abcdefghij", 4, 2)
+	model.wrap = false
+	model.cursor = Cursor{0, 9}
+	model.ensureCursorVisible()
+	assert.Equal(t, []string{"ghij", ""}, screenRows(model), "Without wrap lines should scroll instead of being split")
+
+	model = NewEditor(WithContent("abcdefghij")).(*editorModel)
+	model.width, model.height = 9, 3
+	rows := screenRows(model)
+	assert.Equal(t, "   1 abcd", rows[0])
+	assert.Equal(t, "     efgh", rows[1], "Wrapped rows should have an empty gutter")
+}
+
+func TestWrapScrolling(t *testing.T) {
+	model := newWrapEditor("abcdefghij\nabcdefghij\nx", 4, 4)
+	model.cursor = Cursor{1, 9}
+	model.ensureCursorVisible()
+	assert.Equal(t, 1, model.viewport.YOffset, "Viewport should scroll by buffer lines until the cursor row fits")
+	assert.Equal(t, []string{"abcd", "efgh", "ij", "x"}, screenRows(model))
+
+	model.cursor = Cursor{2, 0}
+	model.ensureCursorVisible()
+	assert.Equal(t, 1, model.viewport.YOffset)
+}
+
+func TestDisplayLineMotions(t *testing.T) {
+	model := newWrapEditor("abcdefghij\nxy", 4, 10)
+	model.cursor = Cursor{0, 1}
+
+	sendKeys(model, "gj")
+	assert.Equal(t, Cursor{0, 5}, model.cursor, "gj should move to the next display line")
+	sendKeys(model, "g$")
+	assert.Equal(t, Cursor{0, 7}, model.cursor, "g$ should move to the end of the display line")
+	sendKeys(model, "g0")
+	assert.Equal(t, Cursor{0, 4}, model.cursor, "g0 should move to the start of the display line")
+
+	sendKeys(model, "2gj")
+	assert.Equal(t, Cursor{1, 0}, model.cursor, "gj should continue onto the next buffer line")
+	sendKeys(model, "gk")
+	assert.Equal(t, Cursor{0, 8}, model.cursor, "gk should move to the last display line of the previous line")
+}
+
+func TestWrapOptions(t *testing.T) {
+	model := newWrapEditor("text", 10, 10)
+	assert.True(t, model.wrap, "Lines should wrap by default")
+
+	runCommand(t, model, "set nowrap lbr bri sbr=+")
+	assert.False(t, model.wrap)
+	assert.True(t, model.lineBreak)
+	assert.True(t, model.breakIndent)
+	assert.Equal(t, "+", model.showBreak)
+}
+
+func TestWrapScrollingLargeBuffer(t *testing.T) {
+	lines := make([]string, 20000)
+	for i := range lines {
+		lines[i] = strings.Repeat("word ", 10)
+	}
+	model := newWrapEditor(strings.Join(lines, "\n"), 20, 10)
+
+	start := time.Now()
+	sendKeys(model, "G")
+	assert.Equal(t, 19999, model.cursor.Row)
+	assert.Equal(t, 19996, model.viewport.YOffset, "The last lines should fill the screen")
+	sendKeys(model, "gg5000j")
+	assert.Equal(t, 5000, model.cursor.Row)
+	assert.Equal(t, 4997, model.viewport.YOffset)
+	assert.Less(t, time.Since(start), time.Second, "Scrolling should not depend on the distance moved")
+}
@@ -20,6 +20,11 @@ func (w *wrappedBuffer) Lines() []string {
 	return w.m.buffer.lines
 }
 
+// Line returns the line at the given row
+func (w *wrappedBuffer) Line(row int) string {
+	return w.m.buffer.Line(row)
+}
+
 // LineCount returns the number of lines in the buffer
 func (w *wrappedBuffer) LineCount() int {
 	return w.m.buffer.lineCount()
@@ -77,3 +82,13 @@ func (w *wrappedBuffer) Clear() tea.Cmd {
 		return nil
 	}
 }
+
+// Modified returns whether the content differs from the last saved state
+func (w *wrappedBuffer) Modified() bool {
+	return w.m.buffer.modified()
+}
+
+// MarkSaved records the current content as the saved state
+func (w *wrappedBuffer) MarkSaved() {
+	w.m.buffer.markSaved()
+}